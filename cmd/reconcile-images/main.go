@@ -0,0 +1,72 @@
+// Command reconcile-images lists stored images that no product image uses
+// and, with -purge, deletes them. Images newer than -min-age are skipped so
+// uploads whose product is still being saved are left alone. Purging needs
+// CLOUDINARY_UPLOAD_FOLDER: without it the whole account is listed, images
+// of other apps included.
+package main
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"basic-trade-api/services"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+func main() {
+	purge := flag.Bool("purge", false, "delete the unreferenced images instead of only listing them")
+	minAge := flag.Duration("min-age", 24*time.Hour, "skip images stored more recently than this")
+	flag.Parse()
+
+	if *purge && configs.EnvCloudUploadFolder() == "" {
+		log.Fatal("refusing to purge without CLOUDINARY_UPLOAD_FOLDER: every image in the account would be deleted")
+	}
+
+	DB := database.StartDB()
+	defer DB.Close()
+
+	ctx := context.Background()
+
+	// Storage is listed first so an image uploaded and saved in between
+	// is seen as referenced
+	stored, err := helpers.ListFiles()
+	if err != nil {
+		log.Fatal("list stored images: ", err)
+	}
+	referenced, err := services.ReferencedImageIDsService(ctx, DB)
+	if err != nil {
+		log.Fatal("list referenced images: ", err)
+	}
+
+	cutoff := time.Now().Add(-*minAge)
+	var orphans []string
+	for _, image := range stored {
+		if referenced[image.PublicID] || image.CreatedAt.After(cutoff) {
+			continue
+		}
+		orphans = append(orphans, image.PublicID)
+		fmt.Println(image.PublicID)
+	}
+	log.Printf("%d stored images, %d unreferenced", len(stored), len(orphans))
+
+	if !*purge || len(orphans) == 0 {
+		return
+	}
+
+	var purged []string
+	for _, publicID := range orphans {
+		if err := helpers.DeleteStoredFile(publicID); err != nil {
+			log.Printf("delete image %s: %v", publicID, err)
+			continue
+		}
+		purged = append(purged, publicID)
+	}
+	if err := services.ClearImageDeletionsService(ctx, DB, purged); err != nil {
+		log.Fatal("clear queued deletions: ", err)
+	}
+	log.Printf("purged %d images", len(purged))
+}
@@ -0,0 +1,45 @@
+package configs
+
+import (
+	"strings"
+	"time"
+)
+
+// EnvLowStockSinks lists where low-stock alerts are sent: any of log,
+// dashboard, webhook and email, comma separated. Defaults to log and
+// dashboard.
+func EnvLowStockSinks() []string {
+	sinks := loadEnv("LOW_STOCK_SINKS")
+	if sinks == "" {
+		return []string{"log", "dashboard"}
+	}
+	return splitList(sinks)
+}
+
+func EnvLowStockWebhookURL() string {
+	return loadEnv("LOW_STOCK_WEBHOOK_URL")
+}
+
+func EnvLowStockEmailTo() []string {
+	return splitList(loadEnv("LOW_STOCK_EMAIL_TO"))
+}
+
+// EnvLowStockCheckInterval is how often pending low-stock events are sent.
+// Defaults to 30 seconds.
+func EnvLowStockCheckInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("LOW_STOCK_CHECK_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * time.Second
+	}
+	return interval
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,94 @@
+package configs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvLoginRateLimitIP is how many login attempts one client IP may make per
+// period, written as count/period, e.g. 20/1m. Defaults to 20 a minute.
+func EnvLoginRateLimitIP() (int, time.Duration) {
+	return parseRate(loadEnv("LOGIN_RATE_LIMIT_IP"), 20, time.Minute)
+}
+
+// EnvLoginRateLimitEmail is how many login attempts may be made for one email
+// per period. Defaults to 5 a minute.
+func EnvLoginRateLimitEmail() (int, time.Duration) {
+	return parseRate(loadEnv("LOGIN_RATE_LIMIT_EMAIL"), 5, time.Minute)
+}
+
+// EnvLoginMaxFailures is how many wrong passwords in a row lock an account.
+// Defaults to 5.
+func EnvLoginMaxFailures() int {
+	failures, err := strconv.Atoi(loadEnv("LOGIN_MAX_FAILURES"))
+	if err != nil || failures <= 0 {
+		return 5
+	}
+	return failures
+}
+
+// EnvLoginLockoutDuration is how long a locked account stays locked. Defaults
+// to 15 minutes.
+func EnvLoginLockoutDuration() time.Duration {
+	duration, err := time.ParseDuration(loadEnv("LOGIN_LOCKOUT_DURATION"))
+	if err != nil || duration <= 0 {
+		return 15 * time.Minute
+	}
+	return duration
+}
+
+func parseRate(value string, defaultCount int, defaultPeriod time.Duration) (int, time.Duration) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return defaultCount, defaultPeriod
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return defaultCount, defaultPeriod
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || period <= 0 {
+		return defaultCount, defaultPeriod
+	}
+	return count, period
+}
+
+// EnvPasswordResetTTL is how long a password reset link stays valid.
+// Defaults to 1 hour.
+func EnvPasswordResetTTL() time.Duration {
+	ttl, err := time.ParseDuration(loadEnv("PASSWORD_RESET_TTL"))
+	if err != nil || ttl <= 0 {
+		return time.Hour
+	}
+	return ttl
+}
+
+// EnvEmailVerificationTTL is how long an email verification link stays valid.
+// Defaults to 48 hours.
+func EnvEmailVerificationTTL() time.Duration {
+	ttl, err := time.ParseDuration(loadEnv("EMAIL_VERIFICATION_TTL"))
+	if err != nil || ttl <= 0 {
+		return 48 * time.Hour
+	}
+	return ttl
+}
+
+// EnvTOTPIssuer is the name authenticator apps show next to the account.
+func EnvTOTPIssuer() string {
+	issuer := loadEnv("TOTP_ISSUER")
+	if issuer == "" {
+		return "Basic Trade"
+	}
+	return issuer
+}
+
+// EnvLoginChallengeTTL is how long an admin with two-factor authentication
+// has to enter a code after their password. Defaults to 5 minutes.
+func EnvLoginChallengeTTL() time.Duration {
+	ttl, err := time.ParseDuration(loadEnv("LOGIN_CHALLENGE_TTL"))
+	if err != nil || ttl <= 0 {
+		return 5 * time.Minute
+	}
+	return ttl
+}
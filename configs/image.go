@@ -0,0 +1,53 @@
+package configs
+
+import (
+	"strconv"
+	"time"
+)
+
+// EnvImageMaxBytes is the largest image file accepted for upload. Defaults
+// to 10 MiB.
+func EnvImageMaxBytes() int64 {
+	maxBytes, err := strconv.ParseInt(loadEnv("IMAGE_MAX_BYTES"), 10, 64)
+	if err != nil || maxBytes <= 0 {
+		return 10 << 20
+	}
+	return maxBytes
+}
+
+// EnvImageMaxDimension is the largest width or height, in pixels, of an
+// image accepted for upload. Defaults to 4096; WebP allows at most 16384.
+func EnvImageMaxDimension() int {
+	maxDimension, err := strconv.Atoi(loadEnv("IMAGE_MAX_DIMENSION"))
+	if err != nil || maxDimension <= 0 {
+		return 4096
+	}
+	if maxDimension > 16384 {
+		return 16384
+	}
+	return maxDimension
+}
+
+// EnvImageCleanupInterval is how often queued deletions of stored images are
+// retried. Defaults to 30 seconds.
+func EnvImageCleanupInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("IMAGE_CLEANUP_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * time.Second
+	}
+	return interval
+}
+
+// EnvImageUploadTTL is how long a signed direct upload may be used and then
+// confirmed. Defaults to 15 minutes; storage accepts a signature for at most
+// an hour.
+func EnvImageUploadTTL() time.Duration {
+	ttl, err := time.ParseDuration(loadEnv("IMAGE_UPLOAD_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	if ttl > time.Hour {
+		return time.Hour
+	}
+	return ttl
+}
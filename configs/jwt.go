@@ -0,0 +1,72 @@
+package configs
+
+import "time"
+
+// EnvJWTAlgorithm is the algorithm new signing keys use, RS256 or EdDSA.
+// Defaults to RS256, which every JWT library can verify.
+func EnvJWTAlgorithm() string {
+	switch algorithm := loadEnv("JWT_ALGORITHM"); algorithm {
+	case "RS256", "EdDSA":
+		return algorithm
+	default:
+		return "RS256"
+	}
+}
+
+// EnvJWTIssuer is the iss claim of access tokens.
+func EnvJWTIssuer() string {
+	issuer := loadEnv("JWT_ISSUER")
+	if issuer == "" {
+		return "basic-trade-api"
+	}
+	return issuer
+}
+
+// EnvJWTAudience is the aud claim of access tokens.
+func EnvJWTAudience() string {
+	audience := loadEnv("JWT_AUDIENCE")
+	if audience == "" {
+		return "basic-trade-admin"
+	}
+	return audience
+}
+
+// EnvJWTTTL is how long an access token is valid. Defaults to 8 hours.
+func EnvJWTTTL() time.Duration {
+	ttl, err := time.ParseDuration(loadEnv("JWT_TTL"))
+	if err != nil || ttl <= 0 {
+		return 8 * time.Hour
+	}
+	return ttl
+}
+
+// EnvJWTLeeway is the clock skew allowed when checking exp and nbf. Defaults
+// to 30 seconds.
+func EnvJWTLeeway() time.Duration {
+	leeway, err := time.ParseDuration(loadEnv("JWT_LEEWAY"))
+	if err != nil || leeway < 0 {
+		return 30 * time.Second
+	}
+	return leeway
+}
+
+// EnvJWTKeyRotationInterval is how long a signing key is used before it is
+// replaced. Defaults to 30 days.
+func EnvJWTKeyRotationInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("JWT_KEY_ROTATION_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return interval
+}
+
+// EnvJWTKeyPublishAhead is how long a new key is listed in the JWKS before
+// tokens are signed with it, so verifiers caching the JWKS know it in time.
+// Defaults to 1 hour.
+func EnvJWTKeyPublishAhead() time.Duration {
+	ahead, err := time.ParseDuration(loadEnv("JWT_KEY_PUBLISH_AHEAD"))
+	if err != nil || ahead < 0 {
+		return time.Hour
+	}
+	return ahead
+}
@@ -0,0 +1,74 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func loadEnv(key string) string {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+	return os.Getenv(key)
+}
+
+func EnvSMTPHost() string {
+	return loadEnv("SMTP_HOST")
+}
+
+func EnvSMTPPort() string {
+	port := loadEnv("SMTP_PORT")
+	if port == "" {
+		return "587"
+	}
+	return port
+}
+
+func EnvSMTPUsername() string {
+	return loadEnv("SMTP_USERNAME")
+}
+
+func EnvSMTPPassword() string {
+	return loadEnv("SMTP_PASSWORD")
+}
+
+func EnvSMTPFrom() string {
+	return loadEnv("SMTP_FROM")
+}
+
+// EnvMailDriver picks how mail is sent: smtp, log or file. Defaults to smtp
+// when SMTP_HOST is set and to log otherwise, so local setups never mail
+// anyone by accident.
+func EnvMailDriver() string {
+	driver := loadEnv("MAIL_DRIVER")
+	if driver != "" {
+		return driver
+	}
+	if EnvSMTPHost() != "" {
+		return "smtp"
+	}
+	return "log"
+}
+
+// EnvMailFileDir is where the file driver writes messages.
+func EnvMailFileDir() string {
+	dir := loadEnv("MAIL_FILE_DIR")
+	if dir == "" {
+		return "tmp/mail"
+	}
+	return dir
+}
+
+// EnvAppBaseURL is the address of the admin front end, used to build the
+// links sent by mail.
+func EnvAppBaseURL() string {
+	url := loadEnv("APP_BASE_URL")
+	if url == "" {
+		return "http://localhost:3000"
+	}
+	return strings.TrimRight(url, "/")
+}
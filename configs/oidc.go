@@ -0,0 +1,59 @@
+package configs
+
+import "strings"
+
+// EnvOIDCIssuerURL is the issuer of the identity provider used for single
+// sign-on. Single sign-on is off while it or the client ID is empty.
+func EnvOIDCIssuerURL() string {
+	return loadEnv("OIDC_ISSUER_URL")
+}
+
+func EnvOIDCClientID() string {
+	return loadEnv("OIDC_CLIENT_ID")
+}
+
+func EnvOIDCClientSecret() string {
+	return loadEnv("OIDC_CLIENT_SECRET")
+}
+
+// EnvOIDCRedirectURL is this API's /auth/oidc/callback as registered with the
+// identity provider.
+func EnvOIDCRedirectURL() string {
+	return loadEnv("OIDC_REDIRECT_URL")
+}
+
+// EnvOIDCScopes are the scopes asked for at login. openid is always added.
+func EnvOIDCScopes() []string {
+	scopes := splitList(loadEnv("OIDC_SCOPES"))
+	if len(scopes) == 0 {
+		scopes = []string{"email", "profile"}
+	}
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return scopes
+		}
+	}
+	return append([]string{"openid"}, scopes...)
+}
+
+// EnvOIDCAllowedDomains limits which email domains may sign in. Empty allows
+// every domain the identity provider vouches for.
+func EnvOIDCAllowedDomains() []string {
+	domains := splitList(loadEnv("OIDC_ALLOWED_DOMAINS"))
+	for i, domain := range domains {
+		domains[i] = strings.ToLower(strings.TrimPrefix(domain, "@"))
+	}
+	return domains
+}
+
+// EnvOIDCAutoProvision creates an admin on first sign-on when none matches.
+// Defaults to true.
+func EnvOIDCAutoProvision() bool {
+	return loadEnv("OIDC_AUTO_PROVISION") != "false"
+}
+
+// EnvOIDCTrustIdPMFA skips this API's two-factor challenge after single
+// sign-on, for identity providers that enforce their own. Defaults to false.
+func EnvOIDCTrustIdPMFA() bool {
+	return loadEnv("OIDC_TRUST_IDP_MFA") == "true"
+}
@@ -0,0 +1,43 @@
+package configs
+
+import "time"
+
+// EnvOutboxPublishers lists where outbox events are relayed: any of log,
+// webhook and broker, comma separated. Defaults to log and webhook.
+func EnvOutboxPublishers() []string {
+	publishers := loadEnv("OUTBOX_PUBLISHERS")
+	if publishers == "" {
+		return []string{"log", "webhook"}
+	}
+	return splitList(publishers)
+}
+
+// EnvOutboxBroker selects the broker used by the broker publisher. Only
+// memory is built in. Defaults to memory.
+func EnvOutboxBroker() string {
+	broker := loadEnv("OUTBOX_BROKER")
+	if broker == "" {
+		return "memory"
+	}
+	return broker
+}
+
+// EnvOutboxTopicPrefix is prepended to the aggregate type to form the broker
+// topic. Defaults to "basic-trade.".
+func EnvOutboxTopicPrefix() string {
+	prefix := loadEnv("OUTBOX_TOPIC_PREFIX")
+	if prefix == "" {
+		return "basic-trade."
+	}
+	return prefix
+}
+
+// EnvOutboxRelayInterval is how often pending outbox events are relayed.
+// Defaults to 1 second.
+func EnvOutboxRelayInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("OUTBOX_RELAY_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Second
+	}
+	return interval
+}
@@ -0,0 +1,13 @@
+package configs
+
+import "time"
+
+// EnvProductScheduleInterval is how often scheduled product publishing and
+// unpublishing is applied. Defaults to 1 minute.
+func EnvProductScheduleInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("PRODUCT_SCHEDULE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Minute
+	}
+	return interval
+}
@@ -0,0 +1,37 @@
+package configs
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// EnvReservationTTL is how long a stock reservation is held when the request
+// does not say otherwise. Defaults to 15 minutes.
+func EnvReservationTTL() time.Duration {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+	ttl, err := time.ParseDuration(os.Getenv("RESERVATION_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+// EnvReservationSweepInterval is how often expired reservations are released.
+// Defaults to 30 seconds.
+func EnvReservationSweepInterval() time.Duration {
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+	interval, err := time.ParseDuration(os.Getenv("RESERVATION_SWEEP_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 30 * time.Second
+	}
+	return interval
+}
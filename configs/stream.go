@@ -0,0 +1,13 @@
+package configs
+
+import "time"
+
+// EnvStockStreamRetention is how long stock changes are kept for streams to
+// resume from. Defaults to 1 hour.
+func EnvStockStreamRetention() time.Duration {
+	retention, err := time.ParseDuration(loadEnv("STOCK_STREAM_RETENTION"))
+	if err != nil || retention <= 0 {
+		return time.Hour
+	}
+	return retention
+}
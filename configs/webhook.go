@@ -0,0 +1,13 @@
+package configs
+
+import "time"
+
+// EnvWebhookDispatchInterval is how often due webhook deliveries are sent.
+// Defaults to 5 seconds.
+func EnvWebhookDispatchInterval() time.Duration {
+	interval, err := time.ParseDuration(loadEnv("WEBHOOK_DISPATCH_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 5 * time.Second
+	}
+	return interval
+}
@@ -0,0 +1,8 @@
+package configs
+
+// EnvWSAllowedOrigins lists the browser origins allowed to open the admin
+// WebSocket, comma separated, or * for any. Same-origin requests are always
+// allowed.
+func EnvWSAllowedOrigins() []string {
+	return splitList(loadEnv("WS_ALLOWED_ORIGINS"))
+}
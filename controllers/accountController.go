@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"basic-trade-api/services"
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountDeps pulls the database and mailer the account endpoints need out of
+// the context, writing the error response itself when one is missing.
+func accountDeps(ctx *gin.Context) (*sql.DB, helpers.Mailer, bool) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return nil, nil, false
+	}
+	mailer, ok := ctx.MustGet("mailer").(helpers.Mailer)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast mailer to helpers.Mailer",
+		})
+		return nil, nil, false
+	}
+	return dbConn, mailer, true
+}
+
+func accountError(ctx *gin.Context, err error) {
+	if err.Error() == "invalid or expired token" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid or expired token",
+			"message": "The link is invalid or has expired, please request a new one",
+		})
+		return
+	}
+	ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+}
+
+func ForgotPassword(ctx *gin.Context) {
+	dbConn, mailer, ok := accountDeps(ctx)
+	if !ok {
+		return
+	}
+	emailRequest, ok := ctx.MustGet("request").(admin.AdminEmailRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to AdminEmailRequest",
+		})
+		return
+	}
+
+	if err := services.ForgotPasswordService(ctx.Request.Context(), dbConn, mailer, emailRequest.Email); err != nil {
+		accountError(ctx, err)
+		return
+	}
+
+	// The same answer whether or not the email has an account
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email belongs to an account, a reset link has been sent to it",
+	})
+}
+
+func ResetPassword(ctx *gin.Context) {
+	dbConn, mailer, ok := accountDeps(ctx)
+	if !ok {
+		return
+	}
+	resetRequest, ok := ctx.MustGet("request").(admin.ResetPasswordRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to ResetPasswordRequest",
+		})
+		return
+	}
+
+	if err := services.ResetPasswordService(ctx.Request.Context(), dbConn, mailer, resetRequest.Token, resetRequest.Password); err != nil {
+		accountError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Password has been reset",
+	})
+}
+
+func VerifyEmail(ctx *gin.Context) {
+	dbConn, _, ok := accountDeps(ctx)
+	if !ok {
+		return
+	}
+	verifyRequest, ok := ctx.MustGet("request").(admin.VerifyEmailRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to VerifyEmailRequest",
+		})
+		return
+	}
+
+	if err := services.VerifyEmailService(ctx.Request.Context(), dbConn, verifyRequest.Token); err != nil {
+		accountError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Email has been verified",
+	})
+}
+
+func ResendVerification(ctx *gin.Context) {
+	dbConn, mailer, ok := accountDeps(ctx)
+	if !ok {
+		return
+	}
+	emailRequest, ok := ctx.MustGet("request").(admin.AdminEmailRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to AdminEmailRequest",
+		})
+		return
+	}
+
+	if err := services.ResendVerificationService(ctx.Request.Context(), dbConn, mailer, emailRequest.Email); err != nil {
+		accountError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{
+		"message": "If the email belongs to an unverified account, a new link has been sent to it",
+	})
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"basic-trade-api/services"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminAccountError writes the response for errors shared by the admin
+// account endpoints.
+func adminAccountError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "admin not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Admin not found",
+			"message": "Admin with the specified UUID does not exist",
+		})
+	case "invalid password":
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid password",
+			"message": "The current password is wrong",
+		})
+	case "email already exists":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Email already exists",
+			"message": "Another admin already uses this email",
+		})
+	case "cannot deactivate yourself":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Cannot deactivate yourself",
+			"message": "Ask another superadmin to deactivate your account",
+		})
+	case "invalid status":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid status",
+			"message": "status must be active or deactivated",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func GetMyAdmin(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	getAdmin, err := services.GetAdminProfileService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		adminAccountError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully fetched your account!",
+		"data":    getAdmin,
+	})
+}
+
+func UpdateMyAdmin(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+	mailer, ok := ctx.MustGet("mailer").(helpers.Mailer)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast mailer to helpers.Mailer",
+		})
+		return
+	}
+	updateRequest, ok := ctx.MustGet("request").(admin.UpdateAdminRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to UpdateAdminRequest",
+		})
+		return
+	}
+
+	editAdmin, err := services.UpdateAdminProfileService(ctx.Request.Context(), dbConn, mailer, adminId, updateRequest)
+	if err != nil {
+		adminAccountError(ctx, err)
+		return
+	}
+
+	message := "Successfully updated your account!"
+	if !editAdmin.EmailVerified {
+		message = "Successfully updated your account! Verify the new email before you next log in."
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    editAdmin,
+	})
+}
+
+func ChangeMyPassword(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+	mailer, ok := ctx.MustGet("mailer").(helpers.Mailer)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast mailer to helpers.Mailer",
+		})
+		return
+	}
+	passwordRequest, ok := ctx.MustGet("request").(admin.ChangePasswordRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to ChangePasswordRequest",
+		})
+		return
+	}
+
+	editAdmin, err := services.ChangePasswordService(ctx.Request.Context(), dbConn, mailer, adminId, passwordRequest)
+	if err != nil {
+		adminAccountError(ctx, err)
+		return
+	}
+
+	// Every token issued before the change, including the one used for this
+	// request, has just stopped working
+	token, err := helpers.GenerateToken(editAdmin.ID, editAdmin.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully changed your password! Other sessions have been signed out.",
+		"data": gin.H{
+			"email":       editAdmin.Email,
+			"name":        editAdmin.Name,
+			"accessToken": token,
+		},
+	})
+}
+
+func GetAllAdmin(ctx *gin.Context) {
+	dbConn, _, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	pageNum, _ := strconv.Atoi(ctx.DefaultQuery("pageNum", "1"))
+	if pageNum < 1 || pageSize < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid page number",
+		})
+		return
+	}
+	offset := (pageNum - 1) * pageSize
+
+	getAdmins, total, err := services.GetAllAdminService(ctx.Request.Context(), dbConn, ctx.Query("status"), pageSize, offset)
+	if err != nil {
+		adminAccountError(ctx, err)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully fetch admins!",
+		"data":    getAdmins,
+		"meta": gin.H{
+			"limit":     pageSize,
+			"offset":    offset,
+			"total":     total,
+			"totalPage": totalPages,
+		},
+	})
+}
+
+func DeactivateAdmin(ctx *gin.Context) {
+	setAdminActive(ctx, false)
+}
+
+func ReactivateAdmin(ctx *gin.Context) {
+	setAdminActive(ctx, true)
+}
+
+func setAdminActive(ctx *gin.Context, active bool) {
+	adminUUID := ctx.Param("adminUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	editAdmin, err := services.SetAdminActiveService(ctx.Request.Context(), dbConn, adminId, adminUUID, active)
+	if err != nil {
+		adminAccountError(ctx, err)
+		return
+	}
+
+	message := "Successfully deactivated the admin!"
+	if active {
+		message = "Successfully reactivated the admin!"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    editAdmin,
+	})
+}
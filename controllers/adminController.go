@@ -37,7 +37,15 @@ func AdminRegister(ctx *gin.Context) {
 		return
 	}
 
-	newAdmin, err := services.AdminRegisterService(dbConn, adminRequest)
+	mailer, ok := ctx.MustGet("mailer").(helpers.Mailer)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast mailer to helpers.Mailer",
+		})
+		return
+	}
+
+	newAdmin, err := services.AdminRegisterService(ctx.Request.Context(), dbConn, mailer, adminRequest)
 	if err != nil {
 		if err.Error() == "email already exists" {
 			ctx.JSON(http.StatusConflict, gin.H{
@@ -53,7 +61,7 @@ func AdminRegister(ctx *gin.Context) {
 	}
 
 	responseData := gin.H{
-		"message": "Successfully created user!",
+		"message": "Successfully created user! Check your email to verify your address.",
 		"data": gin.H{
 			"id":        newAdmin.ID,
 			"uuid":      newAdmin.UUID,
@@ -94,37 +102,27 @@ func AdminLogin(ctx *gin.Context) {
 	}
 
 	// Call the AdminRegisterService
-	adminResponse, err := services.AdminLoginService(dbConn, adminRequest)
+	adminResponse, err := services.AdminLoginService(ctx.Request.Context(), dbConn, adminRequest)
 	if err != nil {
-		var statusCode int
-		switch err.Error() {
-		case "user not found":
-			statusCode = http.StatusNotFound
-		case "invalid password":
-			statusCode = http.StatusBadRequest
-		default:
-			statusCode = http.StatusInternalServerError
+		if err.Error() == "invalid credentials" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			return
+		}
+		if err.Error() == "email not verified" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Email not verified"})
+			return
+		}
+		if err.Error() == "account deactivated" {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Account deactivated"})
+			return
 		}
-		ctx.JSON(statusCode, gin.H{"error": err.Error()})
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate JWT token
-	token, err := helpers.GenerateToken(adminResponse.ID, adminResponse.Email)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	if adminResponse.TwoFactorEnabled {
+		loginChallenged(ctx, dbConn, adminResponse)
 		return
 	}
-
-	responseData := gin.H{
-		"message": "User logged successfully",
-		"data": gin.H{
-			"email":       adminResponse.Email,
-			"name":        adminResponse.Name,
-			"accessToken": token,
-		},
-	}
-
-	ctx.JSON(http.StatusOK, responseData)
-
+	loginSucceeded(ctx, adminResponse)
 }
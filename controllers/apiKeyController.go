@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"basic-trade-api/models/apikey"
+	"basic-trade-api/services"
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+)
+
+// apiKeyError writes the response for errors shared by the API key
+// endpoints.
+func apiKeyError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "api key not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "API key not found",
+			"message": "API key with the specified UUID does not exist",
+		})
+	case "expiry must be in the future":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid expiry",
+			"message": "expiresAt must be in the future",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func CreateAPIKey(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	apiKeyRequest, ok := requestInterface.(apikey.APIKeyRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *apiKeyRequest",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	newAPIKey, err := services.CreateAPIKeyService(ctx.Request.Context(), dbConn, apiKeyRequest, adminId)
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully created API key! Store the key now, it will not be shown again.",
+		"data":    newAPIKey,
+	}
+	ctx.JSON(http.StatusCreated, responseData)
+}
+
+func GetAllAPIKey(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	getAPIKeys, err := services.GetAllAPIKeyService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetch API keys!",
+		"data":    getAPIKeys,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetAPIKeyByID(ctx *gin.Context) {
+	apiKeyUUID := ctx.Param("apiKeyUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	getAPIKey, err := services.GetAPIKeyByIDService(ctx.Request.Context(), dbConn, apiKeyUUID, adminId)
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetch API key!",
+		"data":    getAPIKey,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func RevokeAPIKey(ctx *gin.Context) {
+	apiKeyUUID := ctx.Param("apiKeyUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	revokedAPIKey, err := services.RevokeAPIKeyService(ctx.Request.Context(), dbConn, apiKeyUUID, adminId)
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully revoked API key!",
+		"data":    revokedAPIKey,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
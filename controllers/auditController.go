@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"basic-trade-api/models/audit"
+	"basic-trade-api/services"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditMaxPageSize is the most audit entries returned per page
+const auditMaxPageSize = 100
+
+// GetAllAudit lists audit entries, optionally filtered by entity, actor (an
+// admin UUID) and a from/to time range in RFC 3339. Larger page sizes are
+// cut to auditMaxPageSize.
+func GetAllAudit(ctx *gin.Context) {
+	filter := audit.AuditFilter{
+		Entity: ctx.Query("entity"),
+		Actor:  ctx.Query("actor"),
+	}
+	listAudit(ctx, filter)
+}
+
+// GetEntityAudit lists the history of a single admin, product or variant.
+func GetEntityAudit(ctx *gin.Context) {
+	filter := audit.AuditFilter{
+		Entity:   ctx.Param("entity"),
+		EntityID: ctx.Param("entityUUID"),
+		Actor:    ctx.Query("actor"),
+	}
+	listAudit(ctx, filter)
+}
+
+func listAudit(ctx *gin.Context, filter audit.AuditFilter) {
+	if filter.Entity != "" && !validAuditEntity(filter.Entity) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid entity, must be admin, product or variant",
+		})
+		return
+	}
+	var ok bool
+	if filter.From, ok = auditTimeParam(ctx, "from"); !ok {
+		return
+	}
+	if filter.To, ok = auditTimeParam(ctx, "to"); !ok {
+		return
+	}
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	pageNum, _ := strconv.Atoi(ctx.DefaultQuery("pageNum", "1"))
+	if pageNum < 1 || pageSize < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid page number",
+		})
+		return
+	}
+	if pageSize > auditMaxPageSize {
+		pageSize = auditMaxPageSize
+	}
+	offset := (pageNum - 1) * pageSize
+
+	getEntries, total, err := services.GetAllAuditService(ctx.Request.Context(), dbConn, filter, pageSize, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully fetch audit log!",
+		"data":    getEntries,
+		"meta": gin.H{
+			"limit":     pageSize,
+			"offset":    offset,
+			"total":     total,
+			"totalPage": totalPages,
+		},
+	})
+}
+
+// auditTimeParam parses an optional RFC 3339 query parameter, writing the
+// error response itself when it is malformed.
+func auditTimeParam(ctx *gin.Context, param string) (*time.Time, bool) {
+	value := ctx.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid " + param + " value, use RFC 3339",
+		})
+		return nil, false
+	}
+	return &parsed, true
+}
+
+func validAuditEntity(entity string) bool {
+	for _, known := range audit.Entities {
+		if entity == known {
+			return true
+		}
+	}
+	return false
+}
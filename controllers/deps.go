@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+)
+
+// adminDeps pulls the database and the logged in admin's ID out of the
+// context, writing the error response itself when either is missing.
+func adminDeps(ctx *gin.Context) (*sql.DB, int, bool) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return nil, 0, false
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return nil, 0, false
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return nil, 0, false
+	}
+	return dbConn, int(adminIdFloat64), true
+}
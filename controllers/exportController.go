@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/product"
+	"basic-trade-api/services"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushEvery is how many rows are written between flushes to the client.
+const exportFlushEvery = 1000
+
+func ExportProducts(ctx *gin.Context) {
+	filter := product.ProductFilter{
+		Name:     ctx.Query("name"),
+		Status:   ctx.Query("status"),
+		ViewerID: viewerAdminID(ctx),
+	}
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	format, ok := helpers.ExportFormats[ctx.DefaultQuery("format", "csv")]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid format. Supported formats are csv, jsonl and xlsx",
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), format.Extension)
+	ctx.Header("Content-Type", format.ContentType)
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	ctx.Status(http.StatusOK)
+
+	exporter, err := format.New(ctx.Writer, product.ExportHeader)
+	if err != nil {
+		log.Println("export:", err)
+		return
+	}
+
+	// Headers are already sent, so failures past this point can only be logged
+	// and the response cut short.
+	written := 0
+	err = services.ExportProductService(ctx.Request.Context(), dbConn, filter, func(row product.ProductExportRow) error {
+		if err := exporter.Write(row); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			ctx.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("export:", err)
+		return
+	}
+
+	if err := exporter.Close(); err != nil {
+		log.Println("export:", err)
+	}
+}
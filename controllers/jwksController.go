@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"basic-trade-api/helpers"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS publishes the public keys access tokens can be verified with.
+func GetJWKS(ctx *gin.Context) {
+	// Short enough that a key published ahead is picked up before it is used
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"keys": helpers.JWTKeys.JWKS()})
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/services"
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie ties a callback to the browser that started the login, so
+// an attacker cannot log a victim in to the attacker's account
+const oidcStateCookie = "oidc_state"
+
+// oidcDeps pulls the database and identity provider out of the context,
+// writing the error response itself when either is missing.
+func oidcDeps(ctx *gin.Context) (*sql.DB, *helpers.OIDCProvider, bool) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return nil, nil, false
+	}
+	provider, _ := ctx.MustGet("oidcProvider").(*helpers.OIDCProvider)
+	if provider == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Single sign-on not configured",
+			"message": "Set OIDC_ISSUER_URL and OIDC_CLIENT_ID to enable it",
+		})
+		return nil, nil, false
+	}
+	return dbConn, provider, true
+}
+
+// OIDCLogin sends the browser to the identity provider. returnTo, if given,
+// must be a page of the admin front end; the callback sends the browser
+// there with the access token, or the two-factor challenge token, in the URL
+// fragment.
+func OIDCLogin(ctx *gin.Context) {
+	dbConn, provider, ok := oidcDeps(ctx)
+	if !ok {
+		return
+	}
+
+	returnTo := ctx.Query("returnTo")
+	if returnTo != "" && !allowedReturnTo(returnTo) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid returnTo",
+			"message": "returnTo must be a page under APP_BASE_URL",
+		})
+		return
+	}
+
+	nonce, err := helpers.RandomToken(16)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	verifier, challenge, err := helpers.NewPKCEVerifier()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+	state, err := services.CreateOIDCStateService(ctx.Request.Context(), dbConn, nonce, verifier, returnTo)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx.Request.Context(), state, nonce, challenge)
+	if err != nil {
+		log.Println("oidc login:", err)
+		ctx.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Identity provider unavailable",
+			"message": "Could not reach the identity provider, please try again later",
+		})
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, state, 600, "/auth/oidc", "", isHTTPS(ctx), true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes a login at the identity provider and issues this
+// API's own access token. Admins with two-factor on get a challenge to
+// complete at /auth/login/2fa first, as after a password, unless
+// OIDC_TRUST_IDP_MFA leaves that to the identity provider.
+func OIDCCallback(ctx *gin.Context) {
+	dbConn, provider, ok := oidcDeps(ctx)
+	if !ok {
+		return
+	}
+
+	state := ctx.Query("state")
+	cookie, _ := ctx.Cookie(oidcStateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", isHTTPS(ctx), true)
+	if state == "" || cookie != state {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid state",
+			"message": "The login was started in another browser or has expired, please start again",
+		})
+		return
+	}
+
+	oidcState, err := services.ConsumeOIDCStateService(ctx.Request.Context(), dbConn, state)
+	if err != nil {
+		if err.Error() == "invalid or expired state" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid state",
+				"message": "The login has expired or was already used, please start again",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	if idpError := ctx.Query("error"); idpError != "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Login refused by identity provider",
+			"message": idpError + ": " + ctx.Query("error_description"),
+		})
+		return
+	}
+
+	identity, err := provider.Exchange(ctx.Request.Context(), ctx.Query("code"), oidcState.Verifier, oidcState.Nonce)
+	if err != nil {
+		log.Println("oidc callback:", err)
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Login failed",
+			"message": "The identity provider's answer could not be verified",
+		})
+		return
+	}
+
+	adminResponse, err := services.OIDCLoginService(ctx.Request.Context(), dbConn, identity, configs.EnvOIDCAllowedDomains(), configs.EnvOIDCAutoProvision())
+	if err != nil {
+		switch err.Error() {
+		case "email not verified by identity provider", "email domain not allowed", "no admin for identity", "account deactivated":
+			ctx.JSON(http.StatusForbidden, gin.H{
+				"error":   "Login not allowed",
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		}
+		return
+	}
+
+	challenged := adminResponse.TwoFactorEnabled && !configs.EnvOIDCTrustIdPMFA()
+	if oidcState.ReturnTo == "" {
+		if challenged {
+			loginChallenged(ctx, dbConn, adminResponse)
+		} else {
+			loginSucceeded(ctx, adminResponse)
+		}
+		return
+	}
+
+	// A fragment never reaches server logs or Referer headers
+	if challenged {
+		challenge, ok := loginChallenge(ctx, dbConn, adminResponse)
+		if ok {
+			ctx.Redirect(http.StatusFound, oidcState.ReturnTo+"#challengeToken="+url.QueryEscape(challenge))
+		}
+		return
+	}
+	token, ok := loginToken(ctx, adminResponse)
+	if ok {
+		ctx.Redirect(http.StatusFound, oidcState.ReturnTo+"#accessToken="+url.QueryEscape(token))
+	}
+}
+
+func allowedReturnTo(returnTo string) bool {
+	base := configs.EnvAppBaseURL()
+	return (returnTo == base || strings.HasPrefix(returnTo, base+"/")) && !strings.Contains(returnTo, "#")
+}
+
+func isHTTPS(ctx *gin.Context) bool {
+	return ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -5,9 +5,9 @@ import (
 	"basic-trade-api/models/product"
 	"basic-trade-api/services"
 	"database/sql"
+	"errors"
 	"math"
 	"strconv"
-	"strings"
 
 	"net/http"
 
@@ -63,29 +63,25 @@ func CreateProduct(ctx *gin.Context) {
 
 	// Check if productRequest.ImageFile is not nil
 	if productRequest.ImageFile != nil {
-		// Check if the uploaded file is an image (JPG, JPEG, PNG)
-		contentType := productRequest.ImageFile.Header.Get("Content-Type")
-		if !strings.HasPrefix(contentType, "image/jpeg") &&
-			!strings.HasPrefix(contentType, "image/jpg") &&
-			!strings.HasPrefix(contentType, "image/png") {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file format. Only JPG, JPEG, and PNG images are allowed."})
-			return
-		}
-
-		fileName := helpers.RemoveExtension(productRequest.ImageFile.Filename)
-		// Assign the result of UploadFile to uploadResult
-		var err error
-		uploadResult, err = helpers.UploadFile(productRequest.ImageFile, fileName)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		image, ok := uploadProductImage(ctx, productRequest.ImageFile)
+		if !ok {
 			return
 		}
+		uploadResult = image.URL
 		// Set uploaded file URL in the product request
 		productRequest.ImageURL = uploadResult
+		productRequest.Image = image
 	}
 
-	newProduct, err := services.CreateProductService(dbConn, productRequest, adminId)
+	newProduct, err := services.CreateProductService(ctx.Request.Context(), dbConn, productRequest, adminId)
 	if err != nil {
+		if productRequest.Image != nil {
+			deleteImageAsset(*productRequest.Image.PublicID)
+		}
+		if err.Error() == "unpublish must be after publish" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unpublishAt must be after publishAt"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
 		})
@@ -102,6 +98,11 @@ func CreateProduct(ctx *gin.Context) {
 			"adminId":   adminId,
 			"createdAt": newProduct.CreatedAt,
 			"updatedAt": newProduct.UpdatedAt,
+			"status":      newProduct.Status,
+			"publishAt":   newProduct.PublishAt,
+			"unpublishAt": newProduct.UnpublishAt,
+			"images":      newProduct.Images,
+			"options":     newProduct.Options,
 		},
 	}
 
@@ -109,7 +110,11 @@ func CreateProduct(ctx *gin.Context) {
 }
 
 func GetAllProduct(ctx *gin.Context) {
-    name := ctx.Query("name")
+    filter := product.ProductFilter{
+        Name:     ctx.Query("name"),
+        Status:   ctx.Query("status"),
+        ViewerID: viewerAdminID(ctx),
+    }
     db, _ := ctx.Get("db")
     dbConn, ok := db.(*sql.DB)
     if !ok {
@@ -131,7 +136,7 @@ func GetAllProduct(ctx *gin.Context) {
 
     offset := (pageNum - 1) * pageSize
 
-    getProducts, total, err := services.GetAllProductService(dbConn, pageSize, offset, name)
+    getProducts, total, err := services.GetAllProductService(ctx.Request.Context(), dbConn, pageSize, offset, filter)
     if err != nil {
         ctx.JSON(http.StatusInternalServerError, gin.H{
             "message": err.Error(),
@@ -166,7 +171,11 @@ func GetProductByID(ctx *gin.Context) {
 		return
 	}
 
-	getProduct, err := services.GetProductByIDService(dbConn, productUUID)
+	getProduct, err := services.GetProductByIDService(ctx.Request.Context(), dbConn, productUUID)
+	// Products that are not published do not exist as far as others can tell
+	if err == nil && !getProduct.VisibleTo(viewerAdminID(ctx)) {
+		err = errors.New("product not found")
+	}
 	if err != nil {
 		// Check if the error is due to product not found
 		if err.Error() == "product not found" {
@@ -239,20 +248,21 @@ func UpdateProduct(ctx *gin.Context) {
 
 	// Check if productRequest.ImageFile is not nil
 	if productRequest.ImageFile != nil {
-		fileName := helpers.RemoveExtension(productRequest.ImageFile.Filename)
-		// Assign the result of UploadFile to uploadResult
-		var err error
-		uploadResult, err = helpers.UploadFile(productRequest.ImageFile, fileName)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		image, ok := uploadProductImage(ctx, productRequest.ImageFile)
+		if !ok {
 			return
 		}
+		uploadResult = image.URL
 		// Set uploaded file URL in the product request
 		productRequest.ImageURL = uploadResult
+		productRequest.Image = image
 	}
 
-	editProduct, err := services.UpdateProductService(dbConn, productRequest, productUUID, adminId)
+	editProduct, err := services.UpdateProductService(ctx.Request.Context(), dbConn, productRequest, productUUID, adminId)
 	if err != nil {
+		if productRequest.Image != nil {
+			deleteImageAsset(*productRequest.Image.PublicID)
+		}
 		// Check if the error is due to product not found
 		if err.Error() == "product not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{
@@ -261,6 +271,10 @@ func UpdateProduct(ctx *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "unpublish must be after publish" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unpublishAt must be after publishAt"})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
 		})
@@ -276,6 +290,11 @@ func UpdateProduct(ctx *gin.Context) {
 			"adminId":   editProduct.AdminID,
 			"createdAt": editProduct.CreatedAt,
 			"updatedAt": editProduct.UpdatedAt,
+			"status":      editProduct.Status,
+			"publishAt":   editProduct.PublishAt,
+			"unpublishAt": editProduct.UnpublishAt,
+			"images":      editProduct.Images,
+			"options":     editProduct.Options,
 		},
 	}
 	ctx.JSON(http.StatusOK, responseData)
@@ -307,7 +326,7 @@ func DeleteProduct(ctx *gin.Context) {
 	}
 	adminId := int(adminIdFloat64)
 
-	_, err := services.DeleteProductService(dbConn, productUUID, adminId)
+	_, err := services.DeleteProductService(ctx.Request.Context(), dbConn, productUUID, adminId)
 	if err != nil {
 		if err.Error() == "product not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{
@@ -326,3 +345,19 @@ func DeleteProduct(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, responseData)
 }
+
+// viewerAdminID returns the ID of the admin behind a request to a public
+// endpoint, or zero when it was made anonymously. It must run after
+// OptionalAuthentication.
+func viewerAdminID(ctx *gin.Context) int {
+	adminData, ok := ctx.Get("adminData")
+	if !ok {
+		return 0
+	}
+	claims, ok := adminData.(jwt5.MapClaims)
+	if !ok {
+		return 0
+	}
+	adminIdFloat64, _ := claims["id"].(float64)
+	return int(adminIdFloat64)
+}
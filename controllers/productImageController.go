@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/product"
+	"basic-trade-api/services"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productImageError writes the response for errors shared by the product
+// image endpoints.
+func productImageError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Product not found",
+			"message": "Product with the specified UUID does not exist",
+		})
+	case "image not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Image not found",
+			"message": "The product has no image with that UUID",
+		})
+	case "upload not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Upload not found",
+			"message": "The product has no pending upload with that UUID",
+		})
+	case "upload expired":
+		ctx.JSON(http.StatusGone, gin.H{
+			"error":   "Upload expired",
+			"message": "The upload was not confirmed in time; request a new one",
+		})
+	case "image order does not match":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid image order",
+			"message": "imageUuids must list every image of the product once",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+// deleteImageAsset removes a stored image. The image is already gone from
+// the gallery, so a failure is only logged.
+func deleteImageAsset(publicID string) {
+	if err := helpers.DeleteFile(publicID); err != nil {
+		log.Printf("delete image %s: %v", publicID, err)
+	}
+}
+
+// imageFileError writes the response for an image that was refused.
+func imageFileError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "file too large":
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("File too large. Images may be at most %d bytes.", configs.EnvImageMaxBytes()),
+		})
+	case "unsupported image type":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file format. Only JPG, JPEG, PNG and WebP images are allowed."})
+	case "invalid image":
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "The file could not be read as an image."})
+	case "image dimensions too large":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Image too large. Width and height may be at most %d pixels.", configs.EnvImageMaxDimension()),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+	}
+}
+
+// imageLimits are the configured limits on uploaded images.
+func imageLimits() helpers.ImageLimits {
+	return helpers.ImageLimits{
+		MaxBytes:     configs.EnvImageMaxBytes(),
+		MaxDimension: configs.EnvImageMaxDimension(),
+	}
+}
+
+// productImageFromUploads makes a gallery image of the stored sizes of an
+// image.
+func productImageFromUploads(uploaded []helpers.UploadedImage) *product.ProductImage {
+	image := product.ProductImage{Sizes: make(map[string]product.ImageSize, len(uploaded))}
+	for _, u := range uploaded {
+		if u.Name == helpers.OriginalImageSize {
+			publicID := u.PublicID
+			image.URL, image.PublicID = u.URL, &publicID
+		}
+		image.Sizes[u.Name] = product.ImageSize{URL: u.URL, Width: u.Width, Height: u.Height}
+	}
+	return &image
+}
+
+// uploadProductImage checks an uploaded image, makes its thumbnails and
+// stores them all, writing the error response itself when it cannot.
+func uploadProductImage(ctx *gin.Context, fileHeader *multipart.FileHeader) (*product.ProductImage, bool) {
+	renditions, err := helpers.ProcessImage(fileHeader, imageLimits())
+	if err != nil {
+		imageFileError(ctx, err)
+		return nil, false
+	}
+
+	fileName := helpers.RemoveExtension(fileHeader.Filename)
+	uploaded, err := helpers.UploadFile(renditions, fileName)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	return productImageFromUploads(uploaded), true
+}
+
+func AddProductImage(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	imageRequest := ctx.MustGet("request").(product.ProductImageRequest)
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	uploaded, ok := uploadProductImage(ctx, imageRequest.ImageFile)
+	if !ok {
+		return
+	}
+	uploaded.AltText = imageRequest.AltText
+
+	image, err := services.AddProductImageService(ctx.Request.Context(), dbConn, productUUID, *uploaded, imageRequest.Primary, adminId)
+	if err != nil {
+		// Nothing refers to the upload yet
+		deleteImageAsset(*uploaded.PublicID)
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Successfully added product image!",
+		"data":    image,
+	})
+}
+
+func UpdateProductImage(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	imageUUID := ctx.Param("imageUUID")
+	imageRequest := ctx.MustGet("request").(product.UpdateProductImageRequest)
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	image, err := services.UpdateProductImageService(ctx.Request.Context(), dbConn, productUUID, imageUUID, imageRequest, adminId)
+	if err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully update the product image!",
+		"data":    image,
+	})
+}
+
+func ReorderProductImages(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	orderRequest := ctx.MustGet("request").(product.ReorderProductImagesRequest)
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	images, err := services.ReorderProductImagesService(ctx.Request.Context(), dbConn, productUUID, orderRequest.ImageUUIDs, adminId)
+	if err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully reorder the product images!",
+		"data":    images,
+	})
+}
+
+func DeleteProductImage(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	imageUUID := ctx.Param("imageUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	_, err := services.DeleteProductImageService(ctx.Request.Context(), dbConn, productUUID, imageUUID, adminId)
+	if err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully delete the product image!",
+	})
+}
+
+// CreateProductImageUpload hands out signed parameters for uploading an
+// image straight to storage, so the file never passes through the API.
+func CreateProductImageUpload(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	signed, err := helpers.SignUpload()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return
+	}
+
+	upload := product.ImageUpload{PublicID: signed.PublicID, URL: signed.URL, Fields: signed.Fields}
+	if err := services.CreateImageUploadService(ctx.Request.Context(), dbConn, productUUID, &upload, configs.EnvImageUploadTTL(), adminId); err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Successfully create the image upload!",
+		"data":    upload,
+	})
+}
+
+// ConfirmProductImageUpload checks an image uploaded straight to storage and
+// adds it to the product's gallery. An image that fails the checks is
+// deleted so the upload can be retried before it expires.
+func ConfirmProductImageUpload(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	uploadUUID := ctx.Param("uploadUUID")
+	confirmRequest, ok := ctx.MustGet("request").(product.ConfirmImageUploadRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to ConfirmImageUploadRequest",
+		})
+		return
+	}
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	publicID, err := services.GetImageUploadService(ctx.Request.Context(), dbConn, productUUID, uploadUUID, adminId)
+	if err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	uploaded, err := helpers.VerifyUpload(publicID, imageLimits())
+	if err != nil {
+		switch err.Error() {
+		case "image not uploaded":
+			ctx.JSON(http.StatusConflict, gin.H{
+				"error":   "Image not uploaded",
+				"message": "Upload the image to storage before confirming it",
+			})
+		case "file too large", "unsupported image type", "image dimensions too large":
+			deleteImageAsset(publicID)
+			imageFileError(ctx, err)
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		}
+		return
+	}
+
+	uploadedImage := productImageFromUploads(uploaded)
+	uploadedImage.AltText = confirmRequest.AltText
+
+	image, err := services.ConfirmImageUploadService(ctx.Request.Context(), dbConn, productUUID, uploadUUID, *uploadedImage, confirmRequest.Primary, adminId)
+	if err != nil {
+		productImageError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Successfully added product image!",
+		"data":    image,
+	})
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"basic-trade-api/models/product"
+	"basic-trade-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// productOptionError writes the response for errors shared by the product
+// option endpoints.
+func productOptionError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Product not found",
+			"message": "Product with the specified UUID does not exist",
+		})
+	case "duplicate option", "duplicate option value":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid options",
+			"message": "Option names, and the values of each option, must differ ignoring case",
+		})
+	case "option in use", "option value in use":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Option in use",
+			"message": "Options and values used by a variant cannot be removed; change or delete the variant first",
+		})
+	case "variants have option values":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Variants have options",
+			"message": "Options cannot be added once variants have option values; clear or delete those variants first",
+		})
+	case "product has no options":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "No options",
+			"message": "Set the product's options before generating its variants",
+		})
+	case "variant matrix too large":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Too many variants",
+			"message": "The product's options make too many combinations to generate at once",
+		})
+	case "sku too long":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "SKU too long",
+			"message": "A generated SKU would be longer than 64 characters; use a shorter skuPrefix",
+		})
+	case "sku already exists", "option combination already exists":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func SetProductOptions(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	optionsRequest := ctx.MustGet("request").(product.ProductOptionsRequest)
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	options, err := services.SetProductOptionsService(ctx.Request.Context(), dbConn, productUUID, optionsRequest, adminId)
+	if err != nil {
+		productOptionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully set the product options!",
+		"data":    options,
+	})
+}
+
+// GenerateVariantMatrix creates the variants missing from the product's
+// option matrix and returns only those.
+func GenerateVariantMatrix(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	matrixRequest := ctx.MustGet("request").(product.VariantMatrixRequest)
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	variants, err := services.GenerateVariantMatrixService(ctx.Request.Context(), dbConn, productUUID, matrixRequest, adminId)
+	if err != nil {
+		productOptionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"message": "Successfully generated variants!",
+		"data":    variants,
+	})
+}
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"basic-trade-api/services"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+)
+
+// productRevisionError writes the response for errors shared by the product
+// revision endpoints.
+func productRevisionError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "product not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Product not found",
+			"message": "Product with the specified UUID does not exist",
+		})
+	case "revision not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Revision not found",
+			"message": "The product has no revision with that number",
+		})
+	case "variant has stock":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Variant has stock",
+			"message": "A variant added after this revision still has stock or reservations; remove them first",
+		})
+	case "variant moved to another product":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Variant moved",
+			"message": "A variant of this revision now belongs to another product",
+		})
+	case "revision conflicts with current data":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Revision conflicts",
+			"message": "An image, SKU, barcode or option combination of this revision is now used elsewhere",
+		})
+	case "revision option values no longer exist":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Option values missing",
+			"message": "An option value of this revision has since been removed; add it back first",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+// revisionParam parses a revision number, writing the error response itself
+// when it is malformed.
+func revisionParam(ctx *gin.Context, name, value string) (int, bool) {
+	rev, err := strconv.Atoi(value)
+	if err != nil || rev < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid " + name + " number",
+		})
+		return 0, false
+	}
+	return rev, true
+}
+
+func GetProductRevisions(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	pageNum, _ := strconv.Atoi(ctx.DefaultQuery("pageNum", "1"))
+	if pageNum < 1 || pageSize < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid page number",
+		})
+		return
+	}
+	offset := (pageNum - 1) * pageSize
+
+	getRevisions, total, err := services.GetProductRevisionsService(ctx.Request.Context(), dbConn, productUUID, pageSize, offset)
+	if err != nil {
+		productRevisionError(ctx, err)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully fetch product revisions!",
+		"data":    getRevisions,
+		"meta": gin.H{
+			"limit":     pageSize,
+			"offset":    offset,
+			"total":     total,
+			"totalPage": totalPages,
+		},
+	})
+}
+
+func GetProductRevision(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	rev, ok := revisionParam(ctx, "revision", ctx.Param("rev"))
+	if !ok {
+		return
+	}
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getRevision, err := services.GetProductRevisionService(ctx.Request.Context(), dbConn, productUUID, rev)
+	if err != nil {
+		productRevisionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully fetched specific product revision!",
+		"data":    getRevision,
+	})
+}
+
+// DiffProductRevisions compares the revisions given as the from and to query
+// parameters.
+func DiffProductRevisions(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	from, ok := revisionParam(ctx, "from revision", ctx.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := revisionParam(ctx, "to revision", ctx.Query("to"))
+	if !ok {
+		return
+	}
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	diff, err := services.DiffProductRevisionsService(ctx.Request.Context(), dbConn, productUUID, from, to)
+	if err != nil {
+		productRevisionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully compared product revisions!",
+		"data":    diff,
+	})
+}
+
+func RestoreProductRevision(ctx *gin.Context) {
+	productUUID := ctx.Param("productUUID")
+	rev, ok := revisionParam(ctx, "revision", ctx.Param("rev"))
+	if !ok {
+		return
+	}
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	restored, err := services.RestoreProductRevisionService(ctx.Request.Context(), dbConn, productUUID, rev, adminId)
+	if err != nil {
+		productRevisionError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully restored revision " + strconv.Itoa(rev) + "!",
+		"data":    restored,
+	})
+}
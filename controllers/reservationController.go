@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"basic-trade-api/models/reservation"
+	"basic-trade-api/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reservationError writes the response for errors shared by the
+// reservation endpoints.
+func reservationError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "variant not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Variant not found",
+			"message": "Variant with the specified UUID does not exist",
+		})
+	case "reservation not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Reservation not found",
+			"message": "Reservation with the specified UUID does not exist",
+		})
+	case "insufficient stock", "reservation is not held":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+	case "reservation expired":
+		ctx.JSON(http.StatusGone, gin.H{
+			"message": err.Error(),
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func CreateReservation(ctx *gin.Context) {
+	variantUUID := ctx.Param("variantUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	reservationRequest, ok := requestInterface.(reservation.ReservationRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *reservationRequest",
+		})
+		return
+	}
+
+	newReservation, err := services.ReserveStockService(ctx.Request.Context(), dbConn, variantUUID, reservationRequest, adminId)
+	if err != nil {
+		reservationError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully reserved stock!",
+		"data":    newReservation,
+	}
+	ctx.JSON(http.StatusCreated, responseData)
+}
+
+func GetReservationByID(ctx *gin.Context) {
+	reservationUUID := ctx.Param("reservationUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	getReservation, err := services.GetReservationByIDService(ctx.Request.Context(), dbConn, reservationUUID, adminId)
+	if err != nil {
+		reservationError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetched specific reservation!",
+		"data":    getReservation,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func ConfirmReservation(ctx *gin.Context) {
+	reservationUUID := ctx.Param("reservationUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	editReservation, err := services.ConfirmReservationService(ctx.Request.Context(), dbConn, reservationUUID, adminId)
+	if err != nil {
+		reservationError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully confirmed the reservation!",
+		"data":    editReservation,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func ReleaseReservation(ctx *gin.Context) {
+	reservationUUID := ctx.Param("reservationUUID")
+
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	editReservation, err := services.ReleaseReservationService(ctx.Request.Context(), dbConn, reservationUUID, adminId)
+	if err != nil {
+		reservationError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully released the reservation!",
+		"data":    editReservation,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
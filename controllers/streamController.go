@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"basic-trade-api/models/variant"
+	"basic-trade-api/services"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// streamHeartbeat is how often a comment is sent to keep idle streams
+	// open through proxies
+	streamHeartbeat = 15 * time.Second
+	// streamRetryMillis is the reconnection delay suggested to clients
+	streamRetryMillis = 3000
+)
+
+func StreamVariants(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+	hub, ok := ctx.MustGet("stockHub").(*services.StockHub)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast stock hub to *services.StockHub",
+		})
+		return
+	}
+
+	// productUUID may be repeated or comma separated
+	var productUUIDs []string
+	for _, value := range ctx.QueryArray("productUUID") {
+		for _, productUUID := range strings.Split(value, ",") {
+			if productUUID = strings.TrimSpace(productUUID); productUUID != "" {
+				productUUIDs = append(productUUIDs, productUUID)
+			}
+		}
+	}
+
+	// EventSource sends Last-Event-ID on reconnect; the query parameter is
+	// for clients that cannot set headers
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("lastEventId")
+	}
+	var resumeFrom int64
+	if lastEventID != "" {
+		var err error
+		resumeFrom, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || resumeFrom < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid Last-Event-ID",
+			})
+			return
+		}
+	}
+
+	// Subscribe before reading the backlog so no change falls in between
+	sub := hub.Subscribe(productUUIDs, viewerAdminID(ctx))
+	defer hub.Unsubscribe(sub)
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", streamRetryMillis)
+	ctx.Writer.Flush()
+
+	// Headers are already sent, so failures past this point can only be logged
+	// and the stream closed; the client reconnects and resumes.
+	reqCtx := ctx.Request.Context()
+	backlogUntil := resumeFrom
+	if lastEventID != "" {
+		for {
+			changes, err := services.GetStockChangesSinceService(reqCtx, dbConn, backlogUntil, productUUIDs)
+			if err != nil {
+				log.Println("variant stream:", err)
+				return
+			}
+			if len(changes) == 0 {
+				break
+			}
+			for _, change := range changes {
+				backlogUntil = change.ID
+				if !sub.Matches(change) {
+					continue
+				}
+				if err := writeStockChange(ctx, change); err != nil {
+					return
+				}
+			}
+			ctx.Writer.Flush()
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-reqCtx.Done():
+			return
+		case change, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind
+				return
+			}
+			if change.ID <= backlogUntil && lastEventID != "" {
+				// Already sent from the backlog
+				continue
+			}
+			if err := writeStockChange(ctx, change); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+func writeStockChange(ctx *gin.Context, change variant.StockChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "id: %d\nevent: stock\ndata: %s\n\n", change.ID, data)
+	return err
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"basic-trade-api/services"
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorError writes the response for errors shared by the two-factor
+// endpoints.
+func twoFactorError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "two-factor already enabled":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Two-factor already enabled",
+			"message": "Disable two-factor authentication before enrolling again",
+		})
+	case "two-factor not enrolled":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Two-factor not enrolled",
+			"message": "Start enrollment before confirming a code",
+		})
+	case "two-factor not enabled":
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "Two-factor not enabled",
+			"message": "Two-factor authentication is not enabled for this account",
+		})
+	case "invalid code":
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid code",
+			"message": "The code is wrong or has already been used",
+		})
+	case "invalid password":
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid password",
+			"message": "The password is wrong",
+		})
+	case "invalid or expired challenge":
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Invalid or expired challenge",
+			"message": "Log in with your password again",
+		})
+	case "admin not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Admin not found",
+			"message": "The admin of this token no longer exists",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func GetTwoFactorStatus(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := services.GetTwoFactorStatusService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Successfully retrieved two-factor status",
+		"data":    status,
+	})
+}
+
+func EnrollTwoFactor(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+
+	enrollment, err := services.EnrollTwoFactorService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Scan the QR code with your authenticator app, then confirm with a code from it",
+		"data":    enrollment,
+	})
+}
+
+func ConfirmTwoFactor(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+	codeRequest, ok := ctx.MustGet("request").(admin.TwoFactorCodeRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to TwoFactorCodeRequest",
+		})
+		return
+	}
+
+	codes, err := services.ConfirmTwoFactorService(ctx.Request.Context(), dbConn, adminId, codeRequest.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication enabled! Store the recovery codes now, they will not be shown again.",
+		"data":    gin.H{"recoveryCodes": codes},
+	})
+}
+
+func RegenerateRecoveryCodes(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+	codeRequest, ok := ctx.MustGet("request").(admin.TwoFactorCodeRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to TwoFactorCodeRequest",
+		})
+		return
+	}
+
+	codes, err := services.RegenerateRecoveryCodesService(ctx.Request.Context(), dbConn, adminId, codeRequest.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Recovery codes replaced! Store them now, they will not be shown again.",
+		"data":    gin.H{"recoveryCodes": codes},
+	})
+}
+
+func DisableTwoFactor(ctx *gin.Context) {
+	dbConn, adminId, ok := adminDeps(ctx)
+	if !ok {
+		return
+	}
+	disableRequest, ok := ctx.MustGet("request").(admin.DisableTwoFactorRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to DisableTwoFactorRequest",
+		})
+		return
+	}
+
+	err := services.DisableTwoFactorService(ctx.Request.Context(), dbConn, adminId, disableRequest.Password, disableRequest.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// CompleteTwoFactorLogin trades a login challenge and a code for an access
+// token.
+func CompleteTwoFactorLogin(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+	challengeRequest, ok := ctx.MustGet("request").(admin.TwoFactorLoginRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to TwoFactorLoginRequest",
+		})
+		return
+	}
+
+	adminResponse, err := services.CompleteLoginChallengeService(ctx.Request.Context(), dbConn, challengeRequest.ChallengeToken, challengeRequest.Code)
+	if err != nil {
+		twoFactorError(ctx, err)
+		return
+	}
+
+	loginSucceeded(ctx, adminResponse)
+}
+
+// loginSucceeded answers a completed login with an access token.
+func loginSucceeded(ctx *gin.Context, adminResponse *admin.AdminResponse) {
+	token, ok := loginToken(ctx, adminResponse)
+	if !ok {
+		return
+	}
+
+	responseData := gin.H{
+		"message": "User logged successfully",
+		"data": gin.H{
+			"email":       adminResponse.Email,
+			"name":        adminResponse.Name,
+			"accessToken": token,
+		},
+	}
+
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+// loginToken issues the access token for a completed login, writing the
+// error response itself when that fails.
+func loginToken(ctx *gin.Context, adminResponse *admin.AdminResponse) (string, bool) {
+	// Generate JWT token
+	token, err := helpers.GenerateToken(adminResponse.ID, adminResponse.Email)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return "", false
+	}
+	return token, true
+}
+
+// loginChallenged answers a correct password from an admin with two-factor
+// on: no access token yet, only a challenge to complete with a code.
+func loginChallenged(ctx *gin.Context, dbConn *sql.DB, adminResponse *admin.AdminResponse) {
+	challenge, ok := loginChallenge(ctx, dbConn, adminResponse)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication required",
+		"data": gin.H{
+			"twoFactorRequired": true,
+			"challengeToken":    challenge,
+			"expiresIn":         int(configs.EnvLoginChallengeTTL().Seconds()),
+		},
+	})
+}
+
+// loginChallenge creates the two-factor challenge of a login, writing the
+// error response itself when that fails.
+func loginChallenge(ctx *gin.Context, dbConn *sql.DB, adminResponse *admin.AdminResponse) (string, bool) {
+	challenge, err := services.CreateLoginChallengeService(ctx.Request.Context(), dbConn, adminResponse.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return challenge, true
+}
@@ -1,12 +1,14 @@
 package controllers
 
 import (
+	"basic-trade-api/helpers"
 	"basic-trade-api/models/variant"
 	"basic-trade-api/services"
 	"database/sql"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	jwt5 "github.com/golang-jwt/jwt/v5"
@@ -54,12 +56,24 @@ func CreateVariant(ctx *gin.Context) {
 	}
 	adminID := int(adminIDFloat64)
 
-	newVariant, err := services.CreateVariantService(dbConn, variantRequest, adminID)
+	newVariant, err := services.CreateVariantService(ctx.Request.Context(), dbConn, variantRequest, adminID)
 	if err != nil {
 		if err.Error() == "product does not belong to the admin" {
 			ctx.JSON(http.StatusBadRequest, gin.H{
 				"message": err.Error(),
 			})
+		} else if err.Error() == "warehouse not found" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		} else if err.Error() == "option not found" || err.Error() == "option value not found" || err.Error() == "missing option value" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		} else if err.Error() == "sku already exists" || err.Error() == "barcode already exists" || err.Error() == "option combination already exists" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
 		} else {
 			ctx.JSON(http.StatusInternalServerError, gin.H{
 				"message": err.Error(),
@@ -71,13 +85,19 @@ func CreateVariant(ctx *gin.Context) {
 	responseData := gin.H{
 		"message": "Successfully created variant!",
 		"data": gin.H{
-			"id":          newVariant.ID,
-			"uuid":        newVariant.UUID,
-			"variantName": newVariant.VariantName,
-			"quantity":    newVariant.Quantity,
-			"productId":   newVariant.ProductID,
-			"createdAt":   newVariant.CreatedAt,
-			"updatedAt":   newVariant.UpdatedAt,
+			"id":           newVariant.ID,
+			"uuid":         newVariant.UUID,
+			"variantName":  newVariant.VariantName,
+			"sku":          newVariant.SKU,
+			"barcode":      newVariant.Barcode,
+			"quantity":     newVariant.Quantity,
+			"reserved":     newVariant.Reserved,
+			"available":    newVariant.Available,
+			"reorderPoint": newVariant.ReorderPoint,
+			"productId":    newVariant.ProductID,
+			"options":      newVariant.Options,
+			"createdAt":    newVariant.CreatedAt,
+			"updatedAt":    newVariant.UpdatedAt,
 		},
 	}
 
@@ -85,7 +105,41 @@ func CreateVariant(ctx *gin.Context) {
 }
 
 func GetAllVariant(ctx *gin.Context) {
-	variantName := ctx.Query("variantName")
+	filter := variant.VariantFilter{
+		VariantName: ctx.Query("variantName"),
+		Warehouse:   ctx.Query("warehouse"),
+		ViewerID:    viewerAdminID(ctx),
+	}
+	if inStockParam := ctx.Query("inStock"); inStockParam != "" {
+		inStock, err := strconv.ParseBool(inStockParam)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid inStock value",
+			})
+			return
+		}
+		filter.InStock = &inStock
+	}
+	// options[Color]=Red,Blue keeps variants whose Color is Red or Blue
+	for name, param := range ctx.QueryMap("options") {
+		var values []string
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		if name == "" || len(values) == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": "Invalid options value",
+			})
+			return
+		}
+		if filter.Options == nil {
+			filter.Options = make(map[string][]string)
+		}
+		filter.Options[name] = values
+	}
+
 	db, _ := ctx.Get("db")
 	dbConn, ok := db.(*sql.DB)
 	if !ok {
@@ -96,14 +150,14 @@ func GetAllVariant(ctx *gin.Context) {
 	}
 
 	pageSize := 10
-	pageNum, _ := strconv.Atoi(ctx.Param("pageNum"))
+	pageNum, _ := strconv.Atoi(ctx.Query("pageNum"))
 	offset := (pageNum - 1) * pageSize
 
 	if offset < 0 {
 		offset = 0
 	}
 
-	getVariants, total, err := services.GetAllVariantService(dbConn, pageSize, offset, variantName)
+	getVariants, total, err := services.GetAllVariantService(ctx.Request.Context(), dbConn, pageSize, offset, filter)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
@@ -126,6 +180,45 @@ func GetAllVariant(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, responseData)
 }
 
+func GetLowStockVariants(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	getVariants, err := services.GetLowStockVariantsService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetch low-stock variants!",
+		"data":    getVariants,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
 func GetVariantByID(ctx *gin.Context) {
 	variantUUID := ctx.Param("variantUUID")
 
@@ -138,7 +231,7 @@ func GetVariantByID(ctx *gin.Context) {
 		return
 	}
 
-	getVariant, err := services.GetVariantByIDService(dbConn, variantUUID)
+	getVariant, err := services.GetVariantByIDService(ctx.Request.Context(), dbConn, variantUUID, viewerAdminID(ctx))
 	if err != nil {
 		// Check if the error is due to product not found
 		if err.Error() == "variant not found" {
@@ -162,6 +255,103 @@ func GetVariantByID(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, responseData)
 }
 
+func LookupVariant(ctx *gin.Context) {
+	sku := ctx.Query("sku")
+	barcode := ctx.Query("barcode")
+	if (sku == "") == (barcode == "") {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Provide exactly one of sku or barcode",
+		})
+		return
+	}
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getVariant, err := services.GetVariantByLookupService(ctx.Request.Context(), dbConn, sku, barcode, viewerAdminID(ctx))
+	if err != nil {
+		if err.Error() == "variant not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Variant not found",
+				"message": "No variant matches the specified sku or barcode",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetched specific variant!",
+		"data":    getVariant,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetVariantBarcode(ctx *gin.Context) {
+	variantUUID := ctx.Param("variantUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getVariant, err := services.GetVariantByIDService(ctx.Request.Context(), dbConn, variantUUID, viewerAdminID(ctx))
+	if err != nil {
+		if err.Error() == "variant not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Variant not found",
+				"message": "Variant with the specified UUID does not exist",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if getVariant.Barcode == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Barcode not found",
+			"message": "Variant has no barcode",
+		})
+		return
+	}
+
+	modules, err := helpers.EncodeGTIN(*getVariant.Barcode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	switch ctx.DefaultQuery("format", "png") {
+	case "png":
+		ctx.Header("Content-Type", "image/png")
+		helpers.RenderBarcodePNG(ctx.Writer, modules, 2, 80)
+	case "svg":
+		ctx.Header("Content-Type", "image/svg+xml")
+		helpers.RenderBarcodeSVG(ctx.Writer, modules, *getVariant.Barcode, 2, 80)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid format. Supported formats are png and svg",
+		})
+	}
+}
+
 func UpdateVariant(ctx *gin.Context) {
 	variantUUID := ctx.Param("variantUUID")
 
@@ -205,7 +395,7 @@ func UpdateVariant(ctx *gin.Context) {
 	}
 	adminId := int(adminIdFloat64)
 
-	editVariant, err := services.UpdateVariantService(dbConn, variantRequest, variantUUID, adminId)
+	editVariant, err := services.UpdateVariantService(ctx.Request.Context(), dbConn, variantRequest, variantUUID, adminId)
 	if err != nil {
 		// Check if the error is due to product not found
 		if err.Error() == "variant not found" {
@@ -215,6 +405,24 @@ func UpdateVariant(ctx *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "warehouse not found" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "option not found" || err.Error() == "option value not found" || err.Error() == "missing option value" {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "sku already exists" || err.Error() == "barcode already exists" || err.Error() == "option combination already exists" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"message": err.Error(),
 		})
@@ -224,13 +432,19 @@ func UpdateVariant(ctx *gin.Context) {
 	responseData := gin.H{
 		"message": "Successfully update the variant!",
 		"data": gin.H{
-			"id":          editVariant.ID,
-			"uuid":        editVariant.UUID,
-			"variantName": editVariant.VariantName,
-			"quantity":    editVariant.Quantity,
-			"productId":   editVariant.ProductID,
-			"createdAt":   editVariant.CreatedAt,
-			"updatedAt":   editVariant.UpdatedAt,
+			"id":           editVariant.ID,
+			"uuid":         editVariant.UUID,
+			"variantName":  editVariant.VariantName,
+			"sku":          editVariant.SKU,
+			"barcode":      editVariant.Barcode,
+			"quantity":     editVariant.Quantity,
+			"reserved":     editVariant.Reserved,
+			"available":    editVariant.Available,
+			"reorderPoint": editVariant.ReorderPoint,
+			"productId":    editVariant.ProductID,
+			"options":      editVariant.Options,
+			"createdAt":    editVariant.CreatedAt,
+			"updatedAt":    editVariant.UpdatedAt,
 		},
 	}
 	ctx.JSON(http.StatusOK, responseData)
@@ -262,7 +476,7 @@ func DeleteVariant(ctx *gin.Context) {
 	}
 	adminId := int(adminIdFloat64)
 
-	err := services.DeleteVariantService(dbConn, variantUUID, adminId)
+	err := services.DeleteVariantService(ctx.Request.Context(), dbConn, variantUUID, adminId)
 	if err != nil {
 		if err.Error() == "variant not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{
@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"basic-trade-api/models/warehouse"
+	"basic-trade-api/services"
+	"database/sql"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+)
+
+func CreateWarehouse(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	warehouseRequest, ok := requestInterface.(warehouse.WarehouseRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *warehouseRequest",
+		})
+		return
+	}
+
+	newWarehouse, err := services.CreateWarehouseService(ctx.Request.Context(), dbConn, warehouseRequest)
+	if err != nil {
+		if err.Error() == "warehouse code already exists" {
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully created warehouse!",
+		"data":    newWarehouse,
+	}
+	ctx.JSON(http.StatusCreated, responseData)
+}
+
+func GetAllWarehouse(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getWarehouses, err := services.GetAllWarehouseService(ctx.Request.Context(), dbConn)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetch warehouses!",
+		"data":    getWarehouses,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetWarehouseByID(ctx *gin.Context) {
+	warehouseUUID := ctx.Param("warehouseUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getWarehouse, err := services.GetWarehouseByIDService(ctx.Request.Context(), dbConn, warehouseUUID)
+	if err != nil {
+		if err.Error() == "warehouse not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Warehouse not found",
+				"message": "Warehouse with the specified UUID does not exist",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetched specific warehouse!",
+		"data":    getWarehouse,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func UpdateWarehouse(ctx *gin.Context) {
+	warehouseUUID := ctx.Param("warehouseUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	warehouseRequest, ok := requestInterface.(warehouse.WarehouseRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *warehouseRequest",
+		})
+		return
+	}
+
+	editWarehouse, err := services.UpdateWarehouseService(ctx.Request.Context(), dbConn, warehouseRequest, warehouseUUID)
+	if err != nil {
+		switch err.Error() {
+		case "warehouse not found":
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Warehouse not found",
+				"message": "Warehouse with the specified UUID does not exist",
+			})
+		case "warehouse code already exists":
+			ctx.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully update the warehouse!",
+		"data":    editWarehouse,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetVariantStock(ctx *gin.Context) {
+	variantUUID := ctx.Param("variantUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	getStocks, err := services.GetVariantStockService(ctx.Request.Context(), dbConn, variantUUID, viewerAdminID(ctx))
+	if err != nil {
+		if err.Error() == "variant not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Variant not found",
+				"message": "Variant with the specified UUID does not exist",
+			})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetched variant stock!",
+		"data":    getStocks,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func SetVariantStock(ctx *gin.Context) {
+	variantUUID := ctx.Param("variantUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	stockRequest, ok := requestInterface.(warehouse.StockRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *stockRequest",
+		})
+		return
+	}
+
+	editStocks, err := services.SetVariantStockService(ctx.Request.Context(), dbConn, variantUUID, stockRequest)
+	if err != nil {
+		switch err.Error() {
+		case "variant not found":
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Variant not found",
+				"message": "Variant with the specified UUID does not exist",
+			})
+		case "warehouse not found":
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully update the variant stock!",
+		"data":    editStocks,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func TransferStock(ctx *gin.Context) {
+	variantUUID := ctx.Param("variantUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	transferRequest, ok := requestInterface.(warehouse.TransferRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *transferRequest",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	transfer, err := services.TransferStockService(ctx.Request.Context(), dbConn, variantUUID, transferRequest, adminId)
+	if err != nil {
+		switch err.Error() {
+		case "variant not found":
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "Variant not found",
+				"message": "Variant with the specified UUID does not exist",
+			})
+		case "warehouse not found", "insufficient stock":
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully transferred stock!",
+		"data":    transfer,
+	}
+	ctx.JSON(http.StatusCreated, responseData)
+}
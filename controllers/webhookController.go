@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"basic-trade-api/models/webhook"
+	"basic-trade-api/services"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+)
+
+// webhookError writes the response for errors shared by the webhook
+// endpoints.
+func webhookError(ctx *gin.Context, err error) {
+	switch err.Error() {
+	case "webhook not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Webhook not found",
+			"message": "Webhook with the specified UUID does not exist",
+		})
+	case "delivery not found":
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error":   "Delivery not found",
+			"message": "Delivery with the specified UUID does not exist",
+		})
+	case "webhook address not allowed":
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook URL",
+			"message": "The URL must be http or https and resolve only to public internet addresses",
+		})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": err.Error(),
+		})
+	}
+}
+
+func CreateWebhook(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	webhookRequest, ok := requestInterface.(webhook.WebhookRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *webhookRequest",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	newWebhook, err := services.CreateWebhookService(ctx.Request.Context(), dbConn, webhookRequest, adminId)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully created webhook! Store the secret now, it will not be shown again.",
+		"data":    newWebhook,
+	}
+	ctx.JSON(http.StatusCreated, responseData)
+}
+
+func GetAllWebhook(ctx *gin.Context) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	getWebhooks, err := services.GetAllWebhookService(ctx.Request.Context(), dbConn, adminId)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetch webhooks!",
+		"data":    getWebhooks,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetWebhookByID(ctx *gin.Context) {
+	webhookUUID := ctx.Param("webhookUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	getWebhook, err := services.GetWebhookByIDService(ctx.Request.Context(), dbConn, webhookUUID, adminId)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully fetched specific webhook!",
+		"data":    getWebhook,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func UpdateWebhook(ctx *gin.Context) {
+	webhookUUID := ctx.Param("webhookUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	requestInterface, ok := ctx.Get("request")
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Parsed data not found in context",
+		})
+		return
+	}
+
+	// Get the request data
+	webhookRequest, ok := requestInterface.(webhook.WebhookRequest)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast request to *webhookRequest",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	editWebhook, err := services.UpdateWebhookService(ctx.Request.Context(), dbConn, webhookRequest, webhookUUID, adminId)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully update the webhook!",
+		"data":    editWebhook,
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func DeleteWebhook(ctx *gin.Context) {
+	webhookUUID := ctx.Param("webhookUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	if err := services.DeleteWebhookService(ctx.Request.Context(), dbConn, webhookUUID, adminId); err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully delete the webhook!",
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func GetWebhookDeliveries(ctx *gin.Context) {
+	webhookUUID := ctx.Param("webhookUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("pageSize", "10"))
+	pageNum, _ := strconv.Atoi(ctx.DefaultQuery("pageNum", "1"))
+	if pageNum < 1 || pageSize < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"message": "Invalid page number",
+		})
+		return
+	}
+	offset := (pageNum - 1) * pageSize
+
+	getDeliveries, total, err := services.GetWebhookDeliveriesService(ctx.Request.Context(), dbConn, webhookUUID, adminId, pageSize, offset)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+	responseData := gin.H{
+		"message": "Successfully fetch webhook deliveries!",
+		"data":    getDeliveries,
+		"meta": gin.H{
+			"limit":     pageSize,
+			"offset":    offset,
+			"total":     total,
+			"totalPage": totalPages,
+		},
+	}
+	ctx.JSON(http.StatusOK, responseData)
+}
+
+func RedeliverWebhook(ctx *gin.Context) {
+	webhookUUID := ctx.Param("webhookUUID")
+	deliveryUUID := ctx.Param("deliveryUUID")
+
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	newDelivery, err := services.RedeliverWebhookService(ctx.Request.Context(), dbConn, webhookUUID, deliveryUUID, adminId)
+	if err != nil {
+		webhookError(ctx, err)
+		return
+	}
+
+	responseData := gin.H{
+		"message": "Successfully queued the redelivery!",
+		"data":    newDelivery,
+	}
+	ctx.JSON(http.StatusAccepted, responseData)
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/models/notification"
+	"basic-trade-api/services"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is the time allowed to write one message
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long the connection may stay silent, pongs included
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod must be shorter than wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize is the largest message accepted from a client
+	wsMaxMessageSize = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkWebSocketOrigin,
+}
+
+// checkWebSocketOrigin allows same-origin browsers, the origins listed in
+// WS_ALLOWED_ORIGINS and clients that send no Origin at all.
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range configs.EnvWSAllowedOrigins() {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsReply is sent to the client in answer to a ClientMessage.
+type wsReply struct {
+	Type   string   `json:"type"`
+	Topics []string `json:"topics,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func AdminWebSocket(ctx *gin.Context) {
+	hub, ok := ctx.MustGet("notificationHub").(*services.NotificationHub)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast notification hub to *services.NotificationHub",
+		})
+		return
+	}
+
+	adminData, ok := ctx.MustGet("adminData").(jwt5.MapClaims)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to extract admin data",
+		})
+		return
+	}
+	adminIdFloat64, ok := adminData["id"].(float64)
+	if !ok {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid admin ID"})
+		return
+	}
+	adminId := int(adminIdFloat64)
+
+	// Upgrade writes its own error response
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub := hub.Subscribe(adminId)
+	defer hub.Unsubscribe(sub)
+
+	// Only this goroutine writes to conn; the reader hands its replies over
+	replies := make(chan wsReply, 8)
+	done := make(chan struct{})
+	go readWebSocket(conn, sub, replies, done)
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case n, ok := <-sub.C:
+			if !ok {
+				// Dropped by the hub for not keeping up; the client should
+				// reconnect
+				closeWebSocket(conn, websocket.CloseTryAgainLater, "too slow")
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(n); err != nil {
+				return
+			}
+		case reply := <-replies:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(reply); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readWebSocket handles subscription messages until the connection fails or
+// goes quiet for longer than wsPongWait, then closes done.
+func readWebSocket(conn *websocket.Conn, sub *services.NotificationSubscription, replies chan<- wsReply, done chan<- struct{}) {
+	defer close(done)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var reply wsReply
+		var message notification.ClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			message.Type = ""
+		}
+		switch message.Type {
+		case "subscribe":
+			sub.Follow(message.Topics)
+			reply = wsReply{Type: "subscribed", Topics: sub.Topics()}
+		case "unsubscribe":
+			sub.Unfollow(message.Topics)
+			reply = wsReply{Type: "subscribed", Topics: sub.Topics()}
+		default:
+			reply = wsReply{Type: "error", Error: "unknown message type"}
+		}
+
+		// A client that floods requests without reading the replies loses
+		// the replies, not the connection
+		select {
+		case replies <- reply:
+		default:
+		}
+	}
+}
+
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsWriteWait))
+}
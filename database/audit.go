@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// Audit describes who is behind the changes made with a context. The
+// audit_log triggers record it with every change to an audited table.
+type Audit struct {
+	ActorID    int
+	APIKeyUUID string
+	RequestID  string
+	IPAddress  string
+}
+
+type auditKey struct{}
+
+// WithAudit returns a copy of ctx whose transactions are recorded as made by
+// audit.
+func WithAudit(ctx context.Context, audit Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, audit)
+}
+
+// AuditFrom returns what WithAudit stored in ctx, if anything.
+func AuditFrom(ctx context.Context) (Audit, bool) {
+	audit, ok := ctx.Value(auditKey{}).(Audit)
+	return audit, ok
+}
+
+// setAudit hands the context's audit details to the triggers as settings
+// local to tx.
+func setAudit(ctx context.Context, tx *sql.Tx) error {
+	audit, ok := AuditFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	var actorID string
+	if audit.ActorID != 0 {
+		actorID = strconv.Itoa(audit.ActorID)
+	}
+	query := `
+		SELECT set_config('audit.actor_id', $1, true), set_config('audit.api_key_uuid', $2, true),
+			set_config('audit.request_id', $3, true), set_config('audit.ip_address', $4, true)
+	`
+	_, err := tx.ExecContext(ctx, query, actorID, audit.APIKeyUUID, audit.RequestID, audit.IPAddress)
+	return err
+}
@@ -32,13 +32,7 @@ func StartDB() *sql.DB {
 	// Constructing connection string
 	// connStr := fmt.Sprintf("postgresql://%s:%s@%s/%s?sslmode=%s", user, password, host, dbname, sslmode)
 
-	host := os.Getenv("DB_HOST")
-	user := os.Getenv("DB_USERNAME")
-	password := os.Getenv("DB_PASSWORD")
-	port := os.Getenv("DB_PORT")
-	dbname := os.Getenv("DB_NAME")
-
-	connStr := fmt.Sprintf("host=%s port=%v user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+	connStr := ConnString()
 
 	// Opening a connection to the database
 	db, err := sql.Open("postgres", connStr)
@@ -69,3 +63,15 @@ func StartDB() *sql.DB {
 
 	return db
 }
+
+// ConnString builds the connection string from the DB_* environment
+// variables. It is also used to open dedicated LISTEN connections.
+func ConnString() string {
+	host := os.Getenv("DB_HOST")
+	user := os.Getenv("DB_USERNAME")
+	password := os.Getenv("DB_PASSWORD")
+	port := os.Getenv("DB_PORT")
+	dbname := os.Getenv("DB_NAME")
+
+	return fmt.Sprintf("host=%s port=%v user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// txMaxAttempts is how many times WithTxOptions runs fn when the transaction
+// loses a serialization conflict or a deadlock.
+const txMaxAttempts = 3
+
+// WithTx runs fn in a read committed transaction on db. See WithTxOptions.
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return WithTxOptions(ctx, db, nil, fn)
+}
+
+// WithTxOptions runs fn in a transaction on db and commits it if fn returns
+// nil; any error, panic or cancellation of ctx rolls it back. When Postgres
+// aborts the transaction with a serialization failure or deadlock, fn is run
+// again from the start, so it must not have side effects outside tx. Changes
+// made in tx are audited as made by the Audit in ctx, if any.
+func WithTxOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= txMaxAttempts; attempt++ {
+		err = runTx(ctx, db, opts, fn)
+		if !isRetryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once Commit has succeeded
+	defer tx.Rollback()
+
+	if err := setAudit(ctx, tx); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isRetryable reports whether err aborted the transaction only because of a
+// conflict with a concurrent one.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// IsUniqueViolation reports whether err is a unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// UniqueViolation returns the name of the constraint or unique index err
+// violated, or "" if err is not a unique violation.
+func UniqueViolation(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return pqErr.Constraint
+	}
+	return ""
+}
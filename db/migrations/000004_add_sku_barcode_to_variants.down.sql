@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uq_variant_barcode;
+ALTER TABLE variants DROP COLUMN IF EXISTS barcode;
+ALTER TABLE variants DROP COLUMN IF EXISTS sku;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE variants ADD COLUMN sku VARCHAR(64);
+UPDATE variants SET sku = 'SKU-' || id;
+ALTER TABLE variants ALTER COLUMN sku SET NOT NULL;
+ALTER TABLE variants ADD CONSTRAINT uq_variant_sku UNIQUE (sku);
+ALTER TABLE variants ADD COLUMN barcode VARCHAR(14);
+CREATE UNIQUE INDEX uq_variant_barcode ON variants (LPAD(barcode, 14, '0'));
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_warehouse_stocks_quantity ON warehouse_stocks;
+DROP FUNCTION IF EXISTS sync_variant_quantity();
+DROP TABLE IF EXISTS stock_transfers;
+DROP TABLE IF EXISTS warehouse_stocks;
+DROP TABLE IF EXISTS warehouses;
\ No newline at end of file
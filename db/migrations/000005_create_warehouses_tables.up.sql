@@ -0,0 +1,65 @@
+CREATE TABLE warehouses (
+    id SERIAL PRIMARY KEY,
+    uuid UUID DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(32) UNIQUE NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uq_warehouse_default ON warehouses (is_default) WHERE is_default;
+
+CREATE TABLE warehouse_stocks (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INTEGER NOT NULL,
+    variant_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_warehouse_stock UNIQUE (warehouse_id, variant_id),
+    CONSTRAINT fk_stock_warehouse FOREIGN KEY (warehouse_id) REFERENCES warehouses(id),
+    CONSTRAINT fk_stock_variant FOREIGN KEY (variant_id) REFERENCES variants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE stock_transfers (
+    id SERIAL PRIMARY KEY,
+    uuid UUID DEFAULT gen_random_uuid(),
+    variant_id INTEGER NOT NULL,
+    from_warehouse_id INTEGER NOT NULL,
+    to_warehouse_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    admin_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_transfer_variant FOREIGN KEY (variant_id) REFERENCES variants(id) ON DELETE CASCADE,
+    CONSTRAINT fk_transfer_from FOREIGN KEY (from_warehouse_id) REFERENCES warehouses(id),
+    CONSTRAINT fk_transfer_to FOREIGN KEY (to_warehouse_id) REFERENCES warehouses(id),
+    CONSTRAINT fk_transfer_admin FOREIGN KEY (admin_id) REFERENCES admins(id)
+);
+
+-- Existing stock moves into a default warehouse
+INSERT INTO warehouses (name, code, is_default) VALUES ('Main warehouse', 'MAIN', TRUE);
+INSERT INTO warehouse_stocks (warehouse_id, variant_id, quantity)
+SELECT (SELECT id FROM warehouses WHERE is_default), id, quantity FROM variants;
+
+-- variants.quantity is kept as the total across all warehouses
+CREATE OR REPLACE FUNCTION sync_variant_quantity() RETURNS TRIGGER AS $$
+DECLARE
+    target_variant_id INTEGER;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        target_variant_id := OLD.variant_id;
+    ELSE
+        target_variant_id := NEW.variant_id;
+    END IF;
+
+    UPDATE variants
+    SET quantity = COALESCE((SELECT SUM(quantity) FROM warehouse_stocks WHERE variant_id = target_variant_id), 0),
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = target_variant_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_warehouse_stocks_quantity
+AFTER INSERT OR UPDATE OR DELETE ON warehouse_stocks
+FOR EACH ROW EXECUTE FUNCTION sync_variant_quantity();
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE stock_reservations (
+    id SERIAL PRIMARY KEY,
+    uuid UUID DEFAULT gen_random_uuid(),
+    variant_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'held',
+    reference VARCHAR(255),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_reservation_status CHECK (status IN ('held', 'confirmed', 'released', 'expired')),
+    CONSTRAINT fk_reservation_variant FOREIGN KEY (variant_id) REFERENCES variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_reservations_held_variant ON stock_reservations (variant_id) WHERE status = 'held';
+CREATE INDEX idx_reservations_held_expiry ON stock_reservations (expires_at) WHERE status = 'held';
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_variants_low_stock ON variants;
+DROP FUNCTION IF EXISTS record_low_stock_event();
+DROP TABLE IF EXISTS low_stock_events;
+ALTER TABLE variants DROP COLUMN IF EXISTS reorder_point;
\ No newline at end of file
@@ -0,0 +1,29 @@
+ALTER TABLE variants ADD COLUMN reorder_point INTEGER CHECK (reorder_point >= 0);
+
+CREATE TABLE low_stock_events (
+    id SERIAL PRIMARY KEY,
+    variant_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    reorder_point INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    CONSTRAINT fk_low_stock_variant FOREIGN KEY (variant_id) REFERENCES variants(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_low_stock_events_pending ON low_stock_events (id) WHERE processed_at IS NULL;
+
+-- Record an event only when a variant goes from above to below its reorder point
+CREATE OR REPLACE FUNCTION record_low_stock_event() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.reorder_point IS NOT NULL AND NEW.quantity < NEW.reorder_point
+        AND (OLD.reorder_point IS NULL OR OLD.quantity >= OLD.reorder_point) THEN
+        INSERT INTO low_stock_events (variant_id, quantity, reorder_point)
+        VALUES (NEW.id, NEW.quantity, NEW.reorder_point);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_variants_low_stock
+AFTER UPDATE OF quantity, reorder_point ON variants
+FOR EACH ROW EXECUTE FUNCTION record_low_stock_event();
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS uq_delivery_outbox_subscription;
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS fk_delivery_outbox;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS outbox_id;
+
+DROP TABLE IF EXISTS outbox;
\ No newline at end of file
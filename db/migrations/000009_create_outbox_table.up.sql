@@ -0,0 +1,21 @@
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    aggregate_type VARCHAR(32) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    published_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+
+-- Deliveries fanned out from an outbox event remember it so a relay retry
+-- does not queue the same delivery twice. Redeliveries leave it NULL.
+ALTER TABLE webhook_deliveries ADD COLUMN outbox_id BIGINT;
+ALTER TABLE webhook_deliveries ADD CONSTRAINT fk_delivery_outbox FOREIGN KEY (outbox_id) REFERENCES outbox(id) ON DELETE SET NULL;
+ALTER TABLE webhook_deliveries ADD CONSTRAINT uq_delivery_outbox_subscription UNIQUE (outbox_id, subscription_id);
\ No newline at end of file
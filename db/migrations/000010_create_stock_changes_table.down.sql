@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_variants_stock_change ON variants;
+DROP FUNCTION IF EXISTS record_stock_change();
+DROP TABLE IF EXISTS stock_changes;
\ No newline at end of file
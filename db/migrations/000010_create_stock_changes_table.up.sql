@@ -0,0 +1,42 @@
+CREATE TABLE stock_changes (
+    id BIGSERIAL PRIMARY KEY,
+    variant_id INTEGER NOT NULL,
+    variant_uuid UUID NOT NULL,
+    product_uuid UUID NOT NULL,
+    previous_quantity INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_changes_product ON stock_changes (product_uuid, id);
+CREATE INDEX idx_stock_changes_created_at ON stock_changes (created_at);
+
+-- Keep a short log of quantity changes for stream resumption and tell every
+-- listening API replica about each one. The notification is delivered when
+-- the transaction commits.
+CREATE OR REPLACE FUNCTION record_stock_change() RETURNS TRIGGER AS $$
+DECLARE
+    change stock_changes%ROWTYPE;
+BEGIN
+    INSERT INTO stock_changes (variant_id, variant_uuid, product_uuid, previous_quantity, quantity)
+    SELECT NEW.id, NEW.uuid, products.uuid, OLD.quantity, NEW.quantity
+    FROM products WHERE products.id = NEW.product_id
+    RETURNING * INTO change;
+
+    PERFORM pg_notify('stock_changes', json_build_object(
+        'id', change.id,
+        'variantId', change.variant_id,
+        'variantUuid', change.variant_uuid,
+        'productUuid', change.product_uuid,
+        'previousQuantity', change.previous_quantity,
+        'quantity', change.quantity,
+        'createdAt', to_char(change.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"')
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_variants_stock_change
+AFTER UPDATE OF quantity ON variants
+FOR EACH ROW WHEN (OLD.quantity IS DISTINCT FROM NEW.quantity)
+EXECUTE FUNCTION record_stock_change();
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE admins DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE admins DROP COLUMN IF EXISTS failed_login_attempts;
\ No newline at end of file
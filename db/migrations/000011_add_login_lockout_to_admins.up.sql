@@ -0,0 +1,2 @@
+ALTER TABLE admins ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE admins ADD COLUMN locked_until TIMESTAMP;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS admin_tokens;
+ALTER TABLE admins DROP COLUMN IF EXISTS email_verified_at;
\ No newline at end of file
@@ -0,0 +1,18 @@
+ALTER TABLE admins ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Admins created before verification existed are trusted as they are
+UPDATE admins SET email_verified_at = created_at;
+
+CREATE TABLE admin_tokens (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_admin_token_purpose CHECK (purpose IN ('password_reset', 'email_verification')),
+    CONSTRAINT fk_admin_token_admin FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_admin_tokens_admin ON admin_tokens (admin_id, purpose) WHERE used_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,8 @@
+DELETE FROM admin_tokens WHERE purpose = 'login_challenge';
+ALTER TABLE admin_tokens DROP CONSTRAINT chk_admin_token_purpose;
+ALTER TABLE admin_tokens ADD CONSTRAINT chk_admin_token_purpose CHECK (purpose IN ('password_reset', 'email_verification'));
+
+DROP TABLE IF EXISTS admin_recovery_codes;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS totp_secret;
\ No newline at end of file
@@ -0,0 +1,16 @@
+ALTER TABLE admins ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE admins ADD COLUMN totp_enabled_at TIMESTAMP;
+ALTER TABLE admins ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_admin_recovery_code UNIQUE (admin_id, code_hash),
+    CONSTRAINT fk_recovery_code_admin FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
+
+ALTER TABLE admin_tokens DROP CONSTRAINT chk_admin_token_purpose;
+ALTER TABLE admin_tokens ADD CONSTRAINT chk_admin_token_purpose CHECK (purpose IN ('password_reset', 'email_verification', 'login_challenge'));
\ No newline at end of file
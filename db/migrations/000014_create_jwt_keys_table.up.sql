@@ -0,0 +1,12 @@
+CREATE TABLE jwt_keys (
+    id SERIAL PRIMARY KEY,
+    kid VARCHAR(64) NOT NULL UNIQUE,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key TEXT NOT NULL,
+    active_from TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_jwt_key_algorithm CHECK (algorithm IN ('RS256', 'EdDSA'))
+);
+
+CREATE INDEX idx_jwt_keys_live ON jwt_keys (active_from) WHERE expires_at IS NULL;
\ No newline at end of file
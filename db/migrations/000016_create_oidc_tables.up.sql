@@ -0,0 +1,23 @@
+CREATE TABLE oidc_states (
+    id SERIAL PRIMARY KEY,
+    state_hash CHAR(64) NOT NULL UNIQUE,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    return_to VARCHAR(2048),
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_oidc_states_expires ON oidc_states (expires_at);
+
+CREATE TABLE admin_identities (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER NOT NULL,
+    issuer VARCHAR(2048) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_admin_identity UNIQUE (issuer, subject),
+    CONSTRAINT fk_identity_admin FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE CASCADE
+);
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE admins DROP COLUMN IF EXISTS tokens_valid_after;
+ALTER TABLE admins DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE admins DROP CONSTRAINT IF EXISTS chk_admin_role;
+ALTER TABLE admins DROP COLUMN IF EXISTS role;
\ No newline at end of file
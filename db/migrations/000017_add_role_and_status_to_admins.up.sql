@@ -0,0 +1,7 @@
+ALTER TABLE admins ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'admin';
+ALTER TABLE admins ADD CONSTRAINT chk_admin_role CHECK (role IN ('admin', 'superadmin'));
+ALTER TABLE admins ADD COLUMN deactivated_at TIMESTAMP;
+ALTER TABLE admins ADD COLUMN tokens_valid_after TIMESTAMP;
+
+-- The first admin becomes the one who manages the others
+UPDATE admins SET role = 'superadmin' WHERE id = (SELECT MIN(id) FROM admins);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_variants_audit ON variants;
+DROP TRIGGER IF EXISTS trg_products_audit ON products;
+DROP TRIGGER IF EXISTS trg_admins_audit ON admins;
+DROP FUNCTION IF EXISTS record_audit();
+DROP TABLE IF EXISTS audit_log;
\ No newline at end of file
@@ -0,0 +1,95 @@
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    actor_id INTEGER,
+    api_key_uuid UUID,
+    request_id VARCHAR(64),
+    ip_address VARCHAR(64),
+    action VARCHAR(8) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity VARCHAR(32) NOT NULL,
+    entity_id UUID NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id, id);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_id, id);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+
+-- Record every change to an audited table with who made it. The application
+-- describes the request with transaction-local audit.* settings; changes made
+-- without them, such as by background workers, have no actor.
+--
+-- Arguments: the entity name, a comma-separated list of columns to ignore and
+-- a comma-separated list of columns whose values must not be stored. An
+-- update that only touches ignored columns is not recorded, and only the
+-- columns an update changed are kept in before and after.
+CREATE OR REPLACE FUNCTION record_audit() RETURNS TRIGGER AS $$
+DECLARE
+    ignored TEXT[] := string_to_array(TG_ARGV[1], ',');
+    redacted TEXT[] := string_to_array(TG_ARGV[2], ',');
+    old_row JSONB;
+    new_row JSONB;
+    row_uuid UUID;
+    column_name TEXT;
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        old_row := to_jsonb(OLD) - ignored;
+        row_uuid := OLD.uuid;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        new_row := to_jsonb(NEW) - ignored;
+        row_uuid := NEW.uuid;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        FOR column_name IN SELECT jsonb_object_keys(new_row) LOOP
+            IF old_row -> column_name = new_row -> column_name THEN
+                old_row := old_row - column_name;
+                new_row := new_row - column_name;
+            END IF;
+        END LOOP;
+        IF new_row = '{}'::JSONB THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    -- A redacted column still shows that it changed
+    FOREACH column_name IN ARRAY COALESCE(redacted, '{}') LOOP
+        IF old_row ? column_name THEN
+            old_row := jsonb_set(old_row, ARRAY[column_name], '"[redacted]"');
+        END IF;
+        IF new_row ? column_name THEN
+            new_row := jsonb_set(new_row, ARRAY[column_name], '"[redacted]"');
+        END IF;
+    END LOOP;
+
+    INSERT INTO audit_log (actor_id, api_key_uuid, request_id, ip_address, action, entity, entity_id, before, after)
+    VALUES (
+        NULLIF(current_setting('audit.actor_id', true), '')::INTEGER,
+        NULLIF(current_setting('audit.api_key_uuid', true), '')::UUID,
+        NULLIF(current_setting('audit.request_id', true), ''),
+        NULLIF(current_setting('audit.ip_address', true), ''),
+        CASE TG_OP WHEN 'INSERT' THEN 'create' WHEN 'UPDATE' THEN 'update' ELSE 'delete' END,
+        TG_ARGV[0],
+        row_uuid,
+        old_row,
+        new_row
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Lockout counters change on every login attempt and are not edits anyone made
+CREATE TRIGGER trg_admins_audit
+AFTER INSERT OR UPDATE OR DELETE ON admins
+FOR EACH ROW EXECUTE FUNCTION record_audit('admin', 'updated_at,failed_login_attempts,locked_until,totp_last_step', 'password,totp_secret');
+
+CREATE TRIGGER trg_products_audit
+AFTER INSERT OR UPDATE OR DELETE ON products
+FOR EACH ROW EXECUTE FUNCTION record_audit('product', 'updated_at', '');
+
+CREATE TRIGGER trg_variants_audit
+AFTER INSERT OR UPDATE OR DELETE ON variants
+FOR EACH ROW EXECUTE FUNCTION record_audit('variant', 'updated_at', '');
\ No newline at end of file
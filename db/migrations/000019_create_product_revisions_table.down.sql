@@ -0,0 +1 @@
+DROP TABLE IF EXISTS product_revisions;
\ No newline at end of file
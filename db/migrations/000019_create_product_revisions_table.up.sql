@@ -0,0 +1,30 @@
+CREATE TABLE product_revisions (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    snapshot JSONB NOT NULL,
+    admin_id INTEGER,
+    restored_from INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_product_revision UNIQUE (product_id, revision),
+    CONSTRAINT fk_revision_product FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+    CONSTRAINT fk_revision_admin FOREIGN KEY (admin_id) REFERENCES admins(id) ON DELETE SET NULL
+);
+
+-- Existing products start their history from how they are now
+INSERT INTO product_revisions (product_id, revision, snapshot, admin_id)
+SELECT p.id, 1, jsonb_build_object(
+    'name', p.name,
+    'imageUrl', p.image_url,
+    'variants', COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+            'uuid', v.uuid,
+            'variantName', v.variant_name,
+            'sku', v.sku,
+            'barcode', v.barcode,
+            'reorderPoint', v.reorder_point
+        ) ORDER BY v.id)
+        FROM variants v WHERE v.product_id = p.id
+    ), '[]'::JSONB)
+), p.admin_id
+FROM products p;
\ No newline at end of file
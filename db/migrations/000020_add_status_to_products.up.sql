@@ -0,0 +1,10 @@
+-- Products created so far were public, so they start out published
+ALTER TABLE products ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published';
+ALTER TABLE products ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE products ADD CONSTRAINT chk_product_status CHECK (status IN ('draft', 'published', 'archived'));
+ALTER TABLE products ADD COLUMN publish_at TIMESTAMP;
+ALTER TABLE products ADD COLUMN unpublish_at TIMESTAMP;
+
+CREATE INDEX idx_products_status ON products (status);
+CREATE INDEX idx_products_publish_at ON products (publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX idx_products_unpublish_at ON products (unpublish_at) WHERE unpublish_at IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS product_images;
+ALTER TABLE products ALTER COLUMN image_url DROP DEFAULT;
+-- Fails if products have come to share an image
+ALTER TABLE products ADD CONSTRAINT products_image_url_key UNIQUE (image_url);
\ No newline at end of file
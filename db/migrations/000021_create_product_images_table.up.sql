@@ -0,0 +1,28 @@
+CREATE TABLE product_images (
+    id SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    product_id INTEGER NOT NULL,
+    url VARCHAR(255) NOT NULL,
+    -- Needed to delete the stored asset; unknown for images uploaded before
+    -- galleries existed
+    public_id VARCHAR(255),
+    alt_text VARCHAR(255) NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_product_image_uuid UNIQUE (uuid),
+    CONSTRAINT fk_image_product FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_product_images_product ON product_images (product_id, position);
+CREATE UNIQUE INDEX uq_product_images_primary ON product_images (product_id) WHERE is_primary;
+CREATE INDEX idx_product_images_public_id ON product_images (public_id) WHERE public_id IS NOT NULL;
+
+-- products.image_url now mirrors the primary image, which products may share
+-- and may not have
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_image_url_key;
+ALTER TABLE products ALTER COLUMN image_url SET DEFAULT '';
+
+INSERT INTO product_images (product_id, url, position, is_primary)
+SELECT id, image_url, 0, TRUE FROM products WHERE image_url <> '';
\ No newline at end of file
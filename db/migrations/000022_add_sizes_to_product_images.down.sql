@@ -0,0 +1 @@
+ALTER TABLE product_images DROP COLUMN IF EXISTS sizes;
\ No newline at end of file
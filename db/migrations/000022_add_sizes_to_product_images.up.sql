@@ -0,0 +1,3 @@
+-- Every size of an image, by name, as {url, width, height}. Empty for
+-- images uploaded before they were processed.
+ALTER TABLE product_images ADD COLUMN sizes JSONB NOT NULL DEFAULT '{}';
\ No newline at end of file
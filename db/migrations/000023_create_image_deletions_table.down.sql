@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_product_images_deleted ON product_images;
+DROP FUNCTION IF EXISTS queue_image_deletion();
+DROP TABLE IF EXISTS image_deletions;
\ No newline at end of file
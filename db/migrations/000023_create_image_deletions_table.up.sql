@@ -0,0 +1,35 @@
+-- Stored images waiting to be deleted, queued when the last image row
+-- using them goes. The image cleanup worker deletes them with retries.
+CREATE TABLE image_deletions (
+    id SERIAL PRIMARY KEY,
+    public_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT chk_image_deletion_status CHECK (status IN ('pending', 'failed'))
+);
+
+CREATE INDEX idx_image_deletions_due ON image_deletions (next_attempt_at) WHERE status = 'pending';
+
+-- Images uploaded before their public ID was kept can still be found from
+-- their Cloudinary URL
+UPDATE product_images
+SET public_id = substring(url from '/upload/(?:v[0-9]+/)?(.+)\.[A-Za-z0-9]+$')
+WHERE public_id IS NULL AND url LIKE 'https://res.cloudinary.com/%';
+
+-- Runs for every deleted image row, including those removed with their
+-- product
+CREATE OR REPLACE FUNCTION queue_image_deletion() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO image_deletions (public_id) VALUES (OLD.public_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_product_images_deleted
+AFTER DELETE ON product_images
+FOR EACH ROW WHEN (OLD.public_id IS NOT NULL)
+EXECUTE FUNCTION queue_image_deletion();
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- Images a client was allowed to upload straight to storage, waiting to be
+-- confirmed and added to the product's gallery
+CREATE TABLE image_uploads (
+    id SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    product_id INTEGER NOT NULL,
+    public_id VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_image_upload_uuid UNIQUE (uuid),
+    CONSTRAINT uq_image_upload_public_id UNIQUE (public_id),
+    CONSTRAINT fk_image_upload_product FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_image_uploads_expires_at ON image_uploads (expires_at);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS uq_variant_option_key;
+ALTER TABLE variants DROP COLUMN IF EXISTS option_key;
+DROP TABLE IF EXISTS variant_option_values;
+DROP TABLE IF EXISTS product_option_values;
+DROP TABLE IF EXISTS product_options;
\ No newline at end of file
@@ -0,0 +1,58 @@
+-- Option types of a product, such as Color or Size, and the values each
+-- can take
+CREATE TABLE product_options (
+    id SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    product_id INTEGER NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_product_option_uuid UNIQUE (uuid),
+    CONSTRAINT fk_option_product FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_product_option_name ON product_options (product_id, LOWER(name));
+
+CREATE TABLE product_option_values (
+    id SERIAL PRIMARY KEY,
+    uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+    option_id INTEGER NOT NULL,
+    value VARCHAR(50) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_product_option_value_uuid UNIQUE (uuid),
+    -- Lets variant_option_values check a value belongs to its option
+    CONSTRAINT uq_product_option_value_option UNIQUE (id, option_id),
+    CONSTRAINT fk_option_value_option FOREIGN KEY (option_id) REFERENCES product_options(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX uq_product_option_value ON product_option_values (option_id, LOWER(value));
+
+-- The value a variant has for each option of its product
+CREATE TABLE variant_option_values (
+    variant_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    value_id INTEGER NOT NULL,
+    PRIMARY KEY (variant_id, option_id),
+    CONSTRAINT fk_variant_option_variant FOREIGN KEY (variant_id) REFERENCES variants(id) ON DELETE CASCADE,
+    -- Not cascaded: a value cannot be removed while a variant has it
+    CONSTRAINT fk_variant_option_value FOREIGN KEY (value_id, option_id) REFERENCES product_option_values(id, option_id)
+);
+
+CREATE INDEX idx_variant_option_values_value ON variant_option_values (value_id);
+
+-- option_key names a variant's combination of option values, as its value
+-- IDs ordered by option, so no two variants of a product can have the same
+-- one
+ALTER TABLE variants ADD COLUMN option_key TEXT;
+
+CREATE UNIQUE INDEX uq_variant_option_key ON variants (product_id, option_key) WHERE option_key IS NOT NULL;
+
+CREATE TRIGGER trg_product_options_audit
+AFTER INSERT OR UPDATE OR DELETE ON product_options
+FOR EACH ROW EXECUTE FUNCTION record_audit('product_option', 'updated_at', '');
+
+CREATE TRIGGER trg_product_option_values_audit
+AFTER INSERT OR UPDATE OR DELETE ON product_option_values
+FOR EACH ROW EXECUTE FUNCTION record_audit('product_option_value', '', '');
\ No newline at end of file
@@ -0,0 +1,29 @@
+-- Stock changes carry the product's status and owner so streams only pass
+-- on changes to products their viewer may see
+CREATE OR REPLACE FUNCTION record_stock_change() RETURNS TRIGGER AS $$
+DECLARE
+    change stock_changes%ROWTYPE;
+    product_status VARCHAR;
+    product_admin_id INTEGER;
+BEGIN
+    INSERT INTO stock_changes (variant_id, variant_uuid, product_uuid, previous_quantity, quantity)
+    SELECT NEW.id, NEW.uuid, products.uuid, OLD.quantity, NEW.quantity
+    FROM products WHERE products.id = NEW.product_id
+    RETURNING * INTO change;
+
+    SELECT status, admin_id INTO product_status, product_admin_id FROM products WHERE id = NEW.product_id;
+
+    PERFORM pg_notify('stock_changes', json_build_object(
+        'id', change.id,
+        'variantId', change.variant_id,
+        'variantUuid', change.variant_uuid,
+        'productUuid', change.product_uuid,
+        'previousQuantity', change.previous_quantity,
+        'quantity', change.quantity,
+        'createdAt', to_char(change.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'productStatus', product_status,
+        'adminId', product_admin_id
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE outbox DROP COLUMN IF EXISTS admin_id;
\ No newline at end of file
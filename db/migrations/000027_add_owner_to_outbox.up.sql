@@ -0,0 +1,14 @@
+-- The admin owning the product an event is about, so webhook deliveries
+-- only go to that admin's subscriptions and to superadmins'. Events not yet
+-- relayed are backfilled the way recordEvent fills it in.
+ALTER TABLE outbox ADD COLUMN admin_id INTEGER;
+
+UPDATE outbox SET admin_id = CASE aggregate_type
+    WHEN 'product' THEN (payload->>'adminId')::INTEGER
+    WHEN 'variant' THEN (SELECT admin_id FROM products WHERE id = (outbox.payload->>'productId')::INTEGER)
+    WHEN 'stock' THEN (
+        SELECT products.admin_id FROM variants JOIN products ON products.id = variants.product_id
+        WHERE variants.uuid::TEXT = outbox.aggregate_id
+    )
+END
+WHERE published_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE jwt_keys
+    ALTER COLUMN active_from TYPE TIMESTAMP USING active_from,
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at;
\ No newline at end of file
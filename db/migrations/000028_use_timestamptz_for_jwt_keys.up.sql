@@ -0,0 +1,7 @@
+-- Key times are compared with the clock of the API, so they must name an
+-- instant rather than a wall-clock time in the database's time zone. The
+-- existing values were written with NOW() in that zone and convert as such.
+ALTER TABLE jwt_keys
+    ALTER COLUMN active_from TYPE TIMESTAMPTZ USING active_from,
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at;
\ No newline at end of file
@@ -5,9 +5,13 @@ go 1.18
 require (
 	github.com/cloudinary/cloudinary-go/v2 v2.7.0
 	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/gorilla/websocket v1.5.1
+	github.com/pquerna/otp v1.4.0
+	golang.org/x/image v0.15.0
 )
 
 require (
+	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
 )
@@ -0,0 +1,162 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// ValidGTIN reports whether code is a GTIN-8 (EAN-8), GTIN-12 (UPC-A),
+// GTIN-13 (EAN-13) or GTIN-14 with a correct check digit.
+func ValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(code) - 1; i >= 0; i-- {
+		c := code[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if i == len(code)-1 {
+			continue
+		}
+		// Weights alternate 3, 1, 3, ... starting next to the check digit
+		if (len(code)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	check := (10 - sum%10) % 10
+	return int(code[len(code)-1]-'0') == check
+}
+
+var (
+	eanLCodes = []string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	eanGCodes = []string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	eanRCodes = []string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+	// eanParity selects L or G codes for the left half from the first digit
+	eanParity = []string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+	// itfPatterns holds the narrow/wide widths for each digit in ITF-14
+	itfPatterns = []string{"NNWWN", "WNNNW", "NWNNW", "WWNNN", "NNWNW", "WNWNN", "NWWNN", "NNNWW", "WNNWN", "NWNWN"}
+)
+
+// barcodeQuietZone is the blank margin, in modules, on each side of a barcode.
+const barcodeQuietZone = 10
+
+// EncodeGTIN converts a valid GTIN into barcode modules, where true is a bar.
+// GTIN-8 is drawn as EAN-8, GTIN-12 and GTIN-13 as EAN-13 and GTIN-14 as ITF-14.
+func EncodeGTIN(code string) ([]bool, error) {
+	if !ValidGTIN(code) {
+		return nil, errors.New("invalid barcode")
+	}
+
+	var pattern strings.Builder
+	switch len(code) {
+	case 8:
+		pattern.WriteString("101")
+		for i := 0; i < 4; i++ {
+			pattern.WriteString(eanLCodes[code[i]-'0'])
+		}
+		pattern.WriteString("01010")
+		for i := 4; i < 8; i++ {
+			pattern.WriteString(eanRCodes[code[i]-'0'])
+		}
+		pattern.WriteString("101")
+	case 12, 13:
+		if len(code) == 12 {
+			// UPC-A is EAN-13 with a leading zero
+			code = "0" + code
+		}
+		parity := eanParity[code[0]-'0']
+		pattern.WriteString("101")
+		for i := 1; i < 7; i++ {
+			if parity[i-1] == 'L' {
+				pattern.WriteString(eanLCodes[code[i]-'0'])
+			} else {
+				pattern.WriteString(eanGCodes[code[i]-'0'])
+			}
+		}
+		pattern.WriteString("01010")
+		for i := 7; i < 13; i++ {
+			pattern.WriteString(eanRCodes[code[i]-'0'])
+		}
+		pattern.WriteString("101")
+	case 14:
+		writeITF := func(width byte, bar bool) {
+			n := 1
+			if width == 'W' {
+				n = 3
+			}
+			mark := "0"
+			if bar {
+				mark = "1"
+			}
+			pattern.WriteString(strings.Repeat(mark, n))
+		}
+		pattern.WriteString("1010")
+		for i := 0; i < len(code); i += 2 {
+			bars, spaces := itfPatterns[code[i]-'0'], itfPatterns[code[i+1]-'0']
+			for j := 0; j < 5; j++ {
+				writeITF(bars[j], true)
+				writeITF(spaces[j], false)
+			}
+		}
+		pattern.WriteString("11101")
+	}
+
+	modules := make([]bool, 0, pattern.Len()+2*barcodeQuietZone)
+	modules = append(modules, make([]bool, barcodeQuietZone)...)
+	for _, c := range pattern.String() {
+		modules = append(modules, c == '1')
+	}
+	modules = append(modules, make([]bool, barcodeQuietZone)...)
+	return modules, nil
+}
+
+// RenderBarcodePNG draws modules as a PNG image, moduleWidth pixels per module.
+func RenderBarcodePNG(w io.Writer, modules []bool, moduleWidth, height int) error {
+	img := image.NewGray(image.Rect(0, 0, len(modules)*moduleWidth, height))
+	for x := 0; x < img.Bounds().Dx(); x++ {
+		c := color.Gray{Y: 0xff}
+		if modules[x/moduleWidth] {
+			c = color.Gray{Y: 0x00}
+		}
+		for y := 0; y < height; y++ {
+			img.SetGray(x, y, c)
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// RenderBarcodeSVG draws modules as an SVG image with the code printed below.
+func RenderBarcodeSVG(w io.Writer, modules []bool, code string, moduleWidth, height int) error {
+	width := len(modules) * moduleWidth
+	textHeight := 14
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height+textHeight, width, height+textHeight)
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`, width, height+textHeight)
+	for i := 0; i < len(modules); {
+		if !modules[i] {
+			i++
+			continue
+		}
+		// Merge adjacent bar modules into a single rectangle
+		start := i
+		for i < len(modules) && modules[i] {
+			i++
+		}
+		fmt.Fprintf(w, `<rect x="%d" y="0" width="%d" height="%d" fill="#000"/>`, start*moduleWidth, (i-start)*moduleWidth, height)
+	}
+	fmt.Fprintf(w, `<text x="%d" y="%d" font-family="monospace" font-size="12" text-anchor="middle">%s</text>`, width/2, height+textHeight-2, code)
+	_, err := io.WriteString(w, `</svg>`)
+	return err
+}
@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidGTIN(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"96385074", true},       // EAN-8
+		{"036000291452", true},   // UPC-A
+		{"4006381333931", true},  // EAN-13
+		{"10012345678902", true}, // GTIN-14
+		{"96385075", false},
+		{"036000291453", false},
+		{"4006381333930", false},
+		{"10012345678903", false},
+		{"400638133393", false},
+		{"400638133393a", false},
+		{"-4006381333931", false},
+		{"40063813339311", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidGTIN(tt.code); got != tt.want {
+			t.Errorf("ValidGTIN(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+// barcodeBits turns a string of 0s and 1s into modules.
+func barcodeBits(pattern string) []bool {
+	modules := make([]bool, len(pattern))
+	for i, c := range pattern {
+		modules[i] = c == '1'
+	}
+	return modules
+}
+
+func TestEncodeGTIN(t *testing.T) {
+	tests := []struct {
+		code    string
+		modules int // without the quiet zones
+		start   string
+		end     string
+	}{
+		{"96385074", 67, "101", "101"},
+		{"4006381333931", 95, "101", "101"},
+		{"10012345678902", 135, "1010", "11101"},
+	}
+	for _, tt := range tests {
+		modules, err := EncodeGTIN(tt.code)
+		if err != nil {
+			t.Fatalf("EncodeGTIN(%q): %v", tt.code, err)
+		}
+		if len(modules) != tt.modules+2*barcodeQuietZone {
+			t.Fatalf("EncodeGTIN(%q) has %d modules, want %d", tt.code, len(modules), tt.modules+2*barcodeQuietZone)
+		}
+		quiet := make([]bool, barcodeQuietZone)
+		if !reflect.DeepEqual(modules[:barcodeQuietZone], quiet) || !reflect.DeepEqual(modules[len(modules)-barcodeQuietZone:], quiet) {
+			t.Errorf("EncodeGTIN(%q) has bars in its quiet zones", tt.code)
+		}
+		bars := modules[barcodeQuietZone : len(modules)-barcodeQuietZone]
+		if !reflect.DeepEqual(bars[:len(tt.start)], barcodeBits(tt.start)) {
+			t.Errorf("EncodeGTIN(%q) does not begin with the start pattern %s", tt.code, tt.start)
+		}
+		if !reflect.DeepEqual(bars[len(bars)-len(tt.end):], barcodeBits(tt.end)) {
+			t.Errorf("EncodeGTIN(%q) does not end with the stop pattern %s", tt.code, tt.end)
+		}
+	}
+}
+
+func TestEncodeGTINDigits(t *testing.T) {
+	// EAN-13 4006381333931: first digit 4 selects LGLLGG for the left half
+	want := "101" +
+		"0001101" + "0100111" + "0101111" + "0111101" + "0001001" + "0110011" +
+		"01010" +
+		"1000010" + "1000010" + "1000010" + "1110100" + "1000010" + "1100110" +
+		"101"
+	modules, err := EncodeGTIN("4006381333931")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := modules[barcodeQuietZone : len(modules)-barcodeQuietZone]; !reflect.DeepEqual(got, barcodeBits(want)) {
+		t.Errorf("EncodeGTIN(4006381333931) modules do not match the EAN-13 encoding")
+	}
+}
+
+func TestEncodeGTINUPCA(t *testing.T) {
+	upc, err := EncodeGTIN("036000291452")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ean, err := EncodeGTIN("0036000291452")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(upc, ean) {
+		t.Error("UPC-A is not drawn as EAN-13 with a leading zero")
+	}
+}
+
+func TestEncodeGTINInvalid(t *testing.T) {
+	for _, code := range []string{"4006381333930", "1234", "abcdefgh"} {
+		if _, err := EncodeGTIN(code); err == nil || err.Error() != "invalid barcode" {
+			t.Errorf("EncodeGTIN(%q) error = %v, want invalid barcode", code, err)
+		}
+	}
+}
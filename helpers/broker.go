@@ -0,0 +1,55 @@
+package helpers
+
+import (
+	"context"
+	"sync"
+)
+
+// BrokerMessage is a message published to a topic. Key orders messages within
+// a topic, the way a Kafka partition key or a NATS subject token would.
+type BrokerMessage struct {
+	Topic   string
+	Key     string
+	Value   []byte
+	Headers map[string]string
+}
+
+// Broker publishes messages to a NATS or Kafka style message broker.
+// Publish must return only once the broker has accepted the message.
+type Broker interface {
+	Publish(ctx context.Context, message BrokerMessage) error
+}
+
+// MemoryBroker is an in-process Broker for development and local runs. It
+// hands every message to the topic's subscribers synchronously and keeps
+// nothing once published.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string][]func(BrokerMessage)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string][]func(BrokerMessage))}
+}
+
+// Subscribe registers fn for every message later published to topic.
+func (b *MemoryBroker) Subscribe(topic string, fn func(BrokerMessage)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[topic] = append(b.subscribers[topic], fn)
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, message BrokerMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers[message.Topic]
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(message)
+	}
+	return nil
+}
@@ -4,59 +4,307 @@ import (
 	"basic-trade-api/configs"
 	"bytes"
 	"context"
-	"io"
-	"mime/multipart"
+	"errors"
+	"fmt"
+	"net/url"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
 )
 
-func UploadFile(fileHeader *multipart.FileHeader, fileName string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// UploadedImage is one rendition of an image once stored.
+type UploadedImage struct {
+	Name     string
+	URL      string
+	PublicID string
+	Width    int
+	Height   int
+}
+
+// UploadFile stores every rendition of an image. The original is stored as
+// fileName plus a random suffix, so uploads with the same name do not
+// overwrite each other, and the other sizes under that public ID plus their
+// name. If any upload fails the ones already made are deleted.
+func UploadFile(renditions []ImageRendition, fileName string) ([]UploadedImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Add Cloudinary product environment credentials.
 	cld, err := cloudinary.NewFromParams(configs.EnvCloudName(), configs.EnvCloudAPIKey(), configs.EnvCloudAPISecret())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Convert file
-	fileReader, err := ConvertFile(fileHeader)
+	suffix, err := RandomToken(4)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	baseID := fileName + "-" + suffix
 
-	// Upload file
-	uploadParam, err := cld.Upload.Upload(ctx, fileReader, uploader.UploadParams{
-		PublicID: fileName,
-		Folder:   configs.EnvCloudUploadFolder(),
-	})
+	uploaded := make([]UploadedImage, 0, len(renditions))
+	for _, rendition := range renditions {
+		publicID := ImageSizePublicID(baseID, rendition.Name)
+
+		// Upload file
+		uploadParam, err := cld.Upload.Upload(ctx, bytes.NewReader(rendition.Data), uploader.UploadParams{
+			PublicID: publicID,
+			Folder:   configs.EnvCloudUploadFolder(),
+		})
+		if err == nil && uploadParam.Error.Message != "" {
+			err = errors.New(uploadParam.Error.Message)
+		}
+		if err != nil {
+			for _, image := range uploaded {
+				destroyAsset(ctx, cld, image.PublicID)
+			}
+			return nil, err
+		}
+
+		uploaded = append(uploaded, UploadedImage{
+			Name:     rendition.Name,
+			URL:      uploadParam.SecureURL,
+			PublicID: uploadParam.PublicID,
+			Width:    rendition.Width,
+			Height:   rendition.Height,
+		})
+	}
+
+	return uploaded, nil
+}
+
+// SignedUpload lets a client upload one image straight to storage, by
+// posting Fields and the image as "file" in a multipart form to URL.
+type SignedUpload struct {
+	PublicID string            `json:"-"`
+	URL      string            `json:"url"`
+	Fields   map[string]string `json:"fields"`
+}
+
+// uploadFormats are the formats storage accepts in a signed upload.
+var uploadFormats = map[string]bool{
+	"jpg":  true,
+	"png":  true,
+	"webp": true,
+}
+
+// SignUpload signs the upload of one image under a new random public ID in
+// the upload folder. Storage honours the signature for an hour; shorter
+// limits are up to the caller.
+func SignUpload() (*SignedUpload, error) {
+	token, err := RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	publicID := "upload-" + token
+	if folder := configs.EnvCloudUploadFolder(); folder != "" {
+		publicID = folder + "/" + publicID
+	}
+
+	params := url.Values{}
+	params.Set("public_id", publicID)
+	params.Set("allowed_formats", "jpg,png,webp")
+	params.Set("timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	signature, err := api.SignParameters(params, configs.EnvCloudAPISecret())
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return uploadParam.SecureURL, nil
+	fields := map[string]string{
+		"api_key":   configs.EnvCloudAPIKey(),
+		"signature": signature,
+	}
+	for key := range params {
+		fields[key] = params.Get(key)
+	}
+	return &SignedUpload{
+		PublicID: publicID,
+		URL:      "https://api.cloudinary.com/v1_1/" + configs.EnvCloudName() + "/image/upload",
+		Fields:   fields,
+	}, nil
 }
 
-func ConvertFile(fileHeader *multipart.FileHeader) (*bytes.Reader, error) {
-	file, err := fileHeader.Open()
+// VerifyUpload checks an image uploaded with SignUpload against limits, as
+// ProcessImage does for images sent through the API, and returns its sizes.
+// The sizes are WebP renditions made by storage on delivery rather than
+// stored copies. It fails with "image not uploaded" when nothing was
+// uploaded under publicID.
+func VerifyUpload(publicID string, limits ImageLimits) ([]UploadedImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cld, err := cloudinary.NewFromParams(configs.EnvCloudName(), configs.EnvCloudAPIKey(), configs.EnvCloudAPISecret())
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	cld.Config.URL.Analytics = false
+
+	result, err := cld.Admin.Asset(ctx, admin.AssetParams{
+		AssetType:    api.Image,
+		DeliveryType: "upload",
+		PublicID:     publicID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error.Message != "" {
+		if strings.Contains(strings.ToLower(result.Error.Message), "not found") {
+			return nil, errors.New("image not uploaded")
+		}
+		return nil, errors.New(result.Error.Message)
+	}
+
+	if !uploadFormats[result.Format] {
+		return nil, errors.New("unsupported image type")
+	}
+	if int64(result.Bytes) > limits.MaxBytes {
+		return nil, errors.New("file too large")
+	}
+	if result.Width > limits.MaxDimension || result.Height > limits.MaxDimension {
+		return nil, errors.New("image dimensions too large")
+	}
+
+	sizes := append([]ImageSize{{Name: OriginalImageSize}}, ImageSizes...)
+	uploaded := make([]UploadedImage, 0, len(sizes))
+	for _, size := range sizes {
+		width, height := result.Width, result.Height
+		transformation := "f_webp"
+		if size.Name != OriginalImageSize {
+			width, height = fitSize(width, height, size.MaxSide)
+			transformation = fmt.Sprintf("c_scale,w_%d,h_%d/f_webp", width, height)
+		}
+
+		image, err := cld.Image(publicID)
+		if err != nil {
+			return nil, err
+		}
+		image.Version = result.Version
+		image.Transformation = transformation
+		imageURL, err := image.String()
+		if err != nil {
+			return nil, err
+		}
+
+		uploaded = append(uploaded, UploadedImage{
+			Name:     size.Name,
+			URL:      imageURL,
+			PublicID: publicID,
+			Width:    width,
+			Height:   height,
+		})
+	}
+	return uploaded, nil
+}
+
+// DeleteFile removes an image stored by UploadFile, given the public ID of
+// its original, along with its other sizes. Images that are already gone
+// are not an error.
+func DeleteFile(publicID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cld, err := cloudinary.NewFromParams(configs.EnvCloudName(), configs.EnvCloudAPIKey(), configs.EnvCloudAPISecret())
+	if err != nil {
+		return err
+	}
+
+	if err := destroyAsset(ctx, cld, publicID); err != nil {
+		return err
+	}
+	for _, size := range ImageSizes {
+		if err := destroyAsset(ctx, cld, ImageSizePublicID(publicID, size.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteStoredFile removes exactly one stored image, such as a single size
+// found by ListFiles. An image that is already gone is not an error.
+func DeleteStoredFile(publicID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cld, err := cloudinary.NewFromParams(configs.EnvCloudName(), configs.EnvCloudAPIKey(), configs.EnvCloudAPISecret())
+	if err != nil {
+		return err
+	}
+	return destroyAsset(ctx, cld, publicID)
+}
+
+// ImageSizePublicID is the public ID a size of an image is stored under,
+// given the public ID of its original.
+func ImageSizePublicID(publicID, size string) string {
+	if size == OriginalImageSize {
+		return publicID
+	}
+	return publicID + "_" + size
+}
+
+// StoredImage is an image found in storage.
+type StoredImage struct {
+	PublicID  string
+	CreatedAt time.Time
+}
+
+// ListFiles lists every image stored in the upload folder.
+func ListFiles() ([]StoredImage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
 
-	// Read the file content into an in-memory buffer
-	buffer := new(bytes.Buffer)
-	if _, err := io.Copy(buffer, file); err != nil {
+	cld, err := cloudinary.NewFromParams(configs.EnvCloudName(), configs.EnvCloudAPIKey(), configs.EnvCloudAPISecret())
+	if err != nil {
 		return nil, err
 	}
 
-	// Create a bytes.Reader from the buffer
-	fileReader := bytes.NewReader(buffer.Bytes())
-	return fileReader, nil
+	prefix := configs.EnvCloudUploadFolder()
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var images []StoredImage
+	cursor := ""
+	for {
+		result, err := cld.Admin.Assets(ctx, admin.AssetsParams{
+			AssetType:    api.Image,
+			DeliveryType: "upload",
+			Prefix:       prefix,
+			MaxResults:   500,
+			NextCursor:   cursor,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Error.Message != "" {
+			return nil, errors.New(result.Error.Message)
+		}
+		for _, asset := range result.Assets {
+			images = append(images, StoredImage{PublicID: asset.PublicID, CreatedAt: asset.CreatedAt})
+		}
+		if result.NextCursor == "" {
+			return images, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func destroyAsset(ctx context.Context, cld *cloudinary.Cloudinary, publicID string) error {
+	result, err := cld.Upload.Destroy(ctx, uploader.DestroyParams{PublicID: publicID})
+	if err != nil {
+		return err
+	}
+	if result.Error.Message != "" {
+		return errors.New(result.Error.Message)
+	}
+	if result.Result != "ok" && result.Result != "not found" {
+		return errors.New("delete " + publicID + ": " + result.Result)
+	}
+	return nil
 }
 
 func RemoveExtension(filename string) string {
@@ -0,0 +1,171 @@
+package helpers
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+)
+
+// ExportRow is a single record written by an Exporter. It is marshalled as-is
+// for JSON Lines and through Record for tabular formats.
+type ExportRow interface {
+	Record() []string
+}
+
+// Exporter writes rows to an underlying stream one at a time.
+type Exporter interface {
+	Write(row ExportRow) error
+	Close() error
+}
+
+// ExportFormat describes a supported export format.
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+	New         func(w io.Writer, header []string) (Exporter, error)
+}
+
+var ExportFormats = map[string]ExportFormat{
+	"csv": {
+		ContentType: "text/csv; charset=utf-8",
+		Extension:   "csv",
+		New:         NewCSVExporter,
+	},
+	"jsonl": {
+		ContentType: "application/x-ndjson",
+		Extension:   "jsonl",
+		New:         NewJSONLExporter,
+	},
+	"xlsx": {
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		New:         NewXLSXExporter,
+	},
+}
+
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+func NewCSVExporter(w io.Writer, header []string) (Exporter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvExporter{writer: writer}, nil
+}
+
+func (e *csvExporter) Write(row ExportRow) error {
+	return e.writer.Write(row.Record())
+}
+
+func (e *csvExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type jsonlExporter struct {
+	encoder *json.Encoder
+}
+
+func NewJSONLExporter(w io.Writer, header []string) (Exporter, error) {
+	return &jsonlExporter{encoder: json.NewEncoder(w)}, nil
+}
+
+func (e *jsonlExporter) Write(row ExportRow) error {
+	// Encode terminates every value with a newline
+	return e.encoder.Encode(row)
+}
+
+func (e *jsonlExporter) Close() error {
+	return nil
+}
+
+// xlsxExporter writes a single-sheet workbook. The static parts of the package
+// are written up front and the sheet is streamed last, so only the zip
+// compressor's window is buffered.
+type xlsxExporter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+func NewXLSXExporter(w io.Writer, header []string) (Exporter, error) {
+	archive := zip.NewWriter(w)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxExporter{archive: archive, sheet: bufio.NewWriter(f)}
+	if _, err := e.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	if err := e.writeRecord(header); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *xlsxExporter) Write(row ExportRow) error {
+	return e.writeRecord(row.Record())
+}
+
+func (e *xlsxExporter) writeRecord(record []string) error {
+	e.sheet.WriteString("<row>")
+	for _, value := range record {
+		if value == "" {
+			e.sheet.WriteString("<c/>")
+			continue
+		}
+		e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(e.sheet, []byte(value)); err != nil {
+			return err
+		}
+		e.sheet.WriteString("</t></is></c>")
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxExporter) Close() error {
+	if _, err := e.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.archive.Close()
+}
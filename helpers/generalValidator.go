@@ -27,10 +27,20 @@ func GeneralValidator(payloadValidationError error) []string {
 				message = fmt.Sprintf("%s must be at least %s characters long", fieldErr.Field(), fieldErr.Param())
 			case "gte":
 				message = fmt.Sprintf("%v must have minimum value = %v", fieldErr.Field(), fieldErr.Param())
+			case "gt":
+				message = fmt.Sprintf("%v must be greater than %v", fieldErr.Field(), fieldErr.Param())
+			case "nefield":
+				message = fmt.Sprintf("%v must be different from %v", fieldErr.Field(), fieldErr.Param())
 			case "max":
 				message = fmt.Sprintf("%s must be at most %s characters long", fieldErr.Field(), fieldErr.Param())
 			case "email":
 				message = fmt.Sprintf("%s must be a valid email address", fieldErr.Field())
+			case "barcode":
+				message = fmt.Sprintf("%s must be a valid GTIN-8, UPC-A, EAN-13 or GTIN-14 barcode", fieldErr.Field())
+			case "oneof":
+				message = fmt.Sprintf("%s must be one of: %s", fieldErr.Field(), fieldErr.Param())
+			case "url":
+				message = fmt.Sprintf("%s must be a valid URL", fieldErr.Field())
 			case "e164":
 				message = fmt.Sprintf("%s must be a valid phone number", fieldErr.Field())
 			}
@@ -0,0 +1,288 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"runtime"
+
+	// Registered with image.Decode for the formats uploads may be in
+	_ "image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ImageLimits bound what an uploaded image may be.
+type ImageLimits struct {
+	MaxBytes     int64
+	MaxDimension int
+}
+
+// ImageSize is a size images are scaled down to fit, as a square box of
+// MaxSide pixels. Images smaller than the box are not scaled up.
+type ImageSize struct {
+	Name    string
+	MaxSide int
+}
+
+// ImageSizes are the thumbnails made of every image, besides the original.
+var ImageSizes = []ImageSize{
+	{Name: "thumbnail", MaxSide: 150},
+	{Name: "small", MaxSide: 320},
+	{Name: "medium", MaxSide: 640},
+	{Name: "large", MaxSide: 1280},
+}
+
+// OriginalImageSize names the full size rendition. Uploads sent through the
+// API store it no larger than the largest of ImageSizes: the WebP encoder is
+// lossless, so a full camera resolution photo would be stored several times
+// larger than it was uploaded, and take seconds to encode.
+const OriginalImageSize = "original"
+
+// imageSlots bounds how many images are decoded and encoded at once, so
+// concurrent uploads queue for the CPU instead of exhausting it and memory
+var imageSlots = make(chan struct{}, runtime.NumCPU())
+
+// ImageRendition is one size of a processed image, encoded as WebP.
+type ImageRendition struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// imageTypes are the sniffed MIME types accepted for upload.
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// ReadFile reads an uploaded file, failing with "file too large" past
+// maxBytes however large the client said it was.
+func ReadFile(fileHeader *multipart.FileHeader, maxBytes int64) ([]byte, error) {
+	if fileHeader.Size > maxBytes {
+		return nil, errors.New("file too large")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, errors.New("file too large")
+	}
+	return data, nil
+}
+
+// ProcessImage checks an uploaded image by its content rather than the
+// type the client claimed, turns it upright, and re-encodes it as WebP in
+// its original size, capped as OriginalImageSize says, and every size of
+// ImageSizes. Re-encoding drops EXIF and any other metadata. Only
+// runtime.NumCPU images are processed at once; others wait their turn.
+func ProcessImage(fileHeader *multipart.FileHeader, limits ImageLimits) ([]ImageRendition, error) {
+	data, err := ReadFile(fileHeader, limits.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if !imageTypes[http.DetectContentType(data)] {
+		return nil, errors.New("unsupported image type")
+	}
+
+	// The header is checked before decoding so a small file cannot claim
+	// enough pixels to exhaust memory
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+	if config.Width > limits.MaxDimension || config.Height > limits.MaxDimension {
+		return nil, errors.New("image dimensions too large")
+	}
+
+	imageSlots <- struct{}{}
+	defer func() { <-imageSlots }()
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image")
+	}
+	img = orient(img, jpegOrientation(data))
+
+	renditions := make([]ImageRendition, 0, len(ImageSizes)+1)
+	original, err := encodeRendition(OriginalImageSize, scaleToFit(img, ImageSizes[len(ImageSizes)-1].MaxSide))
+	if err != nil {
+		return nil, err
+	}
+	renditions = append(renditions, *original)
+
+	for _, size := range ImageSizes {
+		width, height := fitSize(original.Width, original.Height, size.MaxSide)
+		if width == original.Width && height == original.Height {
+			renditions = append(renditions, ImageRendition{Name: size.Name, Data: original.Data, Width: width, Height: height})
+			continue
+		}
+		rendition, err := encodeRendition(size.Name, scaleToFit(img, size.MaxSide))
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, *rendition)
+	}
+	return renditions, nil
+}
+
+func encodeRendition(name string, img image.Image) (*ImageRendition, error) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img); err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	return &ImageRendition{Name: name, Data: buf.Bytes(), Width: bounds.Dx(), Height: bounds.Dy()}, nil
+}
+
+// scaleToFit returns img scaled down to fit in a square of maxSide, or img
+// itself if it already fits.
+func scaleToFit(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	width, height := fitSize(bounds.Dx(), bounds.Dy(), maxSide)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return img
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// fitSize scales width and height down to fit in a square of maxSide,
+// keeping the aspect ratio.
+func fitSize(width, height, maxSide int) (int, int) {
+	if width <= maxSide && height <= maxSide {
+		return width, height
+	}
+	if width >= height {
+		scaled := height * maxSide / width
+		if scaled < 1 {
+			scaled = 1
+		}
+		return maxSide, scaled
+	}
+	scaled := width * maxSide / height
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled, maxSide
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG, 1 (upright) if it
+// has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		// Image data starts at SOS; metadata comes before it
+		if marker == 0xda || marker == 0xd9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of EXIF data.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for k := 0; k < entries; k++ {
+		entry := offset + 2 + 12*k
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient turns an image as its EXIF orientation says it should be shown.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
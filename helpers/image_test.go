@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"testing"
+)
+
+// fileHeader wraps data as an uploaded multipart file.
+func fileHeader(t *testing.T, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("image", "upload.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	mw.Close()
+
+	form, err := multipart.NewReader(&body, mw.Boundary()).ReadForm(int64(body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["image"][0]
+}
+
+func TestProcessImageCapsOriginal(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2000, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 2000; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	renditions, err := ProcessImage(fileHeader(t, buf.Bytes()), ImageLimits{MaxBytes: 10 << 20, MaxDimension: 4096})
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	want := map[string][2]int{
+		OriginalImageSize: {1280, 64},
+		"thumbnail":       {150, 7},
+		"small":           {320, 16},
+		"medium":          {640, 32},
+		"large":           {1280, 64},
+	}
+	if len(renditions) != len(want) {
+		t.Fatalf("got %d renditions, want %d", len(renditions), len(want))
+	}
+	for _, r := range renditions {
+		size := want[r.Name]
+		if r.Width != size[0] || r.Height != size[1] {
+			t.Errorf("%s is %dx%d, want %dx%d", r.Name, r.Width, r.Height, size[0], size[1])
+		}
+	}
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	limits := ImageLimits{MaxBytes: 1 << 20, MaxDimension: 64}
+
+	if _, err := ProcessImage(fileHeader(t, []byte("not an image at all")), limits); err == nil || err.Error() != "unsupported image type" {
+		t.Errorf("text upload: err = %v, want unsupported image type", err)
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 65, 10)))
+	if _, err := ProcessImage(fileHeader(t, buf.Bytes()), limits); err == nil || err.Error() != "image dimensions too large" {
+		t.Errorf("oversized upload: err = %v, want image dimensions too large", err)
+	}
+}
@@ -1,72 +1,267 @@
 package helpers
 
 import (
+	"basic-trade-api/configs"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
-	"os"
+	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secretKey = os.Getenv("JWT_SECRET")
+// SigningKey is one key of the JWT key set. A key signs tokens from
+// ActiveFrom until a newer key becomes active, and verifies them until
+// ExpiresAt, if set.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	Private    crypto.Signer
+	ActiveFrom time.Time
+	ExpiresAt  *time.Time
+}
 
-func GenerateToken(id int, email string) (string, error) {
-	claims := jwt.MapClaims{
-		"id": id,
-		"email": email,
-		"exp": time.Now().Add(time.Hour * 8), // expire in 8 hours
+// GenerateSigningKey creates a key for algorithm and returns it with its
+// private key PEM encoded, for storage.
+func GenerateSigningKey(algorithm string) (*SigningKey, string, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, "", fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, "", err
 	}
 
-	parseToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := parseToken.SignedString([]byte(secretKey))
+	der, err := x509.MarshalPKCS8PrivateKey(private)
 	if err != nil {
-		return "", err
+		return nil, "", err
+	}
+	kid, err := RandomToken(8)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return signedToken, nil
+	key := &SigningKey{ID: kid, Algorithm: algorithm, Private: private}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
 }
 
-func VerifyToken(ctx *gin.Context) (interface{}, error) {
-	headerToken := ctx.Request.Header.Get("Authorization")
-	bearer := strings.HasPrefix(headerToken, "Bearer")
+// ParseSigningKey rebuilds a stored key.
+func ParseSigningKey(kid, algorithm, privatePEM string, activeFrom time.Time, expiresAt *time.Time) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, fmt.Errorf("key %s: no PEM data", kid)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", kid, err)
+	}
 
-	if !bearer {
-		return nil, errors.New("sign in to proceed")
+	var private crypto.Signer
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if algorithm == "RS256" {
+			private = k
+		}
+	case ed25519.PrivateKey:
+		if algorithm == "EdDSA" {
+			private = k
+		}
+	}
+	if private == nil {
+		return nil, fmt.Errorf("key %s: not a %s key", kid, algorithm)
+	}
+
+	return &SigningKey{ID: kid, Algorithm: algorithm, Private: private, ActiveFrom: activeFrom, ExpiresAt: expiresAt}, nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == "EdDSA" {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// JWK is the public half of a signing key in JSON Web Key form.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig", Algorithm: k.Algorithm}
+	switch public := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// KeySet holds the signing keys this replica knows about. It is reloaded
+// from the database as keys rotate.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys []*SigningKey
+}
+
+// JWTKeys is the key set access tokens are signed and verified with.
+var JWTKeys = &KeySet{}
+
+func (s *KeySet) Replace(keys []*SigningKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// signing returns the newest key that is already active.
+func (s *KeySet) signing(now time.Time) *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var current *SigningKey
+	for _, key := range s.keys {
+		if key.ActiveFrom.After(now) || expired(key, now) {
+			continue
+		}
+		if current == nil || key.ActiveFrom.After(current.ActiveFrom) {
+			current = key
+		}
 	}
+	return current
+}
+
+// lookup returns the key with kid if it may still verify tokens.
+func (s *KeySet) lookup(kid string, now time.Time) *SigningKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	stringToken := strings.Split(headerToken, " ")[1]
-	token, _ := jwt.Parse(stringToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("sign in to proceed")
+	for _, key := range s.keys {
+		if key.ID == kid && !expired(key, now) {
+			return key
 		}
-		return []byte(secretKey), nil
-	})
+	}
+	return nil
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok && !token.Valid {
-		return nil, errors.New("sign in to proceed")
+// JWKS lists every unexpired key, including keys not yet active, so
+// verifiers learn of a key before it is used.
+func (s *KeySet) JWKS() []JWK {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	jwks := []JWK{}
+	for _, key := range s.keys {
+		if !expired(key, now) {
+			jwks = append(jwks, key.JWK())
+		}
 	}
+	return jwks
+}
+
+func expired(key *SigningKey, now time.Time) bool {
+	return key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)
+}
 
-	expClaim, exists := claims["exp"]
-	if !exists {
-		return nil, errors.New("expire claim is missing")
+// AdminClaims are the claims of an admin access token. id and email are
+// kept beside the registered claims for the handlers that read them.
+type AdminClaims struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+func GenerateToken(id int, email string) (string, error) {
+	now := time.Now()
+	key := JWTKeys.signing(now)
+	if key == nil {
+		return "", errors.New("no signing key available")
 	}
 
-	expStr, ok := expClaim.(string)
-	if !ok {
-		return nil, errors.New("expire claim is not a valid type")
+	claims := AdminClaims{
+		ID:    id,
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    configs.EnvJWTIssuer(),
+			Subject:   strconv.Itoa(id),
+			Audience:  jwt.ClaimStrings{configs.EnvJWTAudience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(configs.EnvJWTTTL())),
+		},
 	}
 
-	expTime, err := time.Parse(time.RFC3339, expStr)
-	if err != nil {
-		return nil, errors.New("error parsing expiration time")
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// VerifyToken checks the bearer token of a request and returns its claims.
+// The signature must come from a known key and exp, nbf, iss and aud are
+// all required to be valid.
+func VerifyToken(ctx *gin.Context) (interface{}, error) {
+	headerToken := ctx.Request.Header.Get("Authorization")
+	stringToken := strings.TrimPrefix(headerToken, "Bearer ")
+	if stringToken == headerToken || stringToken == "" {
+		return nil, errors.New("sign in to proceed")
 	}
 
-	if time.Now().After(expTime) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(configs.EnvJWTIssuer()),
+		jwt.WithAudience(configs.EnvJWTAudience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(configs.EnvJWTLeeway()),
+	)
+	claims := jwt.MapClaims{}
+	token, err := parser.ParseWithClaims(stringToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := JWTKeys.lookup(kid, time.Now())
+		if key == nil || key.Algorithm != t.Method.Alg() {
+			return nil, errors.New("unknown signing key")
+		}
+		return key.Private.Public(), nil
+	})
+	if errors.Is(err, jwt.ErrTokenExpired) {
 		return nil, errors.New("token is expired")
 	}
+	if err != nil || !token.Valid {
+		return nil, errors.New("sign in to proceed")
+	}
 
-	return token.Claims.(jwt.MapClaims), nil
-}
\ No newline at end of file
+	// Handlers read the admin ID as a JSON number
+	if _, ok := claims["id"].(float64); !ok {
+		return nil, errors.New("sign in to proceed")
+	}
+	return claims, nil
+}
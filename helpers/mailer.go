@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"basic-trade-api/configs"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/smtp"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Mailer sends plain-text email.
+type Mailer interface {
+	Send(to []string, subject, body string) error
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// NewSMTPMailer builds an SMTPMailer from the SMTP_* environment variables.
+func NewSMTPMailer() *SMTPMailer {
+	return &SMTPMailer{
+		Host:     configs.EnvSMTPHost(),
+		Port:     configs.EnvSMTPPort(),
+		Username: configs.EnvSMTPUsername(),
+		Password: configs.EnvSMTPPassword(),
+		From:     configs.EnvSMTPFrom(),
+	}
+}
+
+func (m *SMTPMailer) Send(to []string, subject, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, to, formatMessage(m.From, to, subject, body))
+}
+
+func formatMessage(from string, to []string, subject, body string) []byte {
+	return []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		from, strings.Join(to, ", "), subject, body))
+}
+
+// LogMailer writes messages to the log instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) Send(to []string, subject, body string) error {
+	log.Printf("mail to %s: %s\n%s", strings.Join(to, ", "), subject, body)
+	return nil
+}
+
+// FileMailer writes each message to Dir as an .eml file, which most mail
+// clients can open.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(to []string, subject, body string) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405.000000000") + ".eml"
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMessage(m.From, to, subject, body), 0o644)
+}
+
+// NewMailer builds the mailer named by MAIL_DRIVER.
+func NewMailer() Mailer {
+	switch driver := configs.EnvMailDriver(); driver {
+	case "smtp":
+		return NewSMTPMailer()
+	case "file":
+		return FileMailer{Dir: configs.EnvMailFileDir(), From: configs.EnvSMTPFrom()}
+	case "log":
+		return LogMailer{}
+	default:
+		log.Printf("mailer: unknown driver %q, logging mail instead", driver)
+		return LogMailer{}
+	}
+}
+
+//go:embed templates/mail/*.tmpl
+var mailTemplateFS embed.FS
+
+// mailTemplates holds one template per file in templates/mail, each defining
+// a "subject" and a "body".
+var mailTemplates = func() map[string]*template.Template {
+	templates := make(map[string]*template.Template)
+	files, _ := fs.Glob(mailTemplateFS, "templates/mail/*.tmpl")
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		templates[name] = template.Must(template.ParseFS(mailTemplateFS, file))
+	}
+	return templates
+}()
+
+// RenderMail fills in the named mail template.
+func RenderMail(name string, data interface{}) (subject, body string, err error) {
+	tmpl, ok := mailTemplates[name]
+	if !ok {
+		return "", "", fmt.Errorf("mail template %q not found", name)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := tmpl.ExecuteTemplate(&buf, "body", data); err != nil {
+		return "", "", err
+	}
+	return subject, buf.String(), nil
+}
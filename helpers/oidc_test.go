@@ -0,0 +1,222 @@
+package helpers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is an OpenID Connect provider serving discovery, its key set and a
+// token endpoint that answers every code with idToken.
+type mockIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "idp-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code") != "code" ||
+			r.PostFormValue("code_verifier") != "verifier" || clientID != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// sign makes an ID token for claims, signed with the IdP's key under kid.
+func (idp *mockIdP) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	idp := newMockIdP(t)
+	validClaims := func() jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss":            idp.server.URL,
+			"sub":            "user-1",
+			"aud":            "client",
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          "nonce",
+			"email":          " Admin@Example.com ",
+			"email_verified": "true",
+			"name":           "Admin",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		change  func(jwt.MapClaims)
+		wantErr string
+	}{
+		{name: "valid login", kid: "idp-key"},
+		{
+			name:    "wrong nonce",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { c["nonce"] = "other" },
+			wantErr: "nonce does not match",
+		},
+		{
+			name:    "missing nonce",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { delete(c, "nonce") },
+			wantErr: "nonce does not match",
+		},
+		{
+			name:    "wrong audience",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { c["aud"] = "other-client" },
+			wantErr: "token has invalid audience",
+		},
+		{
+			name:    "wrong issuer",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			wantErr: "token has invalid issuer",
+		},
+		{
+			name:    "expired",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+			wantErr: "token is expired",
+		},
+		{
+			name:    "unknown kid",
+			kid:     "rotated-away",
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "multiple audiences without azp",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { c["aud"] = []string{"client", "other-client"} },
+			wantErr: "issued to another client",
+		},
+		{
+			name: "multiple audiences with another azp",
+			kid:  "idp-key",
+			change: func(c jwt.MapClaims) {
+				c["aud"] = []string{"client", "other-client"}
+				c["azp"] = "other-client"
+			},
+			wantErr: "issued to another client",
+		},
+		{
+			name: "multiple audiences with matching azp",
+			kid:  "idp-key",
+			change: func(c jwt.MapClaims) {
+				c["aud"] = []string{"client", "other-client"}
+				c["azp"] = "client"
+			},
+		},
+		{
+			name:    "no subject",
+			kid:     "idp-key",
+			change:  func(c jwt.MapClaims) { delete(c, "sub") },
+			wantErr: "no subject",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.change != nil {
+				tt.change(claims)
+			}
+			idp.idToken = idp.sign(t, tt.kid, claims)
+
+			provider := &OIDCProvider{
+				Issuer:       idp.server.URL,
+				ClientID:     "client",
+				ClientSecret: "secret",
+				RedirectURL:  "http://localhost/callback",
+				Client:       idp.server.Client(),
+			}
+			identity, err := provider.Exchange(context.Background(), "code", "verifier", "nonce")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			want := OIDCIdentity{
+				Issuer:        idp.server.URL,
+				Subject:       "user-1",
+				Email:         "admin@example.com",
+				EmailVerified: true,
+				Name:          "Admin",
+			}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderExchangeRejectedCode(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := &OIDCProvider{
+		Issuer:       idp.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Client:       idp.server.Client(),
+	}
+	_, err := provider.Exchange(context.Background(), "code", "wrong-verifier", "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("Exchange error = %v, want the token endpoint's error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket that holds Burst tokens and refills all of
+// them over Period.
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// RateLimitStore keeps token buckets by key.
+type RateLimitStore interface {
+	// Take removes a token from the bucket at key. If none is left it
+	// reports how long until one is.
+	Take(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	period time.Duration
+}
+
+// MemoryRateLimitStore keeps buckets in process memory, so each replica
+// limits on its own.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	rate := float64(limit.Burst) / limit.Period.Seconds() // tokens per second
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.last).Seconds()*rate)
+	bucket.last = now
+	bucket.period = limit.Period
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// at most once a minute.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.last) >= bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RedisClient is the part of a Redis client RedisRateLimitStore needs. A
+// go-redis client satisfies it with a one-line adapter around Eval.
+type RedisClient interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// tokenBucketScript updates a bucket atomically in Redis and returns whether
+// a token was taken and, if not, the milliseconds until one is available.
+const tokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + (now - ts) * capacity / period)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * period / capacity)
+end
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, wait}
+`
+
+// RedisRateLimitStore keeps buckets in Redis so every replica shares them.
+type RedisRateLimitStore struct {
+	Client RedisClient
+	Prefix string
+}
+
+func (s RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	result, err := s.Client.Eval(ctx, tokenBucketScript, []string{s.Prefix + key},
+		limit.Burst, limit.Period.Milliseconds(), time.Now().UnixMilli())
+	if err != nil {
+		return false, 0, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("rate limit: unexpected reply %v", result)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
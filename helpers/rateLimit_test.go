@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       RateLimit
+		takes       int
+		wantAllowed int
+		wantRetry   time.Duration // roughly, for the first refused take
+	}{
+		{"under the burst", RateLimit{Burst: 5, Period: time.Minute}, 3, 3, 0},
+		{"exactly the burst", RateLimit{Burst: 5, Period: time.Minute}, 5, 5, 0},
+		{"over the burst", RateLimit{Burst: 5, Period: time.Minute}, 8, 5, 12 * time.Second},
+		{"burst of one", RateLimit{Burst: 1, Period: time.Hour}, 2, 1, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRateLimitStore()
+			allowed := 0
+			var retry time.Duration
+			for i := 0; i < tt.takes; i++ {
+				ok, retryAfter, err := store.Take(context.Background(), "key", tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ok {
+					allowed++
+					if retryAfter != 0 {
+						t.Errorf("allowed take %d has retry after %v", i, retryAfter)
+					}
+				} else if retry == 0 {
+					retry = retryAfter
+				}
+			}
+			if allowed != tt.wantAllowed {
+				t.Errorf("allowed %d takes, want %d", allowed, tt.wantAllowed)
+			}
+			if tt.wantRetry != 0 && (retry <= tt.wantRetry-time.Second || retry > tt.wantRetry) {
+				t.Errorf("retry after %v, want about %v", retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreKeys(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Burst: 1, Period: time.Minute}
+
+	if ok, _, _ := store.Take(context.Background(), "a", limit); !ok {
+		t.Fatal("first take of a refused")
+	}
+	if ok, _, _ := store.Take(context.Background(), "a", limit); ok {
+		t.Error("second take of a allowed")
+	}
+	if ok, _, _ := store.Take(context.Background(), "b", limit); !ok {
+		t.Error("take of b refused after a ran out")
+	}
+}
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Burst: 2, Period: time.Minute}
+	for i := 0; i < 2; i++ {
+		store.Take(context.Background(), "key", limit)
+	}
+	if ok, _, _ := store.Take(context.Background(), "key", limit); ok {
+		t.Fatal("take allowed with an empty bucket")
+	}
+
+	// Half the period refills one of the two tokens, and only one
+	store.buckets["key"].last = time.Now().Add(-limit.Period / 2)
+	if ok, _, _ := store.Take(context.Background(), "key", limit); !ok {
+		t.Error("take refused after a token refilled")
+	}
+	if ok, _, _ := store.Take(context.Background(), "key", limit); ok {
+		t.Error("take allowed beyond the refilled token")
+	}
+
+	// A long idle bucket refills no further than the burst
+	store.buckets["key"].last = time.Now().Add(-10 * limit.Period)
+	allowed := 0
+	for i := 0; i < 4; i++ {
+		if ok, _, _ := store.Take(context.Background(), "key", limit); ok {
+			allowed++
+		}
+	}
+	if allowed != limit.Burst {
+		t.Errorf("allowed %d takes after idling, want %d", allowed, limit.Burst)
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Burst: 1, Period: time.Minute}
+	store.Take(context.Background(), "idle", limit)
+	store.Take(context.Background(), "busy", limit)
+
+	store.buckets["idle"].last = time.Now().Add(-2 * limit.Period)
+	store.lastSweep = time.Now().Add(-2 * time.Minute)
+	store.Take(context.Background(), "other", limit)
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
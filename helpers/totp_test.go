@@ -0,0 +1,116 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// totpCode is the code of secret for step.
+func totpCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := totp.GenerateCodeCustom(testTOTPSecret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// currentTOTPStep returns the current step, first waiting out the end of a
+// step that is about to roll over so MatchTOTP sees the same one.
+func currentTOTPStep() int64 {
+	if left := totpPeriod - time.Now().Unix()%totpPeriod; left < 2 {
+		time.Sleep(time.Duration(left) * time.Second)
+	}
+	return time.Now().Unix() / totpPeriod
+}
+
+func TestMatchTOTP(t *testing.T) {
+	current := currentTOTPStep()
+	tests := []struct {
+		name     string
+		step     int64
+		lastStep int64
+		want     bool
+	}{
+		{"current step", current, 0, true},
+		{"one step behind", current - 1, 0, true},
+		{"one step ahead", current + 1, 0, true},
+		{"two steps behind", current - 2, 0, false},
+		{"two steps ahead", current + 2, 0, false},
+		{"replayed code", current, current, false},
+		{"code of an earlier step than the last used", current - 1, current, false},
+		{"later step than the last used", current + 1, current, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := MatchTOTP(testTOTPSecret, totpCode(t, tt.step), tt.lastStep)
+			if ok != tt.want {
+				t.Fatalf("MatchTOTP ok = %v, want %v", ok, tt.want)
+			}
+			if ok && step != tt.step {
+				t.Errorf("MatchTOTP step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPInput(t *testing.T) {
+	current := currentTOTPStep()
+	code := totpCode(t, current)
+
+	if _, ok := MatchTOTP(testTOTPSecret, " "+code+"\n", 0); !ok {
+		t.Error("MatchTOTP refused a code with surrounding space")
+	}
+	if _, ok := MatchTOTP(testTOTPSecret, "", 0); ok {
+		t.Error("MatchTOTP accepted an empty code")
+	}
+	if _, ok := MatchTOTP("not base32!", code, 0); ok {
+		t.Error("MatchTOTP accepted a code for an invalid secret")
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcde-fghij", "abcde-fghij"},
+		{"ABCDE-FGHIJ", "abcde-fghij"},
+		{"abcdefghij", "abcde-fghij"},
+		{"  abcde fghij \t", "abcde-fghij"},
+		{"ab-cde-fg-hij", "abcde-fghij"},
+		{"abcde-fghi", "abcdefghi"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if NormalizeRecoveryCode(code) != code {
+			t.Errorf("code %q is not in normal form", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q was returned twice", code)
+		}
+		seen[code] = true
+	}
+}
@@ -0,0 +1,64 @@
+package helpers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"100.64.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("PublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	for _, rawURL := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"ftp://93.184.216.34/hook",
+		"not a url",
+	} {
+		if err := CheckWebhookURL(context.Background(), rawURL); err != ErrWebhookAddress {
+			t.Errorf("CheckWebhookURL(%q) = %v, want ErrWebhookAddress", rawURL, err)
+		}
+	}
+	if err := CheckWebhookURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("CheckWebhookURL(public address) = %v, want nil", err)
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewWebhookClient(time.Second).Get(server.URL)
+	if err == nil {
+		t.Fatal("webhook client connected to a loopback receiver")
+	}
+}
@@ -0,0 +1,611 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// EncodeWebP writes img as a lossless WebP image. Neither the standard
+// library nor golang.org/x/image can encode WebP, so this is a small VP8L
+// encoder: subtract-green and predictor transforms, then LZ77 and prefix
+// coding. It trades some size for simplicity against libwebp.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return errors.New("webp: invalid image size")
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	pixels := make([]uint32, width*height)
+	hasAlpha := false
+	for i := range pixels {
+		p := nrgba.Pix[i*4 : i*4+4]
+		if p[3] != 0xff {
+			hasAlpha = true
+		}
+		pixels[i] = uint32(p[3])<<24 | uint32(p[0])<<16 | uint32(p[1])<<8 | uint32(p[2])
+	}
+
+	var bw bitWriter
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+
+	// Transforms are undone in the reverse of the order they are written
+	subtractGreen(pixels)
+	bw.write(1, 1)
+	bw.write(2, 2)
+
+	modes, modesWidth := predict(pixels, width, height)
+	bw.write(1, 1)
+	bw.write(0, 2)
+	bw.write(predictorBits-2, 3)
+	writeImageData(&bw, modes, modesWidth, false)
+
+	bw.write(0, 1)
+	writeImageData(&bw, pixels, width, true)
+
+	data := bw.bytes()
+	chunkSize := len(data)
+	padding := chunkSize & 1
+
+	var header [20]byte
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+chunkSize+padding))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(chunkSize))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if padding == 1 {
+		data = append(data, 0)
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (b *bitWriter) write(value uint32, n uint) {
+	b.acc |= uint64(value) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+func (b *bitWriter) bytes() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nbits = 0, 0
+	}
+	return b.buf
+}
+
+func subtractGreen(pixels []uint32) {
+	for i, p := range pixels {
+		green := (p >> 8) & 0xff
+		red := ((p >> 16) - green) & 0xff
+		blue := (p - green) & 0xff
+		pixels[i] = p&0xff00ff00 | red<<16 | blue
+	}
+}
+
+// predictorBits is log2 of the block size that shares a predictor.
+const predictorBits = 4
+
+// predictorModes are the VP8L predictors tried for each block: left, top,
+// average of left and top, and clamped left + top - top-left. Predictors
+// that read the top-right pixel are left out to keep the edge cases simple.
+var predictorModes = []uint32{1, 2, 7, 12}
+
+// predict replaces pixels with their residuals from the best predictor of
+// each block and returns the block modes as a sub-image.
+func predict(pixels []uint32, width, height int) ([]uint32, int) {
+	blockSize := 1 << predictorBits
+	modesWidth := (width + blockSize - 1) >> predictorBits
+	modesHeight := (height + blockSize - 1) >> predictorBits
+	modes := make([]uint32, modesWidth*modesHeight)
+
+	for by := 0; by < modesHeight; by++ {
+		for bx := 0; bx < modesWidth; bx++ {
+			bestMode, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := by * blockSize; y < height && y < (by+1)*blockSize; y++ {
+					for x := bx * blockSize; x < width && x < (bx+1)*blockSize; x++ {
+						cost += residualCost(pixels[y*width+x], predictPixel(pixels, width, x, y, mode))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			modes[by*modesWidth+bx] = 0xff000000 | bestMode<<8
+		}
+	}
+
+	// Predictions read the pixels before their residuals replace them
+	residuals := make([]uint32, len(pixels))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := (modes[(y>>predictorBits)*modesWidth+(x>>predictorBits)] >> 8) & 0xf
+			i := y*width + x
+			residuals[i] = subPixels(pixels[i], predictPixel(pixels, width, x, y, mode))
+		}
+	}
+	copy(pixels, residuals)
+	return modes, modesWidth
+}
+
+func predictPixel(pixels []uint32, width, x, y int, mode uint32) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return pixels[x-1]
+	case x == 0:
+		return pixels[(y-1)*width]
+	}
+	left := pixels[y*width+x-1]
+	top := pixels[(y-1)*width+x]
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	case 7:
+		return average2(left, top)
+	default:
+		return clampAddSubtractFull(left, top, pixels[(y-1)*width+x-1])
+	}
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	var result uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int((a>>shift)&0xff) + int((b>>shift)&0xff) - int((c>>shift)&0xff)
+		if v < 0 {
+			v = 0
+		} else if v > 255 {
+			v = 255
+		}
+		result |= uint32(v) << shift
+	}
+	return result
+}
+
+func subPixels(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+func residualCost(pixel, prediction uint32) int {
+	residual := subPixels(pixel, prediction)
+	cost := 0
+	for shift := uint(0); shift < 32; shift += 8 {
+		cost += int(abs8(int8(residual >> shift)))
+	}
+	return cost
+}
+
+func abs8(v int8) int {
+	if v < 0 {
+		return -int(v)
+	}
+	return int(v)
+}
+
+const (
+	numLiteralCodes = 256
+	numLengthCodes  = 24
+	numDistanceCode = 40
+	minMatchLength  = 3
+	maxMatchLength  = 4096
+	// Distances are sent plus 120, past the codes for nearby pixels
+	maxMatchDistance = 1<<20 - 120
+	hashBits         = 16
+	maxChainLength   = 16
+)
+
+type vp8lToken struct {
+	// length is zero for a literal pixel
+	length   int
+	value    uint32
+	distance int
+}
+
+// backwardReferences finds repeated runs of pixels with a hash chain.
+func backwardReferences(pixels []uint32) []vp8lToken {
+	tokens := make([]vp8lToken, 0, len(pixels)/2)
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	chain := make([]int32, len(pixels))
+
+	hash := func(i int) uint32 {
+		return (pixels[i]*0x9e3779b1 ^ pixels[i+1]*0x85ebca6b) >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+1 < len(pixels) {
+			h := hash(i)
+			chain[i] = head[h]
+			head[h] = int32(i)
+		}
+	}
+
+	for i := 0; i < len(pixels); {
+		bestLength, bestDistance := 0, 0
+		if i+1 < len(pixels) {
+			limit := len(pixels) - i
+			if limit > maxMatchLength {
+				limit = maxMatchLength
+			}
+			candidate := head[hash(i)]
+			for steps := 0; candidate >= 0 && steps < maxChainLength; steps++ {
+				distance := i - int(candidate)
+				if distance > maxMatchDistance {
+					break
+				}
+				length := 0
+				for length < limit && pixels[int(candidate)+length] == pixels[i+length] {
+					length++
+				}
+				if length > bestLength {
+					bestLength, bestDistance = length, distance
+					if length == limit {
+						break
+					}
+				}
+				candidate = chain[candidate]
+			}
+		}
+
+		if bestLength >= minMatchLength {
+			tokens = append(tokens, vp8lToken{length: bestLength, distance: bestDistance})
+			for j := i; j < i+bestLength; j++ {
+				insert(j)
+			}
+			i += bestLength
+			continue
+		}
+		tokens = append(tokens, vp8lToken{value: pixels[i]})
+		insert(i)
+		i++
+	}
+	return tokens
+}
+
+// prefixEncode splits a length or distance into a prefix code and extra
+// bits.
+func prefixEncode(value int) (code int, extraBits uint, extra uint32) {
+	d := value - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	highBit := bits.Len(uint(d)) - 1
+	second := (d >> (highBit - 1)) & 1
+	extraBits = uint(highBit - 1)
+	return 2*highBit + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+// writeImageData writes pixels as an entropy-coded image: the main image
+// also says it has no color cache and one group of prefix codes.
+func writeImageData(bw *bitWriter, pixels []uint32, width int, mainImage bool) {
+	tokens := backwardReferences(pixels)
+
+	green := make([]int, numLiteralCodes+numLengthCodes)
+	red := make([]int, numLiteralCodes)
+	blue := make([]int, numLiteralCodes)
+	alpha := make([]int, numLiteralCodes)
+	distance := make([]int, numDistanceCode)
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[(t.value>>8)&0xff]++
+			red[(t.value>>16)&0xff]++
+			blue[t.value&0xff]++
+			alpha[t.value>>24]++
+			continue
+		}
+		lengthCode, _, _ := prefixEncode(t.length)
+		green[numLiteralCodes+lengthCode]++
+		distanceCode, _, _ := prefixEncode(t.distance + 120)
+		distance[distanceCode]++
+	}
+
+	// No color cache
+	bw.write(0, 1)
+	if mainImage {
+		// No meta prefix codes
+		bw.write(0, 1)
+	}
+
+	greenCode := writePrefixCode(bw, green)
+	redCode := writePrefixCode(bw, red)
+	blueCode := writePrefixCode(bw, blue)
+	alphaCode := writePrefixCode(bw, alpha)
+	distanceCode := writePrefixCode(bw, distance)
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			greenCode.write(bw, int((t.value>>8)&0xff))
+			redCode.write(bw, int((t.value>>16)&0xff))
+			blueCode.write(bw, int(t.value&0xff))
+			alphaCode.write(bw, int(t.value>>24))
+			continue
+		}
+		code, extraBits, extra := prefixEncode(t.length)
+		greenCode.write(bw, numLiteralCodes+code)
+		bw.write(extra, extraBits)
+		code, extraBits, extra = prefixEncode(t.distance + 120)
+		distanceCode.write(bw, code)
+		bw.write(extra, extraBits)
+	}
+}
+
+// prefixCode holds the bit-reversed canonical code of every symbol.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (c prefixCode) write(bw *bitWriter, symbol int) {
+	bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode picks a prefix code for a histogram, writes it and returns
+// it for coding the symbols.
+func writePrefixCode(bw *bitWriter, counts []int) prefixCode {
+	var used []int
+	for symbol, count := range counts {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	// One or two symbols below 256 fit the short form. A single symbol then
+	// takes no bits at all.
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		lengths := make([]uint8, len(counts))
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		return canonicalCode(lengths)
+	}
+
+	lengths := huffmanLengths(counts, 15)
+	bw.write(0, 1)
+	writeCodeLengths(bw, lengths)
+	return canonicalCode(lengths)
+}
+
+// codeLengthOrder is the order in which the code length code is sent.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+type codeLengthToken struct {
+	symbol int
+	extra  uint32
+}
+
+// writeCodeLengths sends the code lengths of a prefix code, themselves
+// prefix coded, with runs of lengths shortened by symbols 16 to 18.
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		value := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == value {
+			run++
+		}
+		i += run
+
+		if value == 0 {
+			for run >= 11 {
+				n := run
+				if n > 138 {
+					n = 138
+				}
+				tokens = append(tokens, codeLengthToken{18, uint32(n - 11)})
+				run -= n
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{17, uint32(run - 3)})
+				run = 0
+			}
+		} else {
+			tokens = append(tokens, codeLengthToken{int(value), 0})
+			run--
+			for run >= 3 {
+				n := run
+				if n > 6 {
+					n = 6
+				}
+				tokens = append(tokens, codeLengthToken{16, uint32(n - 3)})
+				run -= n
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{int(value), 0})
+		}
+	}
+
+	counts := make([]int, len(codeLengthOrder))
+	for _, t := range tokens {
+		counts[t.symbol]++
+	}
+	// A code needs two symbols to be complete
+	used := 0
+	for _, count := range counts {
+		if count > 0 {
+			used++
+		}
+	}
+	for symbol := 0; used < 2; symbol++ {
+		if counts[symbol] == 0 {
+			counts[symbol] = 1
+			used++
+		}
+	}
+	code := canonicalCode(huffmanLengths(counts, 7))
+
+	n := len(codeLengthOrder)
+	for n > 4 && code.lengths[codeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	bw.write(uint32(n-4), 4)
+	for _, symbol := range codeLengthOrder[:n] {
+		bw.write(uint32(code.lengths[symbol]), 3)
+	}
+
+	// Every length is sent, so no max_symbol
+	bw.write(0, 1)
+	for _, t := range tokens {
+		code.write(bw, t.symbol)
+		switch t.symbol {
+		case 16:
+			bw.write(t.extra, 2)
+		case 17:
+			bw.write(t.extra, 3)
+		case 18:
+			bw.write(t.extra, 7)
+		}
+	}
+}
+
+// huffmanLengths returns Huffman code lengths for counts no longer than
+// limit, flattening the counts until the code fits. Two or more symbols must
+// be used.
+func huffmanLengths(counts []int, limit int) []uint8 {
+	counts = append([]int(nil), counts...)
+	for {
+		lengths, maxLength := buildHuffman(counts)
+		if maxLength <= limit {
+			return lengths
+		}
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+func buildHuffman(counts []int) ([]uint8, int) {
+	type node struct {
+		weight int
+		parent int
+	}
+	var leaves []int
+	for symbol, count := range counts {
+		if count > 0 {
+			leaves = append(leaves, symbol)
+		}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return counts[leaves[i]] < counts[leaves[j]] })
+
+	// Leaves sorted by weight and internal nodes, made in order of weight,
+	// are merged like two queues
+	nodes := make([]node, 0, 2*len(leaves))
+	for _, symbol := range leaves {
+		nodes = append(nodes, node{weight: counts[symbol], parent: -1})
+	}
+	nextLeaf, nextInternal := 0, len(leaves)
+	pop := func() int {
+		if nextLeaf < len(leaves) && (nextInternal >= len(nodes) || nodes[nextLeaf].weight <= nodes[nextInternal].weight) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextInternal++
+		return nextInternal - 1
+	}
+	for merges := 0; merges < len(leaves)-1; merges++ {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent = len(nodes) - 1
+		nodes[b].parent = len(nodes) - 1
+	}
+
+	// Parents come after their children, so depths fill in from the root
+	depths := make([]int, len(nodes))
+	for i := len(nodes) - 2; i >= 0; i-- {
+		depths[i] = depths[nodes[i].parent] + 1
+	}
+
+	lengths := make([]uint8, len(counts))
+	maxLength := 0
+	for i, symbol := range leaves {
+		lengths[symbol] = uint8(depths[i])
+		if depths[i] > maxLength {
+			maxLength = depths[i]
+		}
+	}
+	return lengths, maxLength
+}
+
+// canonicalCode assigns canonical codes to lengths, bit-reversed for
+// writing least significant bit first.
+func canonicalCode(lengths []uint8) prefixCode {
+	var lengthCounts [16]uint32
+	for _, length := range lengths {
+		lengthCounts[length]++
+	}
+	lengthCounts[0] = 0
+
+	var nextCode [16]uint32
+	code := uint32(0)
+	for length := 1; length < 16; length++ {
+		code = (code + lengthCounts[length-1]) << 1
+		nextCode[length] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		codes[symbol] = bits.Reverse32(nextCode[length]) >> (32 - uint(length))
+		nextCode[length]++
+	}
+	return prefixCode{lengths: lengths, codes: codes}
+}
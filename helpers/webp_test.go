@@ -0,0 +1,109 @@
+package helpers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// roundTrip encodes img and decodes it again with golang.org/x/image/webp.
+func roundTrip(t *testing.T, img image.Image) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img); err != nil {
+		t.Fatalf("EncodeWebP: %v", err)
+	}
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("webp.Decode: %v", err)
+	}
+	return decoded
+}
+
+func assertSamePixels(t *testing.T, want *image.NRGBA, got image.Image) {
+	t.Helper()
+	if got.Bounds().Dx() != want.Bounds().Dx() || got.Bounds().Dy() != want.Bounds().Dy() {
+		t.Fatalf("decoded size %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	for y := 0; y < want.Bounds().Dy(); y++ {
+		for x := 0; x < want.Bounds().Dx(); x++ {
+			w := want.NRGBAAt(want.Bounds().Min.X+x, want.Bounds().Min.Y+y)
+			g := color.NRGBAModel.Convert(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y)).(color.NRGBA)
+			// Fully transparent pixels carry no color once decoded
+			if w.A == 0 && g.A == 0 {
+				continue
+			}
+			if w != g {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 200, G: 10, B: 30, A: 255}
+		}},
+		{"flat colour", 17, 9, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 12, G: 34, B: 56, A: 255}
+		}},
+		{"gradient odd size", 33, 65, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 7), G: uint8(y * 3), B: uint8(x + y), A: 255}
+		}},
+		{"noise", 101, 37, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+		}},
+		{"alpha", 23, 31, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 11), G: uint8(y * 5), B: 90, A: uint8((x + y) * 6)}
+		}},
+		{"noisy alpha", 64, 3, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: uint8(rng.Intn(256))}
+		}},
+		{"repeating pattern", 257, 5, func(x, y int) color.NRGBA {
+			v := uint8(x % 4 * 60)
+			return color.NRGBA{R: v, G: v, B: 255 - v, A: 255}
+		}},
+		{"tall strip", 1, 300, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(y), G: uint8(y / 2), B: 0, A: 255}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					img.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+			assertSamePixels(t, img, roundTrip(t, img))
+		})
+	}
+}
+
+func TestEncodeWebPSubImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 10), G: uint8(y * 10), B: 128, A: 255})
+		}
+	}
+	sub := img.SubImage(image.Rect(3, 5, 16, 12)).(*image.NRGBA)
+	assertSamePixels(t, sub, roundTrip(t, sub))
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 5))); err == nil {
+		t.Error("EncodeWebP accepted an empty image")
+	}
+}
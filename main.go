@@ -1,10 +1,16 @@
 package main
 
 import (
+	"basic-trade-api/configs"
 	"basic-trade-api/database"
+	"basic-trade-api/helpers"
 	"basic-trade-api/router"
+	"basic-trade-api/services"
+	"basic-trade-api/workers"
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,8 +27,27 @@ func main() {
 	DB := database.StartDB()
 	defer DB.Close()
 
+	// Start the background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stockHub := services.NewStockHub()
+	notificationHub := services.NewNotificationHub()
+	// Tokens cannot be issued or checked before the signing keys are loaded
+	if err := workers.RefreshSigningKeys(ctx, DB); err != nil {
+		log.Fatal("Failed to load signing keys: ", err)
+	}
+	go workers.StartKeyRotation(ctx, DB)
+	go workers.StartReservationSweeper(ctx, DB, configs.EnvReservationSweepInterval())
+	go workers.StartProductScheduler(ctx, DB, configs.EnvProductScheduleInterval())
+	go workers.StartLowStockChecker(ctx, DB, configs.EnvLowStockCheckInterval(), workers.NewLowStockSinks(DB))
+	go workers.StartOutboxRelay(ctx, DB, configs.EnvOutboxRelayInterval(), workers.NewOutboxPublishers(DB))
+	go workers.StartWebhookDispatcher(ctx, DB, configs.EnvWebhookDispatchInterval())
+	go workers.StartImageCleanup(ctx, DB, configs.EnvImageCleanupInterval())
+	go workers.StartStockStream(ctx, DB, stockHub, configs.EnvStockStreamRetention())
+	go workers.StartAdminNotifications(ctx, notificationHub)
+
 	// Initialize the router
-	r := router.StartApp(DB)
+	r := router.StartApp(DB, stockHub, notificationHub, helpers.NewMailer())
 	fmt.Println("Server is running on", PORT)
 
 	// Start the server
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminEmailValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var emailRequest admin.AdminEmailRequest
+		if err := ctx.ShouldBindJSON(&emailRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(emailRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", emailRequest)
+		ctx.Next()
+	}
+}
+
+func ResetPasswordValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var resetRequest admin.ResetPasswordRequest
+		if err := ctx.ShouldBindJSON(&resetRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(resetRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", resetRequest)
+		ctx.Next()
+	}
+}
+
+func VerifyEmailValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var verifyRequest admin.VerifyEmailRequest
+		if err := ctx.ShouldBindJSON(&verifyRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(verifyRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", verifyRequest)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func UpdateAdminValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var updateRequest admin.UpdateAdminRequest
+		if err := ctx.ShouldBindJSON(&updateRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(updateRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", updateRequest)
+		ctx.Next()
+	}
+}
+
+func ChangePasswordValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var passwordRequest admin.ChangePasswordRequest
+		if err := ctx.ShouldBindJSON(&passwordRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(passwordRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", passwordRequest)
+		ctx.Next()
+	}
+}
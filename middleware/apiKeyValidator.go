@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/apikey"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func APIKeyValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var apiKeyRequest apikey.APIKeyRequest
+		if err := ctx.ShouldBindJSON(&apiKeyRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := apikey.Validate.Struct(apiKeyRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", apiKeyRequest)
+		ctx.Next()
+	}
+}
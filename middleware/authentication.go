@@ -2,13 +2,29 @@ package middleware
 
 import (
 	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"basic-trade-api/services"
+	"database/sql"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	jwt5 "github.com/golang-jwt/jwt/v5"
 )
 
+// Authentication accepts either a Bearer access token from a login or an
+// "ApiKey" personal API key. Both set adminData; API keys also set
+// apiKeyScopes, which RequireScope and RequireSession check.
 func Authentication() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if header := ctx.GetHeader("Authorization"); strings.HasPrefix(header, "ApiKey ") {
+			authenticateAPIKey(ctx, strings.TrimPrefix(header, "ApiKey "))
+			return
+		}
+
 		verifyToken, err := helpers.VerifyToken(ctx)
 
 		if err != nil {
@@ -19,7 +35,239 @@ func Authentication() gin.HandlerFunc {
 			return
 		}
 
+		// A token outlives a deactivation or password change, so the admin
+		// is looked up again on every request
+		claims, _ := verifyToken.(jwt5.MapClaims)
+		if !checkAdminState(ctx, claims) {
+			return
+		}
+
 		ctx.Set("adminData", verifyToken)
 		ctx.Next()
 	}
 }
+
+// OptionalAuthentication authenticates requests that carry an Authorization
+// header, as Authentication does, and lets anonymous ones through. It is for
+// public endpoints that show an admin more than everyone else.
+func OptionalAuthentication() gin.HandlerFunc {
+	authenticate := Authentication()
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			ctx.Next()
+			return
+		}
+		authenticate(ctx)
+	}
+}
+
+func checkAdminState(ctx *gin.Context, claims jwt5.MapClaims) bool {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return false
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthenticated",
+			"message": "sign in to proceed",
+		})
+		return false
+	}
+
+	adminID := int(claims["id"].(float64))
+	state, err := services.GetAdminAuthStateService(ctx.Request.Context(), dbConn, adminID, issuedAt.Time)
+	if err != nil {
+		if err.Error() == "admin not found" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthenticated",
+				"message": "sign in to proceed",
+			})
+			return false
+		}
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": err.Error()})
+		return false
+	}
+
+	if !state.Active {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthenticated",
+			"message": "account deactivated",
+		})
+		return false
+	}
+	if !state.TokenCurrent {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthenticated",
+			"message": "token has been revoked, sign in again",
+		})
+		return false
+	}
+
+	ctx.Set("adminRole", state.Role)
+	setAuditActor(ctx, adminID, "")
+	return true
+}
+
+func authenticateAPIKey(ctx *gin.Context, key string) {
+	db, _ := ctx.Get("db")
+	dbConn, ok := db.(*sql.DB)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "Failed to cast database connection to *sql.DB",
+		})
+		return
+	}
+
+	principal, err := services.AuthenticateAPIKeyService(ctx.Request.Context(), dbConn, strings.TrimSpace(key))
+	if err != nil {
+		if err.Error() != "invalid api key" {
+			log.Println("api key authentication:", err)
+		}
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthenticated",
+			"message": "invalid api key",
+		})
+		return
+	}
+
+	// Shaped like token claims so handlers need not care how the admin
+	// authenticated
+	ctx.Set("adminData", jwt5.MapClaims{
+		"id":    float64(principal.AdminID),
+		"email": principal.Email,
+	})
+	ctx.Set("adminRole", principal.Role)
+	setAuditActor(ctx, principal.AdminID, principal.KeyUUID)
+	ctx.Set("apiKeyUUID", principal.KeyUUID)
+	ctx.Set("apiKeyScopes", principal.Scopes)
+	ctx.Next()
+}
+
+// RequireScope lets API keys through only if they were granted scope.
+// Admins who logged in have every scope. It must run after Authentication.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value, isAPIKey := ctx.Get("apiKeyScopes")
+		if !isAPIKey {
+			ctx.Next()
+			return
+		}
+		scopes, _ := value.([]string)
+		for _, granted := range scopes {
+			if granted == scope {
+				ctx.Next()
+				return
+			}
+		}
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":   "Insufficient scope",
+			"message": "This API key does not have the " + scope + " scope",
+		})
+	}
+}
+
+// RequireSession keeps API keys out of endpoints that manage credentials, so
+// a leaked key cannot be used to mint more keys or turn off two-factor. It
+// must run after Authentication.
+func RequireSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, isAPIKey := ctx.Get("apiKeyScopes"); isAPIKey {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Session required",
+				"message": "Log in with a password to use this endpoint",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireSuperadmin lets only superadmins through. It must run after
+// Authentication.
+func RequireSuperadmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetString("adminRole") != admin.RoleSuperadmin {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Only superadmins can use this endpoint",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// WebSocketToken lets browser WebSocket clients, which cannot set request
+// headers, send their token as the access_token query parameter. It must run
+// before Authentication.
+func WebSocketToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			if token := ctx.Query("access_token"); token != "" {
+				ctx.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// RateLimit rejects a request with 429 once the bucket picked by key is
+// empty. Requests for which key returns "" are not limited. If the store
+// fails the request is let through, so an outage of a shared store does not
+// take logins down with it.
+func RateLimit(store helpers.RateLimitStore, name string, limit helpers.RateLimit, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		value := key(ctx)
+		if value == "" {
+			ctx.Next()
+			return
+		}
+
+		allowed, retryAfter, err := store.Take(ctx.Request.Context(), name+":"+value, limit)
+		if err != nil {
+			log.Printf("rate limit %s: %v", name, err)
+			ctx.Next()
+			return
+		}
+		if !allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too many requests",
+				"message": "Too many attempts, please try again later",
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// ClientIPKey limits by the client's IP address.
+func ClientIPKey(ctx *gin.Context) string {
+	return ctx.ClientIP()
+}
+
+// LoginEmailKey limits by the email of a login request. It must run after
+// LoginValidator.
+func LoginEmailKey(ctx *gin.Context) string {
+	loginRequest, ok := ctx.MustGet("request").(admin.AdminLoginRequest)
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(loginRequest.Email))
+}
+
+// AdminEmailKey limits by the email of a request that mails an admin. It must
+// run after AdminEmailValidator.
+func AdminEmailKey(ctx *gin.Context) string {
+	emailRequest, ok := ctx.MustGet("request").(admin.AdminEmailRequest)
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(emailRequest.Email))
+}
@@ -24,3 +24,159 @@ func ProductValidator() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+func ProductImageValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var imageRequest product.ProductImageRequest
+		if err := ctx.ShouldBind(&imageRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(imageRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", imageRequest)
+		ctx.Next()
+	}
+}
+
+func ProductImageUpdateValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var imageRequest product.UpdateProductImageRequest
+		if err := ctx.ShouldBindJSON(&imageRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(imageRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", imageRequest)
+		ctx.Next()
+	}
+}
+
+func ProductImageOrderValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var orderRequest product.ReorderProductImagesRequest
+		if err := ctx.ShouldBindJSON(&orderRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(orderRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", orderRequest)
+		ctx.Next()
+	}
+}
+
+func ProductImageConfirmValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var confirmRequest product.ConfirmImageUploadRequest
+		if err := ctx.ShouldBindJSON(&confirmRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(confirmRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", confirmRequest)
+		ctx.Next()
+	}
+}
+
+func ProductOptionsValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var optionsRequest product.ProductOptionsRequest
+		if err := ctx.ShouldBindJSON(&optionsRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(optionsRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", optionsRequest)
+		ctx.Next()
+	}
+}
+
+func VariantMatrixValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var matrixRequest product.VariantMatrixRequest
+		if err := ctx.ShouldBindJSON(&matrixRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		if err := product.Validate.Struct(matrixRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", matrixRequest)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDPattern is what an incoming X-Request-ID must look like to be
+// kept. Anything else is replaced, so log lines and audit entries cannot be
+// forged with arbitrary text.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID tags every request with an ID, taken from the X-Request-ID header
+// when a proxy in front already set one, and echoes it back. The ID and the
+// client's IP are attached to the request context for the audit log.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID, _ = helpers.RandomToken(16)
+		}
+		ctx.Set("requestID", requestID)
+		ctx.Header("X-Request-ID", requestID)
+
+		audit, _ := database.AuditFrom(ctx.Request.Context())
+		audit.RequestID = requestID
+		audit.IPAddress = ctx.ClientIP()
+		ctx.Request = ctx.Request.WithContext(database.WithAudit(ctx.Request.Context(), audit))
+		ctx.Next()
+	}
+}
+
+// setAuditActor records the authenticated admin, and the API key they used if
+// any, as the actor of the request's changes.
+func setAuditActor(ctx *gin.Context, adminID int, apiKeyUUID string) {
+	audit, _ := database.AuditFrom(ctx.Request.Context())
+	audit.ActorID = adminID
+	audit.APIKeyUUID = apiKeyUUID
+	ctx.Request = ctx.Request.WithContext(database.WithAudit(ctx.Request.Context(), audit))
+}
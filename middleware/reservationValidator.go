@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/reservation"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var reservationRequest reservation.ReservationRequest
+		if err := ctx.ShouldBindJSON(&reservationRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := reservation.Validate.Struct(reservationRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", reservationRequest)
+		ctx.Next()
+	}
+}
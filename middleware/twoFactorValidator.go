@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TwoFactorCodeValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var codeRequest admin.TwoFactorCodeRequest
+		if err := ctx.ShouldBindJSON(&codeRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(codeRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", codeRequest)
+		ctx.Next()
+	}
+}
+
+func TwoFactorLoginValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var challengeRequest admin.TwoFactorLoginRequest
+		if err := ctx.ShouldBindJSON(&challengeRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(challengeRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", challengeRequest)
+		ctx.Next()
+	}
+}
+
+func DisableTwoFactorValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var disableRequest admin.DisableTwoFactorRequest
+		if err := ctx.ShouldBindJSON(&disableRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := admin.Validate.Struct(disableRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", disableRequest)
+		ctx.Next()
+	}
+}
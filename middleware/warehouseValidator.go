@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/warehouse"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WarehouseValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var warehouseRequest warehouse.WarehouseRequest
+		if err := ctx.ShouldBindJSON(&warehouseRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := warehouse.Validate.Struct(warehouseRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", warehouseRequest)
+		ctx.Next()
+	}
+}
+
+func StockValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var stockRequest warehouse.StockRequest
+		if err := ctx.ShouldBindJSON(&stockRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := warehouse.Validate.Struct(stockRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", stockRequest)
+		ctx.Next()
+	}
+}
+
+func TransferValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var transferRequest warehouse.TransferRequest
+		if err := ctx.ShouldBindJSON(&transferRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := warehouse.Validate.Struct(transferRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", transferRequest)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/webhook"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WebhookValidator() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var webhookRequest webhook.WebhookRequest
+		if err := ctx.ShouldBindJSON(&webhookRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Failed to validate request",
+			})
+			return
+		}
+
+		// Validate the request using the Validate struct
+		if err := webhook.Validate.Struct(webhookRequest); err != nil {
+			errors := helpers.GeneralValidator(err)
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   errors,
+				"message": "Validation errors",
+			})
+			return
+		}
+
+		ctx.Set("request", webhookRequest)
+		ctx.Next()
+	}
+}
@@ -15,4 +15,48 @@ type AdminLoginRequest struct {
 	Password string `json:"password" binding:"required,min=3,max=100" validate:"required,min=3,max=100"`
 }
 
+// AdminEmailRequest asks for mail to be sent to an admin, such as a password
+// reset link or a new verification link.
+type AdminEmailRequest struct {
+	Email string `json:"email" binding:"required,email" validate:"required,email,max=100"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required" validate:"required,max=100"`
+	Password string `json:"password" binding:"required,min=6,max=100" validate:"required,min=6,max=100"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" validate:"required,max=100"`
+}
+
+// TwoFactorCodeRequest carries a code from an authenticator app, or a
+// recovery code where those are accepted.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" validate:"required,max=32"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required" validate:"required,max=100"`
+	Code           string `json:"code" binding:"required" validate:"required,max=32"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required,max=100" validate:"required,max=100"`
+	Code     string `json:"code" binding:"required" validate:"required,max=32"`
+}
+
+// UpdateAdminRequest changes an admin's own profile. Changing the email
+// needs the current password and a new verification.
+type UpdateAdminRequest struct {
+	Name            *string `json:"name" validate:"omitempty,min=3,max=100"`
+	Email           *string `json:"email" validate:"omitempty,email,max=100"`
+	CurrentPassword string  `json:"currentPassword" validate:"max=100"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required,max=100" validate:"required,max=100"`
+	NewPassword     string `json:"newPassword" binding:"required,min=6,max=100" validate:"required,min=6,max=100"`
+}
+
 var Validate = validator.New()
\ No newline at end of file
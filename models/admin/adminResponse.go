@@ -2,12 +2,21 @@ package admin
 
 import "time"
 
+// Roles an admin can have. Superadmins also manage the other admins.
+const (
+	RoleAdmin      = "admin"
+	RoleSuperadmin = "superadmin"
+)
+
 type AdminResponse struct {
-	ID        int       `json:"id"`
-	UUID      string    `json:"uuid"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID               int        `json:"id"`
+	UUID             string     `json:"uuid"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	EmailVerified    bool       `json:"emailVerified"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+	DeactivatedAt    *time.Time `json:"deactivatedAt"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
 }
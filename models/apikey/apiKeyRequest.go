@@ -0,0 +1,26 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Scopes an API key can be granted. Logging in with a password grants all of
+// them; API key and two-factor management is never open to API keys.
+const (
+	ScopeCatalogWrite      = "catalog:write"
+	ScopeStockRead         = "stock:read"
+	ScopeStockWrite        = "stock:write"
+	ScopeWebhooksManage    = "webhooks:manage"
+	ScopeExportsRead       = "exports:read"
+	ScopeNotificationsRead = "notifications:read"
+)
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required" validate:"required,min=1,dive,oneof=catalog:write stock:read stock:write webhooks:manage exports:read notifications:read"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+var Validate = validator.New()
@@ -0,0 +1,26 @@
+package apikey
+
+import "time"
+
+type APIKeyResponse struct {
+	ID         int        `json:"id"`
+	UUID       string     `json:"uuid"`
+	AdminID    int        `json:"adminId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"` // only returned on create
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Principal is the admin an API key acts for, with what it may do.
+type Principal struct {
+	KeyUUID string
+	AdminID int
+	Email   string
+	Role    string
+	Scopes  []string
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entities with an audit trail.
+const (
+	EntityAdmin              = "admin"
+	EntityProduct            = "product"
+	EntityVariant            = "variant"
+	EntityProductOption      = "product_option"
+	EntityProductOptionValue = "product_option_value"
+)
+
+// Entities lists every audited entity.
+var Entities = []string{EntityAdmin, EntityProduct, EntityVariant, EntityProductOption, EntityProductOptionValue}
+
+// AuditEntry is one recorded change. Before and After hold only the columns
+// an update changed; a create has no Before and a delete no After. Entries
+// made outside a request, such as by background workers, have no actor.
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	UUID       string          `json:"uuid"`
+	Action     string          `json:"action"`
+	Entity     string          `json:"entity"`
+	EntityID   string          `json:"entityId"`
+	ActorID    *int            `json:"actorId"`
+	ActorEmail *string         `json:"actorEmail"`
+	APIKeyUUID *string         `json:"apiKeyUuid"`
+	RequestID  *string         `json:"requestId"`
+	IPAddress  *string         `json:"ipAddress"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
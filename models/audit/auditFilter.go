@@ -0,0 +1,14 @@
+package audit
+
+import "time"
+
+// AuditFilter holds the optional filters for listing audit entries.
+type AuditFilter struct {
+	Entity string
+	// EntityID is the UUID of a single admin, product or variant
+	EntityID string
+	// Actor is the UUID of the admin who made the changes
+	Actor string
+	From  *time.Time
+	To    *time.Time
+}
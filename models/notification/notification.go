@@ -0,0 +1,32 @@
+package notification
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification types. Products and their variants can only be edited or
+// deleted by their owner, so another admin's changes reach the owner only as
+// a variant created in, or moved into, one of their products. Stock alerts
+// go to the product owner whoever caused them.
+const (
+	TypeVariantCreated = "variant.created"
+	TypeVariantUpdated = "variant.updated" // moved in from another product
+	TypeStockLow       = "stock.low"
+)
+
+// Notification is a real-time message for one admin's dashboard.
+type Notification struct {
+	Type      string          `json:"type"`
+	AdminID   int             `json:"adminId"`
+	ActorID   *int            `json:"actorId,omitempty"` // admin who made the change, if any
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// ClientMessage is sent by dashboard clients to choose which notification
+// types they receive. A topic is a full type or the part before the dot.
+type ClientMessage struct {
+	Type   string   `json:"type"` // subscribe or unsubscribe
+	Topics []string `json:"topics"`
+}
@@ -0,0 +1,17 @@
+package outbox
+
+import "time"
+
+// Event is a domain event recorded in the outbox alongside the change that
+// caused it, as handed to the relay's publishers.
+type Event struct {
+	ID            int64
+	UUID          string
+	AggregateType string
+	AggregateID   string
+	Type          string
+	Payload       []byte
+	AdminID       int // owner of the event's product, zero if unknown
+	Attempts      int
+	CreatedAt     time.Time
+}
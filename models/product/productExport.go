@@ -0,0 +1,70 @@
+package product
+
+import (
+	"strconv"
+	"time"
+)
+
+// ProductExportRow is one flattened product/variant pair. Products without
+// variants are exported once with the variant columns left empty.
+type ProductExportRow struct {
+	ProductID        int        `json:"productId"`
+	ProductUUID      string     `json:"productUuid"`
+	ProductName      string     `json:"productName"`
+	ImageURL         string     `json:"imageUrl"`
+	AdminID          int        `json:"adminId"`
+	ProductCreatedAt time.Time  `json:"productCreatedAt"`
+	ProductUpdatedAt time.Time  `json:"productUpdatedAt"`
+	VariantID        *int       `json:"variantId"`
+	VariantUUID      *string    `json:"variantUuid"`
+	VariantName      *string    `json:"variantName"`
+	SKU              *string    `json:"sku"`
+	Barcode          *string    `json:"barcode"`
+	Quantity         *int       `json:"quantity"`
+	VariantCreatedAt *time.Time `json:"variantCreatedAt"`
+	VariantUpdatedAt *time.Time `json:"variantUpdatedAt"`
+}
+
+var ExportHeader = []string{
+	"productId", "productUuid", "productName", "imageUrl", "adminId", "productCreatedAt", "productUpdatedAt",
+	"variantId", "variantUuid", "variantName", "sku", "barcode", "quantity", "variantCreatedAt", "variantUpdatedAt",
+}
+
+// Record returns the row as strings in ExportHeader order.
+func (r ProductExportRow) Record() []string {
+	record := []string{
+		strconv.Itoa(r.ProductID),
+		r.ProductUUID,
+		r.ProductName,
+		r.ImageURL,
+		strconv.Itoa(r.AdminID),
+		r.ProductCreatedAt.Format(time.RFC3339),
+		r.ProductUpdatedAt.Format(time.RFC3339),
+		"", "", "", "", "", "", "", "",
+	}
+	if r.VariantID != nil {
+		record[7] = strconv.Itoa(*r.VariantID)
+	}
+	if r.VariantUUID != nil {
+		record[8] = *r.VariantUUID
+	}
+	if r.VariantName != nil {
+		record[9] = *r.VariantName
+	}
+	if r.SKU != nil {
+		record[10] = *r.SKU
+	}
+	if r.Barcode != nil {
+		record[11] = *r.Barcode
+	}
+	if r.Quantity != nil {
+		record[12] = strconv.Itoa(*r.Quantity)
+	}
+	if r.VariantCreatedAt != nil {
+		record[13] = r.VariantCreatedAt.Format(time.RFC3339)
+	}
+	if r.VariantUpdatedAt != nil {
+		record[14] = r.VariantUpdatedAt.Format(time.RFC3339)
+	}
+	return record
+}
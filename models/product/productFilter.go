@@ -0,0 +1,11 @@
+package product
+
+// ProductFilter holds the optional filters for listing products.
+type ProductFilter struct {
+	Name string
+	// Status keeps only products with this status
+	Status string
+	// ViewerID is the admin asking, if any. Admins see all of their own
+	// products; everything else is limited to published products.
+	ViewerID int
+}
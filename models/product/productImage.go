@@ -0,0 +1,71 @@
+package product
+
+import (
+	"mime/multipart"
+	"time"
+)
+
+// ProductImage is one photo in a product's gallery. The primary image is also
+// the product's imageUrl.
+type ProductImage struct {
+	ID   int    `json:"id"`
+	UUID string `json:"uuid"`
+	URL  string `json:"url"`
+	// PublicID names the stored asset; it is unknown for images uploaded
+	// before galleries existed
+	PublicID  *string `json:"-"`
+	AltText   string  `json:"altText"`
+	Position  int     `json:"position"`
+	IsPrimary bool    `json:"isPrimary"`
+	// Sizes holds the original and every thumbnail by name
+	Sizes     map[string]ImageSize `json:"sizes"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+// ImageSize is one stored size of an image, in WebP.
+type ImageSize struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type ProductImageRequest struct {
+	ImageFile *multipart.FileHeader `form:"file" binding:"required"`
+	AltText   string                `form:"altText" validate:"max=255"`
+	// Primary makes the image the product's main one. A product's first
+	// image is always primary.
+	Primary bool `form:"primary"`
+}
+
+// UpdateProductImageRequest changes the fields that are sent. An image cannot
+// be made not primary; make another one primary instead.
+type UpdateProductImageRequest struct {
+	AltText *string `json:"altText" validate:"omitempty,max=255"`
+	Primary bool    `json:"primary"`
+}
+
+// ReorderProductImagesRequest lists every image of a product in its new
+// order.
+type ReorderProductImagesRequest struct {
+	ImageUUIDs []string `json:"imageUuids" binding:"required,min=1" validate:"required,min=1,dive,uuid"`
+}
+
+// ImageUpload is a signed direct upload of an image to storage. The client
+// posts Fields and the image as "file" to URL, then confirms the upload
+// before ExpiresAt to add the image to the product's gallery.
+type ImageUpload struct {
+	UUID      string            `json:"uuid"`
+	PublicID  string            `json:"-"`
+	URL       string            `json:"url"`
+	Fields    map[string]string `json:"fields"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+// ConfirmImageUploadRequest adds a directly uploaded image to the gallery.
+type ConfirmImageUploadRequest struct {
+	AltText string `json:"altText" validate:"max=255"`
+	// Primary makes the image the product's main one. A product's first
+	// image is always primary.
+	Primary bool `json:"primary"`
+}
@@ -0,0 +1,41 @@
+package product
+
+import "time"
+
+// ProductOption is an option type of a product, such as Color or Size, with
+// the values its variants choose from in display order.
+type ProductOption struct {
+	ID        int                  `json:"id"`
+	UUID      string               `json:"uuid"`
+	Name      string               `json:"name"`
+	Position  int                  `json:"position"`
+	Values    []ProductOptionValue `json:"values"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+type ProductOptionValue struct {
+	ID       int    `json:"id"`
+	UUID     string `json:"uuid"`
+	Value    string `json:"value"`
+	Position int    `json:"position"`
+}
+
+// ProductOptionsRequest sets every option of a product in order. Options and
+// values are matched to existing ones by name, ignoring case; those left out
+// are removed, which is refused while a variant uses them.
+type ProductOptionsRequest struct {
+	Options []ProductOptionRequest `json:"options" binding:"required" validate:"max=5,dive"`
+}
+
+type ProductOptionRequest struct {
+	Name   string   `json:"name" validate:"required,max=50"`
+	Values []string `json:"values" validate:"required,min=1,max=50,dive,required,max=50"`
+}
+
+// VariantMatrixRequest creates a variant for every combination of option
+// values that has none yet.
+type VariantMatrixRequest struct {
+	// SKUPrefix starts the SKU of each new variant, followed by its values
+	SKUPrefix string `json:"skuPrefix" binding:"required,max=32" validate:"required,max=32"`
+}
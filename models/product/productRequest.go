@@ -2,6 +2,7 @@ package product
 
 import (
 	"mime/multipart"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -10,6 +11,15 @@ type ProductRequest struct {
 	Name      string                `form:"name" binding:"required,min=3,max=100" validate:"required,min=3,max=100"`
 	ImageFile *multipart.FileHeader `form:"file"`
 	ImageURL  string                `form:"imageUrl"` // Add this field
+	// Image is set with ImageURL once ImageFile has been uploaded
+	Image *ProductImage `form:"-"`
+	// Status defaults to draft for a new product and is kept on update
+	Status string `form:"status" validate:"omitempty,oneof=draft published archived"`
+	// PublishAt and UnpublishAt, in RFC 3339, schedule the product to be
+	// published and archived. Left out they are kept; sent empty they are
+	// cleared.
+	PublishAt   *time.Time `form:"publishAt"`
+	UnpublishAt *time.Time `form:"unpublishAt"`
 }
 
 var Validate = validator.New()
@@ -16,4 +16,24 @@ type ProductResponse struct {
 	Variants        []variant.VariantResponse
 	CreatedAt       time.Time `json:"createdAt"`
 	UpdatedAt       time.Time `json:"updatedAt"`
+
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+
+	Images  []ProductImage  `json:"images"`
+	Options []ProductOption `json:"options"`
+}
+
+// Product statuses. Only published products are shown to the public.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// VisibleTo reports whether adminID, or the public when it is zero, may see
+// the product.
+func (p ProductResponse) VisibleTo(adminID int) bool {
+	return p.Status == StatusPublished || (adminID != 0 && p.AdminID == adminID)
 }
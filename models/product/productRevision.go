@@ -0,0 +1,54 @@
+package product
+
+import "time"
+
+// ProductSnapshot is what a revision keeps of a product and its variants.
+// Stock is left out: it follows physical goods and is never rolled back.
+type ProductSnapshot struct {
+	Name     string            `json:"name"`
+	ImageURL string            `json:"imageUrl"`
+	Variants []VariantSnapshot `json:"variants"`
+}
+
+type VariantSnapshot struct {
+	UUID         string  `json:"uuid"`
+	VariantName  string  `json:"variantName"`
+	SKU          string  `json:"sku"`
+	Barcode      *string `json:"barcode"`
+	ReorderPoint *int    `json:"reorderPoint"`
+	// Options maps option names to the variant's values; omitted when it
+	// has none
+	Options map[string]string `json:"options,omitempty"`
+}
+
+type ProductRevision struct {
+	Revision int `json:"revision"`
+	// AdminID is who made the change, if known
+	AdminID *int `json:"adminId"`
+	// RestoredFrom is set when the revision was made by restoring an older one
+	RestoredFrom *int            `json:"restoredFrom"`
+	Snapshot     ProductSnapshot `json:"snapshot"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// FieldChange is one field that differs between two revisions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type VariantChange struct {
+	UUID    string        `json:"uuid"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// RevisionDiff is what changed from revision From to revision To.
+type RevisionDiff struct {
+	From            int               `json:"from"`
+	To              int               `json:"to"`
+	Changes         []FieldChange     `json:"changes"`
+	AddedVariants   []VariantSnapshot `json:"addedVariants"`
+	RemovedVariants []VariantSnapshot `json:"removedVariants"`
+	ChangedVariants []VariantChange   `json:"changedVariants"`
+}
@@ -0,0 +1,14 @@
+package reservation
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type ReservationRequest struct {
+	Quantity int `json:"quantity" binding:"required" validate:"required,gt=0"`
+	// TTLSeconds overrides the default hold time
+	TTLSeconds int    `json:"ttlSeconds" validate:"omitempty,gte=30,lte=86400"`
+	Reference  string `json:"reference" validate:"max=255"`
+}
+
+var Validate = validator.New()
@@ -0,0 +1,15 @@
+package reservation
+
+import "time"
+
+type ReservationResponse struct {
+	ID        int       `json:"id"`
+	UUID      string    `json:"uuid"`
+	VariantID int       `json:"variantId"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	Reference *string   `json:"reference"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,19 @@
+package variant
+
+import "time"
+
+// LowStockEvent is raised when a variant's quantity falls below its reorder
+// point.
+type LowStockEvent struct {
+	ID           int       `json:"id"`
+	VariantID    int       `json:"variantId"`
+	VariantUUID  string    `json:"variantUuid"`
+	VariantName  string    `json:"variantName"`
+	SKU          string    `json:"sku"`
+	ProductID    int       `json:"productId"`
+	ProductName  string    `json:"productName"`
+	AdminID      int       `json:"adminId"`
+	Quantity     int       `json:"quantity"`
+	ReorderPoint int       `json:"reorderPoint"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
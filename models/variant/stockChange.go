@@ -0,0 +1,19 @@
+package variant
+
+import "time"
+
+// StockChange records a change to a variant's on-hand quantity. It is the
+// payload of the stock_changes notification and of the variant stream.
+type StockChange struct {
+	ID               int64     `json:"id"`
+	VariantID        int       `json:"variantId"`
+	VariantUUID      string    `json:"variantUuid"`
+	ProductUUID      string    `json:"productUuid"`
+	PreviousQuantity int       `json:"previousQuantity"`
+	Quantity         int       `json:"quantity"`
+	CreatedAt        time.Time `json:"createdAt"`
+	// ProductStatus and AdminID decide who may see the change; streams do
+	// not send them
+	ProductStatus string `json:"-"`
+	AdminID       int    `json:"-"`
+}
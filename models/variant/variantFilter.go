@@ -0,0 +1,17 @@
+package variant
+
+// VariantFilter holds the optional filters for listing variants.
+type VariantFilter struct {
+	VariantName string
+	// Warehouse is a warehouse code; only variants stocked there are listed
+	Warehouse string
+	// InStock, when set, keeps variants with (true) or without (false) stock,
+	// counted in Warehouse if given and across all warehouses otherwise
+	InStock *bool
+	// Options keeps variants whose value for each named option is one of
+	// the listed values, ignoring case
+	Options map[string][]string
+	// ViewerID is the admin asking, if any. Admins see the variants of all
+	// of their own products; everything else is limited to published ones.
+	ViewerID int
+}
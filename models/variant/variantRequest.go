@@ -1,13 +1,31 @@
 package variant
 
 import (
+	"basic-trade-api/helpers"
+
 	"github.com/go-playground/validator/v10"
 )
 
 type VariantRequest struct {
-    VariantName string `json:"variantName" binding:"required,min=3,max=100" validate:"required,min=3,max=100"`
-    Quantity    int    `json:"quantity" binding:"required" validate:"required,gte=0"`
-    ProductID   int    `json:"productId" binding:"required" validate:"required"`
+	VariantName string `json:"variantName" binding:"required,min=3,max=100" validate:"required,min=3,max=100"`
+	Quantity    int    `json:"quantity" binding:"required" validate:"required,gte=0"`
+	ProductID   int    `json:"productId" binding:"required" validate:"required"`
+	SKU         string `json:"sku" binding:"required,max=64" validate:"required,max=64"`
+	Barcode     string `json:"barcode" validate:"omitempty,barcode"`
+	// WarehouseID is where Quantity is stocked; the default warehouse if omitted
+	WarehouseID int `json:"warehouseId"`
+	// ReorderPoint raises a low-stock alert when quantity drops below it
+	ReorderPoint *int `json:"reorderPoint" validate:"omitempty,gte=0"`
+	// Options gives the variant's value for every option of its product, by
+	// option name. Left out, an update keeps the current values.
+	Options map[string]string `json:"options" validate:"omitempty,dive,keys,required,max=50,endkeys,required,max=50"`
 }
 
-var Validate = validator.New()
\ No newline at end of file
+var Validate = validator.New()
+
+func init() {
+	// barcode accepts GTIN-8, UPC-A, EAN-13 and GTIN-14 with a valid check digit
+	Validate.RegisterValidation("barcode", func(fl validator.FieldLevel) bool {
+		return helpers.ValidGTIN(fl.Field().String())
+	})
+}
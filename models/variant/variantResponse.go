@@ -3,11 +3,18 @@ package variant
 import "time"
 
 type VariantResponse struct {
-	ID          int       `json:"id"`
-	UUID        string    `json:"uuid"`
-	VariantName string    `json:"variantName"`
-	Quantity    int       `json:"quantity"`
-	ProductID   int       `json:"productId"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           int     `json:"id"`
+	UUID         string  `json:"uuid"`
+	VariantName  string  `json:"variantName"`
+	SKU          string  `json:"sku"`
+	Barcode      *string `json:"barcode"`
+	Quantity     int     `json:"quantity"`
+	Reserved     int     `json:"reserved"`
+	Available    int     `json:"available"` // quantity minus active reservations
+	ReorderPoint *int    `json:"reorderPoint"`
+	ProductID    int     `json:"productId"`
+	// Options maps each option name of the product to the variant's value
+	Options   map[string]string `json:"options"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
 }
@@ -0,0 +1,23 @@
+package warehouse
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+type WarehouseRequest struct {
+	Name string `json:"name" binding:"required,min=3,max=100" validate:"required,min=3,max=100"`
+	Code string `json:"code" binding:"required,max=32" validate:"required,max=32"`
+}
+
+type StockRequest struct {
+	WarehouseID int `json:"warehouseId" binding:"required" validate:"required"`
+	Quantity    int `json:"quantity" binding:"gte=0" validate:"gte=0"`
+}
+
+type TransferRequest struct {
+	FromWarehouseID int `json:"fromWarehouseId" binding:"required" validate:"required"`
+	ToWarehouseID   int `json:"toWarehouseId" binding:"required" validate:"required,nefield=FromWarehouseID"`
+	Quantity        int `json:"quantity" binding:"required" validate:"required,gt=0"`
+}
+
+var Validate = validator.New()
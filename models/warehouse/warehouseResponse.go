@@ -0,0 +1,33 @@
+package warehouse
+
+import "time"
+
+type WarehouseResponse struct {
+	ID        int       `json:"id"`
+	UUID      string    `json:"uuid"`
+	Name      string    `json:"name"`
+	Code      string    `json:"code"`
+	IsDefault bool      `json:"isDefault"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type StockResponse struct {
+	WarehouseID   int       `json:"warehouseId"`
+	WarehouseUUID string    `json:"warehouseUuid"`
+	WarehouseCode string    `json:"warehouseCode"`
+	WarehouseName string    `json:"warehouseName"`
+	Quantity      int       `json:"quantity"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+type TransferResponse struct {
+	ID              int       `json:"id"`
+	UUID            string    `json:"uuid"`
+	VariantID       int       `json:"variantId"`
+	FromWarehouseID int       `json:"fromWarehouseId"`
+	ToWarehouseID   int       `json:"toWarehouseId"`
+	Quantity        int       `json:"quantity"`
+	AdminID         int       `json:"adminId"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
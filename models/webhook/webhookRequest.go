@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+	EventVariantCreated = "variant.created"
+	EventVariantUpdated = "variant.updated"
+	EventVariantDeleted = "variant.deleted"
+	EventStockChanged   = "stock.changed"
+)
+
+type WebhookRequest struct {
+	URL      string   `json:"url" binding:"required" validate:"required,url,max=2048"`
+	Events   []string `json:"events" binding:"required" validate:"required,min=1,dive,oneof=product.created product.updated product.deleted variant.created variant.updated variant.deleted stock.changed"`
+	IsActive *bool    `json:"isActive"`
+}
+
+var Validate = validator.New()
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WebhookResponse struct {
+	ID        int       `json:"id"`
+	UUID      string    `json:"uuid"`
+	AdminID   int       `json:"adminId"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // only returned on create
+	Events    []string  `json:"events"`
+	IsActive  bool      `json:"isActive"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DeliveryResponse struct {
+	ID             int             `json:"id"`
+	UUID           string          `json:"uuid"`
+	SubscriptionID int             `json:"subscriptionId"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time      `json:"lastAttemptAt"`
+	LastStatusCode *int            `json:"lastStatusCode"`
+	LastError      *string         `json:"lastError"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Delivery is a pending delivery joined with its subscription, as handed to
+// the dispatcher.
+type Delivery struct {
+	ID       int
+	UUID     string
+	Event    string
+	Payload  []byte
+	Attempts int
+	URL      string
+	Secret   string
+}
@@ -1,44 +1,205 @@
 package router
 
 import (
+	"basic-trade-api/configs"
 	"basic-trade-api/controllers"
+	"basic-trade-api/helpers"
 	"basic-trade-api/middleware"
+	"basic-trade-api/models/apikey"
+	"basic-trade-api/services"
 	"database/sql"
 
 	"github.com/gin-gonic/gin"
 )
 
-func StartApp(db *sql.DB) *gin.Engine {
+func StartApp(db *sql.DB, stockHub *services.StockHub, notificationHub *services.NotificationHub, mailer helpers.Mailer) *gin.Engine {
 	router := gin.Default()
+	router.Use(middleware.RequestID())
+
+	// Single sign-on is only offered once an identity provider is configured
+	var oidcProvider *helpers.OIDCProvider
+	if configs.EnvOIDCIssuerURL() != "" && configs.EnvOIDCClientID() != "" {
+		oidcProvider = &helpers.OIDCProvider{
+			Issuer:       configs.EnvOIDCIssuerURL(),
+			ClientID:     configs.EnvOIDCClientID(),
+			ClientSecret: configs.EnvOIDCClientSecret(),
+			RedirectURL:  configs.EnvOIDCRedirectURL(),
+			Scopes:       configs.EnvOIDCScopes(),
+		}
+	}
+
 	router.Use(func(ctx *gin.Context) {
 		ctx.Set("db", db)
+		ctx.Set("stockHub", stockHub)
+		ctx.Set("notificationHub", notificationHub)
+		ctx.Set("mailer", mailer)
+		ctx.Set("oidcProvider", oidcProvider)
 		ctx.Next()
 	})
 
+	loginLimits := helpers.NewMemoryRateLimitStore()
+	ipBurst, ipPeriod := configs.EnvLoginRateLimitIP()
+	loginIPLimit := helpers.RateLimit{Burst: ipBurst, Period: ipPeriod}
+	emailBurst, emailPeriod := configs.EnvLoginRateLimitEmail()
+	loginEmailLimit := helpers.RateLimit{Burst: emailBurst, Period: emailPeriod}
+
+	router.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
 	adminRouter := router.Group("/auth")
 	{
 		adminRouter.POST("/register", middleware.RegisterValidator(), controllers.AdminRegister)
-		adminRouter.POST("/login", middleware.LoginValidator(), controllers.AdminLogin)
+		adminRouter.POST("/login",
+			middleware.RateLimit(loginLimits, "login-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.LoginValidator(),
+			middleware.RateLimit(loginLimits, "login-email", loginEmailLimit, middleware.LoginEmailKey),
+			controllers.AdminLogin)
+		adminRouter.POST("/login/2fa",
+			middleware.RateLimit(loginLimits, "login-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.TwoFactorLoginValidator(),
+			controllers.CompleteTwoFactorLogin)
+		// Mail-sending endpoints share the login limits so they cannot be used
+		// to flood a mailbox
+		adminRouter.POST("/forgot-password",
+			middleware.RateLimit(loginLimits, "mail-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.AdminEmailValidator(),
+			middleware.RateLimit(loginLimits, "mail-email", loginEmailLimit, middleware.AdminEmailKey),
+			controllers.ForgotPassword)
+		adminRouter.POST("/reset-password",
+			middleware.RateLimit(loginLimits, "reset-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.ResetPasswordValidator(),
+			controllers.ResetPassword)
+		adminRouter.POST("/verify-email",
+			middleware.RateLimit(loginLimits, "verify-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.VerifyEmailValidator(),
+			controllers.VerifyEmail)
+		adminRouter.POST("/resend-verification",
+			middleware.RateLimit(loginLimits, "mail-ip", loginIPLimit, middleware.ClientIPKey),
+			middleware.AdminEmailValidator(),
+			middleware.RateLimit(loginLimits, "mail-email", loginEmailLimit, middleware.AdminEmailKey),
+			controllers.ResendVerification)
+	}
+
+	oidcRouter := router.Group("/auth/oidc")
+	{
+		oidcRouter.GET("/login", middleware.RateLimit(loginLimits, "login-ip", loginIPLimit, middleware.ClientIPKey), controllers.OIDCLogin)
+		oidcRouter.GET("/callback", controllers.OIDCCallback)
+	}
+
+	twoFactorRouter := router.Group("/auth/2fa")
+	{
+		twoFactorRouter.Use(middleware.Authentication(), middleware.RequireSession())
+		twoFactorRouter.GET("/", controllers.GetTwoFactorStatus)
+		twoFactorRouter.POST("/enroll", controllers.EnrollTwoFactor)
+		twoFactorRouter.POST("/verify", middleware.TwoFactorCodeValidator(), controllers.ConfirmTwoFactor)
+		twoFactorRouter.POST("/recovery-codes", middleware.TwoFactorCodeValidator(), controllers.RegenerateRecoveryCodes)
+		twoFactorRouter.POST("/disable", middleware.DisableTwoFactorValidator(), controllers.DisableTwoFactor)
+	}
+
+	adminAccountRouter := router.Group("/admins")
+	{
+		adminAccountRouter.Use(middleware.Authentication(), middleware.RequireSession())
+		adminAccountRouter.GET("/me", controllers.GetMyAdmin)
+		adminAccountRouter.PATCH("/me", middleware.UpdateAdminValidator(), controllers.UpdateMyAdmin)
+		adminAccountRouter.PUT("/me/password", middleware.ChangePasswordValidator(), controllers.ChangeMyPassword)
+		adminAccountRouter.GET("/", middleware.RequireSuperadmin(), controllers.GetAllAdmin)
+		adminAccountRouter.POST("/:adminUUID/deactivate", middleware.RequireSuperadmin(), controllers.DeactivateAdmin)
+		adminAccountRouter.POST("/:adminUUID/reactivate", middleware.RequireSuperadmin(), controllers.ReactivateAdmin)
 	}
 
 	productRouter := router.Group("/products")
 	{
-		productRouter.GET("/", controllers.GetAllProduct)
-		productRouter.GET("/:productUUID", controllers.GetProductByID)
+		productRouter.GET("/", middleware.OptionalAuthentication(), controllers.GetAllProduct)
+		productRouter.GET("/:productUUID", middleware.OptionalAuthentication(), controllers.GetProductByID)
 		// productRouter.Use(middleware.Authentication())
-		productRouter.POST("/", middleware.Authentication(), middleware.ProductValidator(), controllers.CreateProduct)
-		productRouter.PUT("/:productUUID", middleware.Authentication(), middleware.ProductAuthorization(), middleware.ProductValidator(), controllers.UpdateProduct)
-		productRouter.DELETE("/:productUUID", middleware.Authentication(), middleware.ProductAuthorization(), controllers.DeleteProduct)
+		productRouter.POST("/", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductValidator(), controllers.CreateProduct)
+		productRouter.PUT("/:productUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductValidator(), controllers.UpdateProduct)
+		productRouter.DELETE("/:productUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.DeleteProduct)
+		productRouter.GET("/:productUUID/revisions", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.GetProductRevisions)
+		productRouter.GET("/:productUUID/revisions/diff", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.DiffProductRevisions)
+		productRouter.GET("/:productUUID/revisions/:rev", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.GetProductRevision)
+		productRouter.POST("/:productUUID/revisions/:rev/restore", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.RestoreProductRevision)
+		productRouter.POST("/:productUUID/images", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductImageValidator(), controllers.AddProductImage)
+		productRouter.POST("/:productUUID/images/uploads", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.CreateProductImageUpload)
+		productRouter.POST("/:productUUID/images/uploads/:uploadUUID/confirm", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductImageConfirmValidator(), controllers.ConfirmProductImageUpload)
+		productRouter.PUT("/:productUUID/images/order", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductImageOrderValidator(), controllers.ReorderProductImages)
+		productRouter.PATCH("/:productUUID/images/:imageUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductImageUpdateValidator(), controllers.UpdateProductImage)
+		productRouter.DELETE("/:productUUID/images/:imageUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), controllers.DeleteProductImage)
+		productRouter.PUT("/:productUUID/options", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.ProductOptionsValidator(), controllers.SetProductOptions)
+		productRouter.POST("/:productUUID/variants/matrix", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.ProductAuthorization(), middleware.VariantMatrixValidator(), controllers.GenerateVariantMatrix)
 	}
 
 	variantRouter := router.Group("/products/variants")
 	{
-		variantRouter.GET("/", controllers.GetAllVariant)
-		variantRouter.GET("/:variantUUID", controllers.GetVariantByID)
+		variantRouter.GET("/", middleware.OptionalAuthentication(), controllers.GetAllVariant)
+		variantRouter.GET("/lookup", middleware.OptionalAuthentication(), controllers.LookupVariant)
+		variantRouter.GET("/low-stock", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockRead), controllers.GetLowStockVariants)
+		variantRouter.GET("/:variantUUID", middleware.OptionalAuthentication(), controllers.GetVariantByID)
+		variantRouter.GET("/:variantUUID/barcode", middleware.OptionalAuthentication(), controllers.GetVariantBarcode)
+		variantRouter.GET("/:variantUUID/stock", middleware.OptionalAuthentication(), controllers.GetVariantStock)
 		// variantRouter.Use(middleware.Authentication())
-		variantRouter.POST("/", middleware.Authentication(), middleware.VariantValidator(), controllers.CreateVariant)
-		variantRouter.PUT("/:variantUUID", middleware.Authentication(), middleware.VariantAuthorization(), middleware.VariantValidator(), controllers.UpdateVariant)
-		variantRouter.DELETE("/:variantUUID", middleware.Authentication(), middleware.VariantAuthorization(), controllers.DeleteVariant)
+		variantRouter.POST("/", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.VariantValidator(), controllers.CreateVariant)
+		variantRouter.PUT("/:variantUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.VariantAuthorization(), middleware.VariantValidator(), controllers.UpdateVariant)
+		variantRouter.DELETE("/:variantUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeCatalogWrite), middleware.VariantAuthorization(), controllers.DeleteVariant)
+		variantRouter.PUT("/:variantUUID/stock", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockWrite), middleware.VariantAuthorization(), middleware.StockValidator(), controllers.SetVariantStock)
+		variantRouter.POST("/:variantUUID/transfers", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockWrite), middleware.VariantAuthorization(), middleware.TransferValidator(), controllers.TransferStock)
+		variantRouter.POST("/:variantUUID/reservations", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockWrite), middleware.ReservationValidator(), controllers.CreateReservation)
+	}
+
+	warehouseRouter := router.Group("/warehouses")
+	{
+		warehouseRouter.GET("/", controllers.GetAllWarehouse)
+		warehouseRouter.GET("/:warehouseUUID", controllers.GetWarehouseByID)
+		warehouseRouter.POST("/", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockWrite), middleware.WarehouseValidator(), controllers.CreateWarehouse)
+		warehouseRouter.PUT("/:warehouseUUID", middleware.Authentication(), middleware.RequireScope(apikey.ScopeStockWrite), middleware.WarehouseValidator(), controllers.UpdateWarehouse)
+	}
+
+	reservationRouter := router.Group("/reservations")
+	{
+		reservationRouter.Use(middleware.Authentication())
+		reservationRouter.GET("/:reservationUUID", middleware.RequireScope(apikey.ScopeStockRead), controllers.GetReservationByID)
+		reservationRouter.POST("/:reservationUUID/confirm", middleware.RequireScope(apikey.ScopeStockWrite), controllers.ConfirmReservation)
+		reservationRouter.POST("/:reservationUUID/release", middleware.RequireScope(apikey.ScopeStockWrite), controllers.ReleaseReservation)
+	}
+
+	streamRouter := router.Group("/stream")
+	{
+		streamRouter.GET("/variants", middleware.OptionalAuthentication(), controllers.StreamVariants)
+	}
+
+	router.GET("/ws", middleware.WebSocketToken(), middleware.Authentication(), middleware.RequireScope(apikey.ScopeNotificationsRead), controllers.AdminWebSocket)
+
+	webhookRouter := router.Group("/webhooks")
+	{
+		webhookRouter.Use(middleware.Authentication(), middleware.RequireScope(apikey.ScopeWebhooksManage))
+		webhookRouter.GET("/", controllers.GetAllWebhook)
+		webhookRouter.GET("/:webhookUUID", controllers.GetWebhookByID)
+		webhookRouter.POST("/", middleware.WebhookValidator(), controllers.CreateWebhook)
+		webhookRouter.PUT("/:webhookUUID", middleware.WebhookValidator(), controllers.UpdateWebhook)
+		webhookRouter.DELETE("/:webhookUUID", controllers.DeleteWebhook)
+		webhookRouter.GET("/:webhookUUID/deliveries", controllers.GetWebhookDeliveries)
+		webhookRouter.POST("/:webhookUUID/deliveries/:deliveryUUID/redeliver", controllers.RedeliverWebhook)
+	}
+
+	apiKeyRouter := router.Group("/api-keys")
+	{
+		apiKeyRouter.Use(middleware.Authentication(), middleware.RequireSession())
+		apiKeyRouter.GET("/", controllers.GetAllAPIKey)
+		apiKeyRouter.GET("/:apiKeyUUID", controllers.GetAPIKeyByID)
+		apiKeyRouter.POST("/", middleware.APIKeyValidator(), controllers.CreateAPIKey)
+		apiKeyRouter.DELETE("/:apiKeyUUID", controllers.RevokeAPIKey)
+	}
+
+	// The audit log spans every admin's changes, so only superadmins see it
+	auditRouter := router.Group("/audit")
+	{
+		auditRouter.Use(middleware.Authentication(), middleware.RequireSession(), middleware.RequireSuperadmin())
+		auditRouter.GET("/", controllers.GetAllAudit)
+		auditRouter.GET("/:entity/:entityUUID", controllers.GetEntityAudit)
+	}
+
+	exportRouter := router.Group("/exports")
+	{
+		exportRouter.GET("/products", middleware.Authentication(), middleware.RequireScope(apikey.ScopeExportsRead), controllers.ExportProducts)
 	}
 	return router
 }
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const adminColumns = `id, uuid, name, email, role, email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL, deactivated_at, created_at, updated_at`
+
+func scanAdmin(row rowScanner, adminResponse *admin.AdminResponse) error {
+	return row.Scan(&adminResponse.ID, &adminResponse.UUID, &adminResponse.Name, &adminResponse.Email, &adminResponse.Role, &adminResponse.EmailVerified, &adminResponse.TwoFactorEnabled, &adminResponse.DeactivatedAt, &adminResponse.CreatedAt, &adminResponse.UpdatedAt)
+}
+
+// AdminAuthState is what Authentication checks about an admin on every
+// request, on top of the token itself.
+type AdminAuthState struct {
+	Role   string
+	Active bool
+	// TokenCurrent is false for tokens issued before the admin last changed
+	// their password
+	TokenCurrent bool
+}
+
+// GetAdminAuthStateService looks up an admin for a token issued at
+// issuedAt. The comparison runs in the database, which set
+// tokens_valid_after, so it does not depend on its time zone.
+func GetAdminAuthStateService(ctx context.Context, db *sql.DB, adminID int, issuedAt time.Time) (*AdminAuthState, error) {
+	var state AdminAuthState
+	query := `
+		SELECT role, deactivated_at IS NULL, COALESCE(tokens_valid_after <= to_timestamp($2)::timestamp, true)
+		FROM admins
+		WHERE id = $1
+	`
+	err := db.QueryRowContext(ctx, query, adminID, issuedAt.Unix()).Scan(&state.Role, &state.Active, &state.TokenCurrent)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("admin not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func GetAdminProfileService(ctx context.Context, db *sql.DB, adminID int) (*admin.AdminResponse, error) {
+	var adminResponse admin.AdminResponse
+	err := scanAdmin(db.QueryRowContext(ctx, `SELECT `+adminColumns+` FROM admins WHERE id = $1`, adminID), &adminResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("admin not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &adminResponse, nil
+}
+
+// UpdateAdminProfileService changes an admin's own name and email. A new
+// email needs the current password, and must be verified again before the
+// admin can next log in.
+func UpdateAdminProfileService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, adminID int, updateRequest admin.UpdateAdminRequest) (*admin.AdminResponse, error) {
+	err := admin.Validate.Struct(updateRequest)
+	if err != nil {
+		validationErrors := helpers.GeneralValidator(err)
+		return nil, fmt.Errorf(fmt.Sprintf("Validation errors: %v", validationErrors))
+	}
+
+	var adminResponse admin.AdminResponse
+	var sendVerification func()
+	err = database.WithTxOptions(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *sql.Tx) error {
+		sendVerification = nil
+
+		var hashedPassword string
+		err := tx.QueryRowContext(ctx, `SELECT name, email, password FROM admins WHERE id = $1 FOR UPDATE`, adminID).Scan(&adminResponse.Name, &adminResponse.Email, &hashedPassword)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+
+		name := adminResponse.Name
+		if updateRequest.Name != nil {
+			name = strings.TrimSpace(*updateRequest.Name)
+		}
+		emailChanged := updateRequest.Email != nil && !strings.EqualFold(*updateRequest.Email, adminResponse.Email)
+
+		if emailChanged {
+			if !helpers.ComparePassword([]byte(hashedPassword), []byte(updateRequest.CurrentPassword)) {
+				return errors.New("invalid password")
+			}
+
+			var count int
+			err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins WHERE email = $1 AND id <> $2`, *updateRequest.Email, adminID).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return errors.New("email already exists")
+			}
+
+			query := `UPDATE admins SET email = $1, email_verified_at = NULL WHERE id = $2`
+			if _, err := tx.ExecContext(ctx, query, *updateRequest.Email, adminID); err != nil {
+				return err
+			}
+			sendVerification, err = sendEmailVerification(ctx, tx, mailer, adminID, name, *updateRequest.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `UPDATE admins SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 RETURNING ` + adminColumns
+		return scanAdmin(tx.QueryRowContext(ctx, query, name, adminID), &adminResponse)
+	})
+	if database.IsUniqueViolation(err) {
+		return nil, errors.New("email already exists")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if sendVerification != nil {
+		sendVerification()
+	}
+	return &adminResponse, nil
+}
+
+// ChangePasswordService sets a new password for an admin who knows the
+// current one. A wrong current password counts towards the login lockout.
+// Access tokens issued before the change stop working, so the caller must
+// hand the admin a new one.
+func ChangePasswordService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, adminID int, passwordRequest admin.ChangePasswordRequest) (*admin.AdminResponse, error) {
+	err := admin.Validate.Struct(passwordRequest)
+	if err != nil {
+		validationErrors := helpers.GeneralValidator(err)
+		return nil, fmt.Errorf(fmt.Sprintf("Validation errors: %v", validationErrors))
+	}
+
+	// Hash the password before opening the transaction, bcrypt is slow
+	newHashedPassword, err := helpers.HashPassword(passwordRequest.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var adminResponse admin.AdminResponse
+	var authenticated bool
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		authenticated = false
+
+		var hashedPassword string
+		var locked bool
+		query := `SELECT password, COALESCE(locked_until > NOW(), false) FROM admins WHERE id = $1 FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, adminID).Scan(&hashedPassword, &locked)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+
+		comparePass := helpers.ComparePassword([]byte(hashedPassword), []byte(passwordRequest.CurrentPassword))
+		if locked {
+			return nil
+		}
+		if !comparePass {
+			return recordLoginFailure(ctx, tx, adminID)
+		}
+
+		query = `
+			UPDATE admins SET
+				password = $1,
+				failed_login_attempts = 0,
+				locked_until = NULL,
+				tokens_valid_after = date_trunc('second', NOW()),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+			RETURNING ` + adminColumns
+		if err := scanAdmin(tx.QueryRowContext(ctx, query, newHashedPassword, adminID), &adminResponse); err != nil {
+			return err
+		}
+		authenticated = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !authenticated {
+		return nil, errors.New("invalid password")
+	}
+
+	sendAdminMail(mailer, adminResponse.Email, "password_changed", adminMail{Name: adminResponse.Name})
+	return &adminResponse, nil
+}
+
+// GetAllAdminService lists admins page by page. status is "active",
+// "deactivated" or empty for both.
+func GetAllAdminService(ctx context.Context, db *sql.DB, status string, pageSize, offset int) ([]admin.AdminResponse, int, error) {
+	var filter string
+	switch status {
+	case "":
+	case "active":
+		filter = ` WHERE deactivated_at IS NULL`
+	case "deactivated":
+		filter = ` WHERE deactivated_at IS NOT NULL`
+	default:
+		return nil, 0, errors.New("invalid status")
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins`+filter).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT `+adminColumns+` FROM admins`+filter+` ORDER BY id LIMIT $1 OFFSET $2`, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	admins := []admin.AdminResponse{}
+	for rows.Next() {
+		var adminResponse admin.AdminResponse
+		if err := scanAdmin(rows, &adminResponse); err != nil {
+			return nil, 0, err
+		}
+		admins = append(admins, adminResponse)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return admins, total, nil
+}
+
+// SetAdminActiveService deactivates or reactivates another admin. A
+// deactivated admin cannot log in, and their tokens, API keys and pending
+// login challenges and reset links stop working.
+func SetAdminActiveService(ctx context.Context, db *sql.DB, actorID int, adminUUID string, active bool) (*admin.AdminResponse, error) {
+	var adminResponse admin.AdminResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var adminID int
+		err := tx.QueryRowContext(ctx, `SELECT id FROM admins WHERE uuid = $1 FOR UPDATE`, adminUUID).Scan(&adminID)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+		if adminID == actorID {
+			return errors.New("cannot deactivate yourself")
+		}
+
+		if !active {
+			query := `UPDATE admin_tokens SET used_at = NOW() WHERE admin_id = $1 AND used_at IS NULL`
+			if _, err := tx.ExecContext(ctx, query, adminID); err != nil {
+				return err
+			}
+		}
+
+		// Tokens issued before a deactivation stay revoked after a
+		// reactivation
+		query := `
+			UPDATE admins SET
+				deactivated_at = CASE WHEN $2 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END,
+				tokens_valid_after = CASE WHEN $2 THEN tokens_valid_after ELSE date_trunc('second', NOW()) END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING ` + adminColumns
+		return scanAdmin(tx.QueryRowContext(ctx, query, adminID, active), &adminResponse)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adminResponse, nil
+}
@@ -1,61 +1,94 @@
 package services
 
 import (
+	"basic-trade-api/configs"
+	"basic-trade-api/database"
 	"basic-trade-api/helpers"
 	"basic-trade-api/models/admin"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"sync"
 )
 
-func AdminRegisterService(db *sql.DB, adminRequest admin.AdminRegisterRequest) (*admin.AdminResponse, error) {
-	// Check if the email already exists
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM admins WHERE email = $1", adminRequest.Email).Scan(&count)
-	if err != nil {
-		return nil, err
-	}
-	if count > 0 {
-		return nil, errors.New("email already exists")
-	}
-
+// AdminRegisterService creates an admin and mails them a link to verify
+// their email, which they must follow before they can log in.
+func AdminRegisterService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, adminRequest admin.AdminRegisterRequest) (*admin.AdminResponse, error) {
 	// Validate the admin request data
-	err = admin.Validate.Struct(adminRequest)
+	err := admin.Validate.Struct(adminRequest)
 	if err != nil {
 		validationErrors := helpers.GeneralValidator(err)
 		return nil, fmt.Errorf(fmt.Sprintf("Validation errors: %v", validationErrors))
 	}
 
-	// Hash the password before storing it in the database
+	// Hash the password before opening the transaction, bcrypt is slow
 	hashedPassword, err := helpers.HashPassword(adminRequest.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare the SQL query to insert a new admin
-	query := `
-		INSERT INTO admins (name, email, password)
-		VALUES ($1, $2, $3)
-		RETURNING id, uuid, created_at, updated_at
-	`
-
-	// Execute the query and get the new admin's ID, UUID, created_at, and updated_at
+	// The email check and the insert run serializably so two registrations
+	// for the same email cannot both pass the check
 	var newAdmin admin.AdminResponse
-	err = db.QueryRow(query, adminRequest.Name, adminRequest.Email, hashedPassword).Scan(&newAdmin.ID, &newAdmin.UUID, &newAdmin.CreatedAt, &newAdmin.UpdatedAt)
-	if err != nil {
+	var sendVerification func()
+	err = database.WithTxOptions(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *sql.Tx) error {
+		// Check if the email already exists
+		var count int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM admins WHERE email = $1", adminRequest.Email).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("email already exists")
+		}
+
+		// Prepare the SQL query to insert a new admin
+		query := `
+			INSERT INTO admins (name, email, password)
+			VALUES ($1, $2, $3)
+			RETURNING id, uuid, created_at, updated_at
+		`
+
+		// Execute the query and get the new admin's ID, UUID, created_at, and updated_at
+		err = tx.QueryRowContext(ctx, query, adminRequest.Name, adminRequest.Email, hashedPassword).Scan(&newAdmin.ID, &newAdmin.UUID, &newAdmin.CreatedAt, &newAdmin.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		// The first admin of a fresh install manages the others
+		err = tx.QueryRowContext(ctx, `
+			UPDATE admins SET role = CASE WHEN EXISTS (SELECT 1 FROM admins WHERE role = $2) THEN role ELSE $2 END
+			WHERE id = $1
+			RETURNING role
+		`, newAdmin.ID, admin.RoleSuperadmin).Scan(&newAdmin.Role)
+		if err != nil {
+			return err
+		}
+
+		sendVerification, err = sendEmailVerification(ctx, tx, mailer, newAdmin.ID, adminRequest.Name, adminRequest.Email)
+		return err
+	})
+	if database.IsUniqueViolation(err) {
+		return nil, errors.New("email already exists")
+	} else if err != nil {
 		return nil, err
 	}
 
+	sendVerification()
+
 	// Set the remaining fields of the AdminResponse struct
 	newAdmin.Name = adminRequest.Name
 	newAdmin.Email = adminRequest.Email
-	newAdmin.Password = "" // Don't return the password
 
 	// Return the new admin
 	return &newAdmin, nil
 }
 
-func AdminLoginService(db *sql.DB, adminRequest admin.AdminLoginRequest) (*admin.AdminResponse, error) {
+// AdminLoginService checks an admin's password. When the admin has two-factor
+// authentication enabled the caller must still complete a login challenge
+// before treating them as logged in.
+func AdminLoginService(ctx context.Context, db *sql.DB, adminRequest admin.AdminLoginRequest) (*admin.AdminResponse, error) {
 	// Validate the admin request data
 	err := admin.Validate.Struct(adminRequest)
 	if err != nil {
@@ -63,26 +96,98 @@ func AdminLoginService(db *sql.DB, adminRequest admin.AdminLoginRequest) (*admin
 		return nil, fmt.Errorf(fmt.Sprintf("Validation errors: %v", validationErrors))
 	}
 
-	query := `
-		SELECT id, name, email, password 
-		FROM admins 
-		WHERE email = $1
-	`
+	// Every failure is reported the same way so callers cannot tell unknown,
+	// locked and wrong-password accounts apart
+	errInvalid := errors.New("invalid credentials")
 
-	// Execute the query and get the new admin's ID, UUID, created_at, and updated_at
 	var adminResponse admin.AdminResponse
-	err = db.QueryRow(query, adminRequest.Email).Scan(&adminResponse.ID, &adminResponse.Name, &adminResponse.Email, &adminResponse.Password)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
-	} else if err != nil {
+	var authenticated, verified, deactivated bool
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		authenticated = false
+
+		var locked bool
+		var hashedPassword string
+		query := `
+			SELECT id, uuid, name, email, password, role, COALESCE(locked_until > NOW(), false), email_verified_at IS NOT NULL, totp_enabled_at IS NOT NULL, deactivated_at IS NOT NULL
+			FROM admins
+			WHERE email = $1
+			FOR UPDATE
+		`
+		err := tx.QueryRowContext(ctx, query, adminRequest.Email).Scan(&adminResponse.ID, &adminResponse.UUID, &adminResponse.Name, &adminResponse.Email, &hashedPassword, &adminResponse.Role, &locked, &verified, &adminResponse.TwoFactorEnabled, &deactivated)
+		if err == sql.ErrNoRows {
+			// Compare anyway so an unknown email takes as long as a known one
+			helpers.ComparePassword([]byte(dummyPasswordHash()), []byte(adminRequest.Password))
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		comparePass := helpers.ComparePassword([]byte(hashedPassword), []byte(adminRequest.Password))
+		if locked {
+			return nil
+		}
+		if !comparePass {
+			return recordLoginFailure(ctx, tx, adminResponse.ID)
+		}
+
+		// With two-factor on, failures are only cleared once the code is right
+		// too, or knowing the password would allow unlimited code guesses
+		if !adminResponse.TwoFactorEnabled {
+			if err := clearLoginFailures(ctx, tx, adminResponse.ID); err != nil {
+				return err
+			}
+		}
+		authenticated = true
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	// Compare password
-	comparePass := helpers.ComparePassword([]byte(adminResponse.Password), []byte(adminRequest.Password))
-	if !comparePass {
-		return nil, errors.New("invalid password")
+	if !authenticated {
+		return nil, errInvalid
+	}
+	// Like an unverified email, only told to someone who knows the password
+	if deactivated {
+		return nil, errors.New("account deactivated")
+	}
+	if !verified {
+		// Only told to someone who knows the password
+		return nil, errors.New("email not verified")
 	}
 
 	return &adminResponse, nil
 }
+
+// recordLoginFailure counts a wrong password or code against an admin and
+// locks the account once EnvLoginMaxFailures is reached. The counter restarts
+// on lockout, so the next lockout takes another full run of failures.
+func recordLoginFailure(ctx context.Context, tx *sql.Tx, adminID int) error {
+	query := `
+		UPDATE admins SET
+			failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END,
+			locked_until = CASE WHEN failed_login_attempts + 1 >= $2 THEN NOW() + $3 * INTERVAL '1 second' ELSE locked_until END
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, query, adminID, configs.EnvLoginMaxFailures(), int(configs.EnvLoginLockoutDuration().Seconds()))
+	return err
+}
+
+func clearLoginFailures(ctx context.Context, tx *sql.Tx, adminID int) error {
+	query := `UPDATE admins SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1 AND (failed_login_attempts <> 0 OR locked_until IS NOT NULL)`
+	_, err := tx.ExecContext(ctx, query, adminID)
+	return err
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash is a hash at the configured cost that no password is
+// expected to match, compared against when an email is unknown.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = helpers.HashPassword("invalid-credentials-placeholder")
+	})
+	return dummyHash
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Purposes of the single-use tokens mailed to admins
+const (
+	tokenPasswordReset     = "password_reset"
+	tokenEmailVerification = "email_verification"
+	tokenLoginChallenge    = "login_challenge"
+)
+
+// issueAdminToken creates a token for adminID and returns it in the clear;
+// only its hash is stored. Unused tokens issued earlier for the same purpose
+// stop working, so only the latest link is valid.
+func issueAdminToken(ctx context.Context, tx *sql.Tx, adminID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := helpers.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	query := `UPDATE admin_tokens SET used_at = NOW() WHERE admin_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, adminID, purpose); err != nil {
+		return "", err
+	}
+
+	query = `
+		INSERT INTO admin_tokens (admin_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+	`
+	if _, err := tx.ExecContext(ctx, query, adminID, purpose, helpers.HashToken(token), int(ttl.Seconds())); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeAdminToken marks a token used and returns the admin it was issued
+// to. Unknown, used and expired tokens are all reported the same way.
+func consumeAdminToken(ctx context.Context, tx *sql.Tx, token, purpose string) (int, error) {
+	var adminID int
+	query := `
+		UPDATE admin_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING admin_id
+	`
+	err := tx.QueryRowContext(ctx, query, helpers.HashToken(token), purpose).Scan(&adminID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("invalid or expired token")
+	}
+	return adminID, err
+}
+
+// adminMail is the data the account mail templates are filled with.
+type adminMail struct {
+	Name      string
+	Link      string
+	ExpiresIn string
+}
+
+// sendAdminMail renders a template and sends it in the background, so the
+// response time does not tell whether an email belongs to an account.
+func sendAdminMail(mailer helpers.Mailer, to, template string, data adminMail) {
+	subject, body, err := helpers.RenderMail(template, data)
+	if err != nil {
+		log.Printf("mail %s: %v", template, err)
+		return
+	}
+	go func() {
+		if err := mailer.Send([]string{to}, subject, body); err != nil {
+			log.Printf("mail %s to %s: %v", template, to, err)
+		}
+	}()
+}
+
+func accountLink(page, token string) string {
+	return configs.EnvAppBaseURL() + "/" + page + "?token=" + url.QueryEscape(token)
+}
+
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return pluralize(int(d/time.Hour), "hour")
+	case d >= time.Minute && d%time.Minute == 0:
+		return pluralize(int(d/time.Minute), "minute")
+	default:
+		return d.String()
+	}
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
+
+// ForgotPasswordService mails a reset link if email belongs to an admin. It
+// succeeds either way so it cannot be used to find out which emails exist.
+func ForgotPasswordService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, email string) error {
+	ttl := configs.EnvPasswordResetTTL()
+
+	var name, token string
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var adminID int
+		err := tx.QueryRowContext(ctx, `SELECT id, name FROM admins WHERE email = $1 AND deactivated_at IS NULL`, email).Scan(&adminID, &name)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		token, err = issueAdminToken(ctx, tx, adminID, tokenPasswordReset, ttl)
+		return err
+	})
+	if err != nil || token == "" {
+		return err
+	}
+
+	sendAdminMail(mailer, email, "password_reset", adminMail{
+		Name:      name,
+		Link:      accountLink("reset-password", token),
+		ExpiresIn: humanDuration(ttl),
+	})
+	return nil
+}
+
+// ResetPasswordService sets a new password with a reset token. Proving
+// control of the mailbox also verifies the email and lifts any lockout.
+// Access tokens issued before the reset stop working.
+func ResetPasswordService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, token, password string) error {
+	// Hash the password before opening the transaction, bcrypt is slow
+	hashedPassword, err := helpers.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	var name, email string
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		adminID, err := consumeAdminToken(ctx, tx, token, tokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE admins SET
+				password = $1,
+				failed_login_attempts = 0,
+				locked_until = NULL,
+				email_verified_at = COALESCE(email_verified_at, NOW()),
+				tokens_valid_after = date_trunc('second', NOW()),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $2
+			RETURNING name, email
+		`
+		return tx.QueryRowContext(ctx, query, hashedPassword, adminID).Scan(&name, &email)
+	})
+	if err != nil {
+		return err
+	}
+
+	sendAdminMail(mailer, email, "password_changed", adminMail{Name: name})
+	return nil
+}
+
+// sendEmailVerification issues a verification token inside tx and returns a
+// function that mails it, to be called once tx has committed.
+func sendEmailVerification(ctx context.Context, tx *sql.Tx, mailer helpers.Mailer, adminID int, name, email string) (func(), error) {
+	ttl := configs.EnvEmailVerificationTTL()
+	token, err := issueAdminToken(ctx, tx, adminID, tokenEmailVerification, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		sendAdminMail(mailer, email, "email_verification", adminMail{
+			Name:      name,
+			Link:      accountLink("verify-email", token),
+			ExpiresIn: humanDuration(ttl),
+		})
+	}, nil
+}
+
+// ResendVerificationService mails a new verification link if email belongs to
+// an admin who has not verified it yet. Like ForgotPasswordService it
+// succeeds either way.
+func ResendVerificationService(ctx context.Context, db *sql.DB, mailer helpers.Mailer, email string) error {
+	var send func()
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		send = nil
+
+		var adminID int
+		var name string
+		query := `SELECT id, name FROM admins WHERE email = $1 AND email_verified_at IS NULL`
+		err := tx.QueryRowContext(ctx, query, email).Scan(&adminID, &name)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		send, err = sendEmailVerification(ctx, tx, mailer, adminID, name, email)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if send != nil {
+		send()
+	}
+	return nil
+}
+
+func VerifyEmailService(ctx context.Context, db *sql.DB, token string) error {
+	return database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		adminID, err := consumeAdminToken(ctx, tx, token, tokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE admins SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+		_, err = tx.ExecContext(ctx, query, adminID)
+		return err
+	})
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/apikey"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// apiKeyPrefix marks our keys so secret scanners can recognise leaked
+	// ones
+	apiKeyPrefix = "btk_"
+	// apiKeyShownLength is how much of a key is kept in the clear to tell
+	// keys apart in listings
+	apiKeyShownLength = 12
+	// apiKeyTouchInterval limits how often last_used_at is written for a
+	// busy key
+	apiKeyTouchInterval = time.Minute
+)
+
+const apiKeyColumns = `id, uuid, admin_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row rowScanner, apiKeyResponse *apikey.APIKeyResponse) error {
+	return row.Scan(&apiKeyResponse.ID, &apiKeyResponse.UUID, &apiKeyResponse.AdminID, &apiKeyResponse.Name, &apiKeyResponse.Prefix, pq.Array(&apiKeyResponse.Scopes), &apiKeyResponse.ExpiresAt, &apiKeyResponse.LastUsedAt, &apiKeyResponse.RevokedAt, &apiKeyResponse.CreatedAt)
+}
+
+func CreateAPIKeyService(ctx context.Context, db *sql.DB, apiKeyReq apikey.APIKeyRequest, adminId int) (*apikey.APIKeyResponse, error) {
+	if apiKeyReq.ExpiresAt != nil && !apiKeyReq.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	token, err := helpers.RandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + token
+
+	var apiKeyResponse apikey.APIKeyResponse
+	query := `
+		INSERT INTO api_keys (admin_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + apiKeyColumns
+	err = scanAPIKey(db.QueryRowContext(ctx, query, adminId, apiKeyReq.Name, key[:apiKeyShownLength], helpers.HashToken(key), pq.Array(apiKeyReq.Scopes), apiKeyReq.ExpiresAt), &apiKeyResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the hash is stored, so this is the one chance to see the key
+	apiKeyResponse.Key = key
+	return &apiKeyResponse, nil
+}
+
+func GetAllAPIKeyService(ctx context.Context, db *sql.DB, adminId int) ([]apikey.APIKeyResponse, error) {
+	var apiKeys []apikey.APIKeyResponse
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE admin_id = $1 ORDER BY id`
+	rows, err := db.QueryContext(ctx, query, adminId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var apiKeyResponse apikey.APIKeyResponse
+		if err := scanAPIKey(rows, &apiKeyResponse); err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKeyResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func GetAPIKeyByIDService(ctx context.Context, db *sql.DB, apiKeyUUID string, adminId int) (*apikey.APIKeyResponse, error) {
+	var apiKeyResponse apikey.APIKeyResponse
+
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE uuid = $1 AND admin_id = $2`
+	err := scanAPIKey(db.QueryRowContext(ctx, query, apiKeyUUID, adminId), &apiKeyResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("api key not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &apiKeyResponse, nil
+}
+
+// RevokeAPIKeyService stops a key from working. The row is kept so the key
+// still shows up, as revoked, in the admin's list.
+func RevokeAPIKeyService(ctx context.Context, db *sql.DB, apiKeyUUID string, adminId int) (*apikey.APIKeyResponse, error) {
+	var apiKeyResponse apikey.APIKeyResponse
+
+	query := `
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE uuid = $1 AND admin_id = $2
+		RETURNING ` + apiKeyColumns
+	err := scanAPIKey(db.QueryRowContext(ctx, query, apiKeyUUID, adminId), &apiKeyResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("api key not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &apiKeyResponse, nil
+}
+
+// AuthenticateAPIKeyService returns who a key acts for if it is known, not
+// revoked, not expired and its admin is not deactivated, and records that it
+// was used.
+func AuthenticateAPIKeyService(ctx context.Context, db *sql.DB, key string) (*apikey.Principal, error) {
+	var principal apikey.Principal
+	var id int
+	var touch bool
+	query := `
+		SELECT k.id, k.uuid, a.id, a.email, a.role, k.scopes,
+			k.last_used_at IS NULL OR k.last_used_at < NOW() - $2 * INTERVAL '1 second'
+		FROM api_keys k
+		JOIN admins a ON a.id = k.admin_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+			AND a.deactivated_at IS NULL
+	`
+	err := db.QueryRowContext(ctx, query, helpers.HashToken(key), int(apiKeyTouchInterval.Seconds())).Scan(&id, &principal.KeyUUID, &principal.AdminID, &principal.Email, &principal.Role, pq.Array(&principal.Scopes), &touch)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid api key")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if touch {
+		if _, err := db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id); err != nil {
+			return nil, err
+		}
+	}
+	return &principal, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"basic-trade-api/models/audit"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const auditColumns = `l.id, l.uuid, l.action, l.entity, l.entity_id, l.actor_id, a.email, l.api_key_uuid, l.request_id, l.ip_address, l.before, l.after, l.created_at`
+
+func scanAuditEntry(row rowScanner, entry *audit.AuditEntry) error {
+	var before, after []byte
+	err := row.Scan(&entry.ID, &entry.UUID, &entry.Action, &entry.Entity, &entry.EntityID, &entry.ActorID, &entry.ActorEmail, &entry.APIKeyUUID, &entry.RequestID, &entry.IPAddress, &before, &after, &entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if before != nil {
+		entry.Before = json.RawMessage(before)
+	}
+	if after != nil {
+		entry.After = json.RawMessage(after)
+	}
+	return nil
+}
+
+// auditFilter builds the WHERE clause for listing audit entries.
+// Placeholders are numbered from 1.
+func auditFilter(filter audit.AuditFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Entity != "" {
+		args = append(args, filter.Entity)
+		conditions = append(conditions, fmt.Sprintf(`l.entity = $%d`, len(args)))
+	}
+	if filter.EntityID != "" {
+		args = append(args, filter.EntityID)
+		conditions = append(conditions, fmt.Sprintf(`l.entity_id::text = $%d`, len(args)))
+	}
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		conditions = append(conditions, fmt.Sprintf(`a.uuid::text = $%d`, len(args)))
+	}
+	// created_at is in the database's time zone, so the bounds are converted
+	// there
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf(`l.created_at >= $%d::timestamptz::timestamp`, len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf(`l.created_at < $%d::timestamptz::timestamp`, len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conditions, ` AND `), args
+}
+
+// GetAllAuditService lists audit entries matching filter, newest first.
+func GetAllAuditService(ctx context.Context, db *sql.DB, filter audit.AuditFilter, pageSize, offset int) ([]audit.AuditEntry, int, error) {
+	where, args := auditFilter(filter)
+	from := ` FROM audit_log l LEFT JOIN admins a ON a.id = l.actor_id` + where
+
+	var total int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + auditColumns + from
+	query += fmt.Sprintf(` ORDER BY l.id DESC LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	rows, err := db.QueryContext(ctx, query, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []audit.AuditEntry{}
+	for rows.Next() {
+		var entry audit.AuditEntry
+		if err := scanAuditEntry(rows, &entry); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
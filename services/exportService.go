@@ -0,0 +1,70 @@
+package services
+
+import (
+	"basic-trade-api/models/product"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// exportBatchSize is the number of rows fetched from the cursor per round trip.
+const exportBatchSize = 500
+
+// ExportProductService streams every product matching the listing filters,
+// flattened with its variants, to fn. Rows are read through a server-side
+// cursor so the whole catalog is never held in memory.
+func ExportProductService(ctx context.Context, db *sql.DB, filter product.ProductFilter, fn func(product.ProductExportRow) error) error {
+	// Cursors only live inside a transaction
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, args := productFilter(filter)
+	query := `DECLARE product_export NO SCROLL CURSOR FOR
+		SELECT products.id, products.uuid, products.name, products.image_url, products.admin_id, products.created_at, products.updated_at,
+			variants.id, variants.uuid, variants.variant_name, variants.sku, variants.barcode, variants.quantity, variants.created_at, variants.updated_at
+		FROM products
+		LEFT JOIN variants ON variants.product_id = products.id` + where + `
+		ORDER BY products.id, variants.id`
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(`FETCH FORWARD %d FROM product_export`, exportBatchSize))
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+		for rows.Next() {
+			var row product.ProductExportRow
+			err := rows.Scan(
+				&row.ProductID, &row.ProductUUID, &row.ProductName, &row.ImageURL, &row.AdminID, &row.ProductCreatedAt, &row.ProductUpdatedAt,
+				&row.VariantID, &row.VariantUUID, &row.VariantName, &row.SKU, &row.Barcode, &row.Quantity, &row.VariantCreatedAt, &row.VariantUpdatedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if err := fn(row); err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		if fetched < exportBatchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
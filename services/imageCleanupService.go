@@ -0,0 +1,167 @@
+package services
+
+import (
+	"basic-trade-api/helpers"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// imageDeletionMaxAttempts is how many times a stored image is deleted
+	// before it is marked failed and left to the reconciliation command
+	imageDeletionMaxAttempts = 6
+	// imageDeletionBaseBackoff is the wait after the first failure; it
+	// doubles on every further failure
+	imageDeletionBaseBackoff = time.Minute
+	// imageDeletionBatchSize is the most images deleted per cleanup tick
+	imageDeletionBatchSize = 20
+	// imageDeletionLease is how long claimed deletions are held back from
+	// other replicas while storage is called; it outlasts a whole batch
+	imageDeletionLease = 5 * time.Minute
+)
+
+type imageDeletion struct {
+	id       int
+	publicID string
+	attempts int
+	inUse    bool
+}
+
+// ProcessImageDeletionsService claims stored images queued for deletion,
+// hands each to remove and records the outcome. Images that an image row
+// uses again, such as one restored with the same public ID, are dropped from
+// the queue instead. Rows are claimed with SKIP LOCKED and leased for
+// imageDeletionLease by moving their next attempt ahead, so replicas never
+// delete the same image concurrently; the claim commits before storage is
+// called, and a deletion whose outcome was never recorded is tried again once
+// its lease runs out.
+func ProcessImageDeletionsService(ctx context.Context, db *sql.DB, remove func(publicID string) error) (int, error) {
+	deletions, err := claimImageDeletions(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, deletion := range deletions {
+		var removeErr error
+		if !deletion.inUse {
+			removeErr = remove(deletion.publicID)
+		}
+		if err := recordImageDeletionAttempt(ctx, db, deletion, removeErr); err != nil {
+			return 0, err
+		}
+	}
+	return len(deletions), nil
+}
+
+func claimImageDeletions(ctx context.Context, db *sql.DB) ([]imageDeletion, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT d.id, d.public_id, d.attempts,
+			EXISTS (SELECT 1 FROM product_images i WHERE i.public_id = d.public_id)
+		FROM image_deletions d
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, imageDeletionBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	var deletions []imageDeletion
+	var ids []int64
+	for rows.Next() {
+		var deletion imageDeletion
+		if err := rows.Scan(&deletion.id, &deletion.publicID, &deletion.attempts, &deletion.inUse); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deletions = append(deletions, deletion)
+		ids = append(ids, int64(deletion.id))
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	if len(deletions) == 0 {
+		return nil, nil
+	}
+	query = `UPDATE image_deletions SET next_attempt_at = NOW() + $1 * INTERVAL '1 second' WHERE id = ANY($2)`
+	if _, err := tx.ExecContext(ctx, query, int(imageDeletionLease.Seconds()), pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deletions, nil
+}
+
+// recordImageDeletionAttempt drops a deletion that succeeded from the queue,
+// or schedules the next attempt of one that failed and may be retried.
+func recordImageDeletionAttempt(ctx context.Context, db *sql.DB, deletion imageDeletion, removeErr error) error {
+	attempts := deletion.attempts + 1
+
+	var err error
+	if removeErr == nil {
+		_, err = db.ExecContext(ctx, `DELETE FROM image_deletions WHERE id = $1`, deletion.id)
+	} else if attempts >= imageDeletionMaxAttempts {
+		query := `UPDATE image_deletions SET status = 'failed', attempts = $1, last_attempt_at = NOW(), last_error = $2, next_attempt_at = NULL WHERE id = $3`
+		_, err = db.ExecContext(ctx, query, attempts, removeErr.Error(), deletion.id)
+	} else {
+		backoff := imageDeletionBaseBackoff << (attempts - 1)
+		query := `UPDATE image_deletions SET attempts = $1, last_attempt_at = NOW(), last_error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second' WHERE id = $4`
+		_, err = db.ExecContext(ctx, query, attempts, removeErr.Error(), int(backoff.Seconds()), deletion.id)
+	}
+	return err
+}
+
+// ReferencedImageIDsService returns the public ID of every stored image an
+// image row or a pending direct upload uses, each size included. Images
+// still queued for deletion are not referenced.
+func ReferencedImageIDsService(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	query := `
+		SELECT public_id FROM product_images WHERE public_id IS NOT NULL
+		UNION
+		SELECT public_id FROM image_uploads
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var publicID string
+		if err := rows.Scan(&publicID); err != nil {
+			return nil, err
+		}
+		referenced[publicID] = true
+		for _, size := range helpers.ImageSizes {
+			referenced[helpers.ImageSizePublicID(publicID, size.Name)] = true
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return referenced, nil
+}
+
+// ClearImageDeletionsService drops queued deletions of stored images that are
+// already gone, including those that failed for good.
+func ClearImageDeletionsService(ctx context.Context, db *sql.DB, publicIDs []string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM image_deletions WHERE public_id = ANY($1)`, pq.Array(publicIDs))
+	return err
+}
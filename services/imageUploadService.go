@@ -0,0 +1,95 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/product"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateImageUploadService records a signed direct upload to a product of
+// adminId, valid for ttl.
+func CreateImageUploadService(ctx context.Context, db *sql.DB, productUUID string, upload *product.ImageUpload, ttl time.Duration, adminId int) error {
+	query := `
+		INSERT INTO image_uploads (product_id, public_id, expires_at)
+		SELECT id, $3, NOW() + $4 * INTERVAL '1 second' FROM products WHERE uuid = $1 AND admin_id = $2
+		RETURNING uuid, expires_at
+	`
+	err := db.QueryRowContext(ctx, query, productUUID, adminId, upload.PublicID, int(ttl.Seconds())).Scan(&upload.UUID, &upload.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return errors.New("product not found")
+	}
+	return err
+}
+
+// GetImageUploadService returns the public ID an unexpired direct upload to
+// a product of adminId stores its image under.
+func GetImageUploadService(ctx context.Context, db *sql.DB, productUUID, uploadUUID string, adminId int) (string, error) {
+	var publicID string
+	var expired bool
+	query := `
+		SELECT image_uploads.public_id, image_uploads.expires_at <= NOW()
+		FROM image_uploads JOIN products ON products.id = image_uploads.product_id
+		WHERE image_uploads.uuid = $1 AND products.uuid = $2 AND products.admin_id = $3
+	`
+	err := db.QueryRowContext(ctx, query, uploadUUID, productUUID, adminId).Scan(&publicID, &expired)
+	if err == sql.ErrNoRows {
+		return "", errors.New("upload not found")
+	} else if err != nil {
+		return "", err
+	}
+	if expired {
+		return "", errors.New("upload expired")
+	}
+	return publicID, nil
+}
+
+// ConfirmImageUploadService adds a verified direct upload to the end of a
+// product's gallery. An upload can be confirmed once.
+func ConfirmImageUploadService(ctx context.Context, db *sql.DB, productUUID, uploadUUID string, image product.ProductImage, primary bool, adminId int) (*product.ProductImage, error) {
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		productID, err := lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		var publicID string
+		query := `DELETE FROM image_uploads WHERE uuid = $1 AND product_id = $2 AND expires_at > NOW() RETURNING public_id`
+		err = tx.QueryRowContext(ctx, query, uploadUUID, productID).Scan(&publicID)
+		if err == sql.ErrNoRows {
+			return errors.New("upload not found")
+		} else if err != nil {
+			return err
+		}
+		image.PublicID = &publicID
+
+		if err := insertProductImage(ctx, tx, productID, &image, primary); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, productID, adminId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// ExpireImageUploadsService drops direct uploads that were not confirmed in
+// time and queues whatever was uploaded for them for deletion.
+func ExpireImageUploadsService(ctx context.Context, db *sql.DB) (int, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM image_uploads WHERE expires_at <= NOW() RETURNING public_id
+		)
+		INSERT INTO image_deletions (public_id) SELECT public_id FROM expired
+	`
+	result, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	expired, err := result.RowsAffected()
+	return int(expired), err
+}
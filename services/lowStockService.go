@@ -0,0 +1,101 @@
+package services
+
+import (
+	"basic-trade-api/models/variant"
+	"context"
+	"database/sql"
+)
+
+// lowStockBatchSize is the most events handled per ProcessLowStockEventsService call.
+const lowStockBatchSize = 100
+
+// GetLowStockVariantsService lists the admin's variants whose quantity is
+// below their reorder point, lowest stock first.
+func GetLowStockVariantsService(ctx context.Context, db *sql.DB, adminId int) ([]variant.VariantResponse, error) {
+	var variants []variant.VariantResponse
+
+	query := `
+		SELECT ` + variantColumns + `
+		FROM variants
+		JOIN products ON products.id = variants.product_id
+		WHERE products.admin_id = $1
+			AND variants.reorder_point IS NOT NULL
+			AND variants.quantity < variants.reorder_point
+		ORDER BY variants.quantity, variants.id
+	`
+	rows, err := db.QueryContext(ctx, query, adminId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variantResponse variant.VariantResponse
+		if err := scanVariant(rows, &variantResponse); err != nil {
+			return nil, err
+		}
+		variants = append(variants, variantResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// ProcessLowStockEventsService marks pending low-stock events processed and,
+// once that has committed, hands them to fn, so slow or failing sinks never
+// hold the claim open, and an alert is sent at most once. Events are claimed
+// with SKIP LOCKED so several API replicas can run the checker without
+// sending an alert twice.
+func ProcessLowStockEventsService(ctx context.Context, db *sql.DB, fn func(variant.LowStockEvent)) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT e.id, v.id, v.uuid, v.variant_name, v.sku, p.id, p.name, p.admin_id, e.quantity, e.reorder_point, e.created_at
+		FROM low_stock_events e
+		JOIN variants v ON v.id = e.variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE e.processed_at IS NULL
+		ORDER BY e.id
+		LIMIT $1
+		FOR UPDATE OF e SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, lowStockBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var events []variant.LowStockEvent
+	for rows.Next() {
+		var event variant.LowStockEvent
+		err := rows.Scan(&event.ID, &event.VariantID, &event.VariantUUID, &event.VariantName, &event.SKU, &event.ProductID, &event.ProductName, &event.AdminID, &event.Quantity, &event.ReorderPoint, &event.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	for _, event := range events {
+		if _, err := tx.ExecContext(ctx, `UPDATE low_stock_events SET processed_at = CURRENT_TIMESTAMP WHERE id = $1`, event.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		fn(event)
+	}
+	return len(events), nil
+}
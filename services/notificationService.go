@@ -0,0 +1,171 @@
+package services
+
+import (
+	"basic-trade-api/models/notification"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AdminNotificationsChannel is the NOTIFY channel admin notifications are
+	// sent on
+	AdminNotificationsChannel = "admin_notifications"
+	// notificationSubscriberBuffer is how many notifications a connection may
+	// fall behind before it is dropped
+	notificationSubscriberBuffer = 32
+)
+
+// notifyAdmin sends a notification to every replica through NOTIFY. Inside a
+// transaction it is only delivered if the transaction commits. Keep data
+// small: NOTIFY payloads are limited to 8000 bytes.
+func notifyAdmin(ctx context.Context, q execer, adminID int, actorID *int, kind string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(notification.Notification{
+		Type:      kind,
+		AdminID:   adminID,
+		ActorID:   actorID,
+		Data:      raw,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `SELECT pg_notify($1, $2)`, AdminNotificationsChannel, string(payload))
+	return err
+}
+
+func NotifyAdminService(ctx context.Context, db *sql.DB, adminID int, kind string, data interface{}) error {
+	return notifyAdmin(ctx, db, adminID, nil, kind, data)
+}
+
+// notifyProductOwner tells the owner of productID about a change another
+// admin made to it. Changes made by the owner are not notified.
+func notifyProductOwner(ctx context.Context, tx *sql.Tx, productID, actorID int, kind string, data interface{}) error {
+	var ownerID int
+	err := tx.QueryRowContext(ctx, `SELECT admin_id FROM products WHERE id = $1`, productID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ownerID == actorID {
+		return nil
+	}
+	return notifyAdmin(ctx, tx, ownerID, &actorID, kind, data)
+}
+
+// NotificationHub fans admin notifications out to the dashboard connections
+// open on this replica.
+type NotificationHub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[*NotificationSubscription]struct{}
+}
+
+// NotificationSubscription receives one admin's notifications for the topics
+// it follows, or all of them if it follows none. C is closed when the
+// connection falls too far behind.
+type NotificationSubscription struct {
+	C       chan notification.Notification
+	adminID int
+
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+func NewNotificationHub() *NotificationHub {
+	return &NotificationHub{subscribers: make(map[int]map[*NotificationSubscription]struct{})}
+}
+
+func (h *NotificationHub) Subscribe(adminID int) *NotificationSubscription {
+	sub := &NotificationSubscription{
+		C:       make(chan notification.Notification, notificationSubscriberBuffer),
+		adminID: adminID,
+		topics:  make(map[string]bool),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[adminID] == nil {
+		h.subscribers[adminID] = make(map[*NotificationSubscription]struct{})
+	}
+	h.subscribers[adminID][sub] = struct{}{}
+	return sub
+}
+
+func (h *NotificationHub) Unsubscribe(sub *NotificationSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+func (h *NotificationHub) remove(sub *NotificationSubscription) {
+	subs := h.subscribers[sub.adminID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscribers, sub.adminID)
+	}
+	close(sub.C)
+}
+
+// Publish hands n to the recipient's matching subscriptions without blocking.
+func (h *NotificationHub) Publish(n notification.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers[n.AdminID] {
+		if !sub.Matches(n.Type) {
+			continue
+		}
+		select {
+		case sub.C <- n:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+func (s *NotificationSubscription) Follow(topics []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, topic := range topics {
+		s.topics[topic] = true
+	}
+}
+
+func (s *NotificationSubscription) Unfollow(topics []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, topic := range topics {
+		delete(s.topics, topic)
+	}
+}
+
+// Topics returns the topics followed, empty meaning all.
+func (s *NotificationSubscription) Topics() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func (s *NotificationSubscription) Matches(kind string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.topics) == 0 {
+		return true
+	}
+	return s.topics[kind] || s.topics[strings.SplitN(kind, ".", 2)[0]]
+}
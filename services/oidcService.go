@@ -0,0 +1,176 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// oidcStateTTL is how long a user has to finish logging in at the identity
+// provider
+const oidcStateTTL = 10 * time.Minute
+
+// CreateOIDCStateService remembers the nonce and PKCE verifier of a login
+// that is about to leave for the identity provider, keyed by a new state,
+// which it returns.
+func CreateOIDCStateService(ctx context.Context, db *sql.DB, nonce, verifier, returnTo string) (string, error) {
+	state, err := helpers.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	// Abandoned logins are cleared out as new ones start
+	if _, err := db.ExecContext(ctx, `DELETE FROM oidc_states WHERE expires_at <= NOW()`); err != nil {
+		return "", err
+	}
+
+	query := `
+		INSERT INTO oidc_states (state_hash, nonce, code_verifier, return_to, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+	`
+	_, err = db.ExecContext(ctx, query, helpers.HashToken(state), nonce, verifier, nullString(returnTo), int(oidcStateTTL.Seconds()))
+	if err != nil {
+		return "", err
+	}
+	return state, nil
+}
+
+// OIDCState is a login in progress, as saved by CreateOIDCStateService.
+type OIDCState struct {
+	Nonce    string
+	Verifier string
+	ReturnTo string
+}
+
+// ConsumeOIDCStateService returns and forgets the login for state, so each
+// callback can only be used once.
+func ConsumeOIDCStateService(ctx context.Context, db *sql.DB, state string) (*OIDCState, error) {
+	var oidcState OIDCState
+	var returnTo sql.NullString
+	query := `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING nonce, code_verifier, return_to
+	`
+	err := db.QueryRowContext(ctx, query, helpers.HashToken(state)).Scan(&oidcState.Nonce, &oidcState.Verifier, &returnTo)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("invalid or expired state")
+	} else if err != nil {
+		return nil, err
+	}
+	oidcState.ReturnTo = returnTo.String
+	return &oidcState, nil
+}
+
+// OIDCLoginService finds the admin an identity provider login belongs to.
+// An identity seen before maps to the admin it was linked to. A new one is
+// linked to the admin with the same email, or, with autoProvision, to a new
+// admin, but only if the provider has verified the email and its domain is
+// allowed.
+func OIDCLoginService(ctx context.Context, db *sql.DB, identity *helpers.OIDCIdentity, allowedDomains []string, autoProvision bool) (*admin.AdminResponse, error) {
+	var adminResponse admin.AdminResponse
+	var deactivated bool
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		deactivated = false
+		query := `
+			UPDATE admin_identities i SET last_login_at = NOW(), email = $3
+			FROM admins a
+			WHERE a.id = i.admin_id AND i.issuer = $1 AND i.subject = $2
+			RETURNING a.id, a.uuid, a.name, a.email, a.totp_enabled_at IS NOT NULL, a.deactivated_at IS NOT NULL
+		`
+		err := tx.QueryRowContext(ctx, query, identity.Issuer, identity.Subject, nullString(identity.Email)).Scan(&adminResponse.ID, &adminResponse.UUID, &adminResponse.Name, &adminResponse.Email, &adminResponse.TwoFactorEnabled, &deactivated)
+		if err == nil {
+			return nil
+		} else if err != sql.ErrNoRows {
+			return err
+		}
+
+		if !strings.Contains(identity.Email, "@") || !identity.EmailVerified {
+			return errors.New("email not verified by identity provider")
+		}
+		if !emailDomainAllowed(identity.Email, allowedDomains) {
+			return errors.New("email domain not allowed")
+		}
+
+		query = `SELECT id, uuid, name, email, totp_enabled_at IS NOT NULL, deactivated_at IS NOT NULL FROM admins WHERE LOWER(email) = $1 FOR UPDATE`
+		err = tx.QueryRowContext(ctx, query, identity.Email).Scan(&adminResponse.ID, &adminResponse.UUID, &adminResponse.Name, &adminResponse.Email, &adminResponse.TwoFactorEnabled, &deactivated)
+		if err == sql.ErrNoRows {
+			if !autoProvision {
+				return errors.New("no admin for identity")
+			}
+			err = provisionOIDCAdmin(ctx, tx, identity, &adminResponse)
+		}
+		if err != nil {
+			return err
+		}
+
+		// The provider vouches for the email, so a local admin that had not
+		// verified it yet has now
+		query = `UPDATE admins SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, adminResponse.ID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO admin_identities (admin_id, issuer, subject, email, last_login_at)
+			VALUES ($1, $2, $3, $4, NOW())
+		`
+		_, err = tx.ExecContext(ctx, query, adminResponse.ID, identity.Issuer, identity.Subject, identity.Email)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The identity stays linked so the admin signs in the same way once
+	// reactivated
+	if deactivated {
+		return nil, errors.New("account deactivated")
+	}
+	return &adminResponse, nil
+}
+
+// provisionOIDCAdmin creates an admin for a first sign-on. It gets a random
+// password nobody knows; it can still set one through a password reset.
+func provisionOIDCAdmin(ctx context.Context, tx *sql.Tx, identity *helpers.OIDCIdentity, adminResponse *admin.AdminResponse) error {
+	password, err := helpers.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := helpers.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name = identity.Email[:strings.Index(identity.Email, "@")]
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+
+	query := `
+		INSERT INTO admins (name, email, password, email_verified_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, uuid, name, email
+	`
+	return tx.QueryRowContext(ctx, query, name, identity.Email, hashedPassword).Scan(&adminResponse.ID, &adminResponse.UUID, &adminResponse.Name, &adminResponse.Email)
+}
+
+func emailDomainAllowed(email string, allowedDomains []string) bool {
+	if len(allowedDomains) == 0 {
+		return true
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range allowedDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"basic-trade-api/models/outbox"
+	"basic-trade-api/models/webhook"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// outboxBatchSize is the most events relayed per ProcessOutboxService call
+	outboxBatchSize = 100
+	// outboxBaseBackoff is the wait after the first failed publish; it
+	// doubles on every further failure up to outboxMaxBackoff
+	outboxBaseBackoff = 5 * time.Second
+	outboxMaxBackoff  = 10 * time.Minute
+)
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordEvent writes event to the outbox. Pass the caller's transaction so
+// the event exists if and only if the change itself commits. The aggregate
+// type is the part of the event name before the first dot. The owner of the
+// product is taken from a product's own payload, since it may be deleted,
+// and looked up for variants and stock, whose product still exists.
+func recordEvent(ctx context.Context, q execer, event, aggregateID string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	aggregateType := strings.SplitN(event, ".", 2)[0]
+	query := `
+		INSERT INTO outbox (aggregate_type, aggregate_id, event, payload, admin_id)
+		VALUES ($1, $2, $3, $4, CASE $1
+			WHEN 'product' THEN ($4::JSONB->>'adminId')::INTEGER
+			WHEN 'variant' THEN (SELECT admin_id FROM products WHERE id = ($4::JSONB->>'productId')::INTEGER)
+			WHEN 'stock' THEN (
+				SELECT products.admin_id FROM variants JOIN products ON products.id = variants.product_id
+				WHERE variants.uuid::TEXT = $2
+			)
+		END)
+	`
+	_, err = q.ExecContext(ctx, query, aggregateType, aggregateID, event, string(payload))
+	return err
+}
+
+// ProcessOutboxService claims due outbox events in order and hands each to
+// publish. An event is marked published only after publish returns nil and
+// the claim commits, so delivery is at least once: a crash in between
+// publishes it again. Failed events are retried with capped exponential
+// backoff and never dropped. Events are claimed with SKIP LOCKED so several
+// replicas can run the relay.
+func ProcessOutboxService(ctx context.Context, db *sql.DB, publish func(outbox.Event) error) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, uuid, aggregate_type, aggregate_id, event, payload, COALESCE(admin_id, 0), attempts, created_at
+		FROM outbox
+		WHERE published_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var events []outbox.Event
+	for rows.Next() {
+		var event outbox.Event
+		if err := rows.Scan(&event.ID, &event.UUID, &event.AggregateType, &event.AggregateID, &event.Type, &event.Payload, &event.AdminID, &event.Attempts, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	rows.Close()
+
+	for _, event := range events {
+		if publishErr := publish(event); publishErr != nil {
+			backoff := outboxMaxBackoff
+			if event.Attempts < 16 && outboxBaseBackoff<<event.Attempts < outboxMaxBackoff {
+				backoff = outboxBaseBackoff << event.Attempts
+			}
+			query = `UPDATE outbox SET attempts = attempts + 1, last_error = $1, next_attempt_at = NOW() + $2 * INTERVAL '1 second' WHERE id = $3`
+			_, err = tx.ExecContext(ctx, query, publishErr.Error(), int(backoff.Seconds()), event.ID)
+		} else {
+			query = `UPDATE outbox SET attempts = attempts + 1, last_error = NULL, published_at = NOW() WHERE id = $1`
+			_, err = tx.ExecContext(ctx, query, event.ID)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// variantQuantity reads the on-hand total of a variant.
+func variantQuantity(ctx context.Context, q queryRower, variantID int) (int, error) {
+	var quantity int
+	err := q.QueryRowContext(ctx, `SELECT quantity FROM variants WHERE id = $1`, variantID).Scan(&quantity)
+	return quantity, err
+}
+
+// raiseStockChanged records a stock.changed event if the variant total moved
+// away from previous.
+func raiseStockChanged(ctx context.Context, tx *sql.Tx, variantID, previous int) error {
+	var data struct {
+		VariantID        int    `json:"variantId"`
+		VariantUUID      string `json:"variantUuid"`
+		SKU              string `json:"sku"`
+		PreviousQuantity int    `json:"previousQuantity"`
+		Quantity         int    `json:"quantity"`
+	}
+	query := `SELECT id, uuid, sku, quantity FROM variants WHERE id = $1`
+	if err := tx.QueryRowContext(ctx, query, variantID).Scan(&data.VariantID, &data.VariantUUID, &data.SKU, &data.Quantity); err != nil {
+		return err
+	}
+	if data.Quantity == previous {
+		return nil
+	}
+	data.PreviousQuantity = previous
+	return recordEvent(ctx, tx, webhook.EventStockChanged, data.VariantUUID, data)
+}
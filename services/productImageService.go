@@ -0,0 +1,299 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/product"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const productImageColumns = `product_images.id, product_images.uuid, product_images.url, product_images.public_id, product_images.alt_text,
+	product_images.position, product_images.is_primary, product_images.sizes, product_images.created_at, product_images.updated_at`
+
+func scanProductImage(row rowScanner, image *product.ProductImage) error {
+	var sizes []byte
+	err := row.Scan(&image.ID, &image.UUID, &image.URL, &image.PublicID, &image.AltText,
+		&image.Position, &image.IsPrimary, &sizes, &image.CreatedAt, &image.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(sizes, &image.Sizes)
+}
+
+func getImagesForProduct(ctx context.Context, db *sql.DB, productID int) ([]product.ProductImage, error) {
+	images := []product.ProductImage{}
+	query := `SELECT ` + productImageColumns + ` FROM product_images WHERE product_id = $1 ORDER BY position, id`
+	rows, err := db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var image product.ProductImage
+		if err := scanProductImage(rows, &image); err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// lockProduct locks a product of adminId so changes to its gallery or
+// options happen one at a time, and returns its ID.
+func lockProduct(ctx context.Context, tx *sql.Tx, productUUID string, adminId int) (int, error) {
+	var productID int
+	query := `SELECT id FROM products WHERE uuid = $1 AND admin_id = $2 FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, productUUID, adminId).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("product not found")
+	}
+	return productID, err
+}
+
+// insertProductImage adds an image at the end of a product's gallery. The
+// first image of a product is made primary whatever primary says.
+func insertProductImage(ctx context.Context, tx *sql.Tx, productID int, image *product.ProductImage, primary bool) error {
+	sizes := image.Sizes
+	if sizes == nil {
+		sizes = map[string]product.ImageSize{}
+	}
+	sizesJSON, err := json.Marshal(sizes)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO product_images (product_id, url, public_id, alt_text, sizes, position)
+		SELECT $1, $2, $3, $4, $5, COALESCE(MAX(position) + 1, 0) FROM product_images WHERE product_id = $1
+		RETURNING ` + productImageColumns
+	err = scanProductImage(tx.QueryRowContext(ctx, query, productID, image.URL, image.PublicID, image.AltText, sizesJSON), image)
+	if err != nil {
+		return err
+	}
+
+	if !primary && image.Position > 0 {
+		var hasPrimary bool
+		query = `SELECT EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND is_primary)`
+		if err := tx.QueryRowContext(ctx, query, productID).Scan(&hasPrimary); err != nil {
+			return err
+		}
+		if hasPrimary {
+			return nil
+		}
+	}
+	image.IsPrimary = true
+	return setPrimaryImage(ctx, tx, productID, image.ID)
+}
+
+// setPrimaryImage makes imageID the primary image of a product, or leaves
+// the product without one when imageID is zero, and copies its URL to the
+// product's image_url.
+func setPrimaryImage(ctx context.Context, tx *sql.Tx, productID, imageID int) error {
+	// Cleared first: the index allowing one primary image per product is
+	// checked row by row
+	query := `UPDATE product_images SET is_primary = FALSE, updated_at = CURRENT_TIMESTAMP WHERE product_id = $1 AND is_primary AND id <> $2`
+	if _, err := tx.ExecContext(ctx, query, productID, imageID); err != nil {
+		return err
+	}
+	query = `UPDATE product_images SET is_primary = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND NOT is_primary`
+	if _, err := tx.ExecContext(ctx, query, imageID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE products SET image_url = COALESCE((SELECT url FROM product_images WHERE id = $2), ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND image_url IS DISTINCT FROM COALESCE((SELECT url FROM product_images WHERE id = $2), '')
+	`
+	_, err := tx.ExecContext(ctx, query, productID, imageID)
+	return err
+}
+
+// AddProductImageService adds an uploaded image to the end of a product's
+// gallery.
+func AddProductImageService(ctx context.Context, db *sql.DB, productUUID string, image product.ProductImage, primary bool, adminId int) (*product.ProductImage, error) {
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		productID, err := lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		if err := insertProductImage(ctx, tx, productID, &image, primary); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, productID, adminId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// lockProductImage locks one image of a product.
+func lockProductImage(ctx context.Context, tx *sql.Tx, productID int, imageUUID string) (*product.ProductImage, error) {
+	var image product.ProductImage
+	query := `SELECT ` + productImageColumns + ` FROM product_images WHERE product_id = $1 AND uuid = $2 FOR UPDATE`
+	err := scanProductImage(tx.QueryRowContext(ctx, query, productID, imageUUID), &image)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("image not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// UpdateProductImageService changes an image's alt text or makes it the
+// primary one.
+func UpdateProductImageService(ctx context.Context, db *sql.DB, productUUID, imageUUID string, imageRequest product.UpdateProductImageRequest, adminId int) (*product.ProductImage, error) {
+	var image *product.ProductImage
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		productID, err := lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+		image, err = lockProductImage(ctx, tx, productID, imageUUID)
+		if err != nil {
+			return err
+		}
+
+		if imageRequest.AltText != nil {
+			image.AltText = *imageRequest.AltText
+			query := `UPDATE product_images SET alt_text = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+			if _, err := tx.ExecContext(ctx, query, image.AltText, image.ID); err != nil {
+				return err
+			}
+		}
+		if imageRequest.Primary && !image.IsPrimary {
+			image.IsPrimary = true
+			if err := setPrimaryImage(ctx, tx, productID, image.ID); err != nil {
+				return err
+			}
+		}
+
+		query := `SELECT ` + productImageColumns + ` FROM product_images WHERE id = $1`
+		if err := scanProductImage(tx.QueryRowContext(ctx, query, image.ID), image); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, productID, adminId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
+
+// ReorderProductImagesService puts a product's images in the order of
+// imageUUIDs, which must list each of them once.
+func ReorderProductImagesService(ctx context.Context, db *sql.DB, productUUID string, imageUUIDs []string, adminId int) ([]product.ProductImage, error) {
+	var productID int
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		productID, err = lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		positions := make(map[string]int, len(imageUUIDs))
+		for i, imageUUID := range imageUUIDs {
+			imageUUID = strings.ToLower(imageUUID)
+			if _, ok := positions[imageUUID]; ok {
+				return errors.New("image order does not match")
+			}
+			positions[imageUUID] = i
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT id, uuid FROM product_images WHERE product_id = $1 FOR UPDATE`, productID)
+		if err != nil {
+			return err
+		}
+		ids := make(map[int]int, len(imageUUIDs))
+		for rows.Next() {
+			var id int
+			var imageUUID string
+			if err := rows.Scan(&id, &imageUUID); err != nil {
+				rows.Close()
+				return err
+			}
+			position, ok := positions[imageUUID]
+			if !ok {
+				rows.Close()
+				return errors.New("image order does not match")
+			}
+			ids[id] = position
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+		if len(ids) != len(imageUUIDs) {
+			return errors.New("image order does not match")
+		}
+
+		query := `UPDATE product_images SET position = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND position <> $1`
+		for id, position := range ids {
+			if _, err := tx.ExecContext(ctx, query, position, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getImagesForProduct(ctx, db, productID)
+}
+
+// DeleteProductImageService removes an image from a product's gallery. When
+// it was the primary image, the next one in order takes its place. Deleting
+// the row queues its stored image for deletion.
+func DeleteProductImageService(ctx context.Context, db *sql.DB, productUUID, imageUUID string, adminId int) (*product.ProductImage, error) {
+	var image *product.ProductImage
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		productID, err := lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+		image, err = lockProductImage(ctx, tx, productID, imageUUID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM product_images WHERE id = $1`, image.ID); err != nil {
+			return err
+		}
+
+		if image.IsPrimary {
+			var nextID int
+			query := `SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1`
+			err := tx.QueryRowContext(ctx, query, productID).Scan(&nextID)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err := setPrimaryImage(ctx, tx, productID, nextID); err != nil {
+				return err
+			}
+		}
+
+		return recordProductRevision(ctx, tx, productID, adminId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return image, nil
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/product"
+	"basic-trade-api/models/variant"
+	"basic-trade-api/models/webhook"
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// variantMatrixMaxSize is the most option combinations a product may have
+// for its variant matrix to be generated
+const variantMatrixMaxSize = 100
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getOptionsForProduct returns a product's options with their values, both
+// in display order.
+func getOptionsForProduct(ctx context.Context, q querier, productID int) ([]product.ProductOption, error) {
+	options := []product.ProductOption{}
+	query := `
+		SELECT o.id, o.uuid, o.name, o.position, o.created_at, o.updated_at, ov.id, ov.uuid, ov.value, ov.position
+		FROM product_options o JOIN product_option_values ov ON ov.option_id = o.id
+		WHERE o.product_id = $1
+		ORDER BY o.position, o.id, ov.position, ov.id
+	`
+	rows, err := q.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var option product.ProductOption
+		var value product.ProductOptionValue
+		err := rows.Scan(&option.ID, &option.UUID, &option.Name, &option.Position, &option.CreatedAt, &option.UpdatedAt,
+			&value.ID, &value.UUID, &value.Value, &value.Position)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(options); n == 0 || options[n-1].ID != option.ID {
+			options = append(options, option)
+		}
+		last := &options[len(options)-1]
+		last.Values = append(last.Values, value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return options, nil
+}
+
+// optionKey names a combination of option values the way variants.option_key
+// stores it: value IDs ordered by option ID.
+func optionKey(values map[int]int) string {
+	optionIDs := make([]int, 0, len(values))
+	for optionID := range values {
+		optionIDs = append(optionIDs, optionID)
+	}
+	sort.Ints(optionIDs)
+
+	ids := make([]string, len(optionIDs))
+	for i, optionID := range optionIDs {
+		ids[i] = strconv.Itoa(values[optionID])
+	}
+	return strings.Join(ids, ",")
+}
+
+// writeVariantOptions replaces a variant's option values with values, keyed
+// by option ID, refusing a combination another variant of the product has.
+func writeVariantOptions(ctx context.Context, tx *sql.Tx, variantID int, values map[int]int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM variant_option_values WHERE variant_id = $1`, variantID); err != nil {
+		return err
+	}
+	query := `INSERT INTO variant_option_values (variant_id, option_id, value_id) VALUES ($1, $2, $3)`
+	for optionID, valueID := range values {
+		if _, err := tx.ExecContext(ctx, query, variantID, optionID, valueID); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE variants SET option_key = NULLIF($1, '') WHERE id = $2`, optionKey(values), variantID)
+	if database.IsUniqueViolation(err) {
+		return errors.New("option combination already exists")
+	}
+	return err
+}
+
+// setVariantOptions gives a variant the values named in options, by option
+// name, which must give a value for every option of the product. Names and
+// values are matched ignoring case. An empty options clears the values.
+func setVariantOptions(ctx context.Context, tx *sql.Tx, variantID, productID int, options map[string]string) error {
+	productOptions, err := getOptionsForProduct(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[int]int, len(options))
+	for name, value := range options {
+		var option *product.ProductOption
+		for i := range productOptions {
+			if strings.EqualFold(productOptions[i].Name, name) {
+				option = &productOptions[i]
+			}
+		}
+		if option == nil {
+			return errors.New("option not found")
+		}
+
+		found := false
+		for _, v := range option.Values {
+			if strings.EqualFold(v.Value, value) {
+				values[option.ID], found = v.ID, true
+			}
+		}
+		if !found {
+			return errors.New("option value not found")
+		}
+	}
+	// Also catches the same option named twice in different case
+	if len(options) > 0 && len(values) != len(productOptions) {
+		return errors.New("missing option value")
+	}
+
+	return writeVariantOptions(ctx, tx, variantID, values)
+}
+
+// SetProductOptionsService makes a product's options and their values those
+// of optionsRequest, in its order. Once variants have option values, no
+// option can be added: they would lack a value for it, and their option keys
+// would no longer name a full combination.
+func SetProductOptionsService(ctx context.Context, db *sql.DB, productUUID string, optionsRequest product.ProductOptionsRequest, adminId int) ([]product.ProductOption, error) {
+	seen := make(map[string]bool, len(optionsRequest.Options))
+	for _, option := range optionsRequest.Options {
+		name := strings.ToLower(option.Name)
+		if seen[name] {
+			return nil, errors.New("duplicate option")
+		}
+		seen[name] = true
+
+		values := make(map[string]bool, len(option.Values))
+		for _, value := range option.Values {
+			if values[strings.ToLower(value)] {
+				return nil, errors.New("duplicate option value")
+			}
+			values[strings.ToLower(value)] = true
+		}
+	}
+
+	var productID int
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		productID, err = lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		var keyed bool
+		query := `SELECT EXISTS (SELECT 1 FROM variants WHERE product_id = $1 AND option_key IS NOT NULL)`
+		if err := tx.QueryRowContext(ctx, query, productID).Scan(&keyed); err != nil {
+			return err
+		}
+		if keyed {
+			current, err := getOptionsForProduct(ctx, tx, productID)
+			if err != nil {
+				return err
+			}
+			existing := make(map[string]bool, len(current))
+			for _, option := range current {
+				existing[strings.ToLower(option.Name)] = true
+			}
+			for _, option := range optionsRequest.Options {
+				if !existing[strings.ToLower(option.Name)] {
+					return errors.New("variants have option values")
+				}
+			}
+		}
+
+		optionIDs := make([]int64, 0, len(optionsRequest.Options))
+		for i, option := range optionsRequest.Options {
+			var optionID int64
+			query := `
+				INSERT INTO product_options (product_id, name, position) VALUES ($1, $2, $3)
+				ON CONFLICT (product_id, (LOWER(name))) DO UPDATE
+				SET name = EXCLUDED.name, position = EXCLUDED.position, updated_at = CURRENT_TIMESTAMP
+				RETURNING id
+			`
+			if err := tx.QueryRowContext(ctx, query, productID, option.Name, i).Scan(&optionID); err != nil {
+				return err
+			}
+			optionIDs = append(optionIDs, optionID)
+
+			valueIDs := make([]int64, 0, len(option.Values))
+			for j, value := range option.Values {
+				var valueID int64
+				query = `
+					INSERT INTO product_option_values (option_id, value, position) VALUES ($1, $2, $3)
+					ON CONFLICT (option_id, (LOWER(value))) DO UPDATE
+					SET value = EXCLUDED.value, position = EXCLUDED.position
+					RETURNING id
+				`
+				if err := tx.QueryRowContext(ctx, query, optionID, value, j).Scan(&valueID); err != nil {
+					return err
+				}
+				valueIDs = append(valueIDs, valueID)
+			}
+
+			var inUse bool
+			query = `
+				SELECT EXISTS (
+					SELECT 1 FROM variant_option_values vo JOIN product_option_values ov ON ov.id = vo.value_id
+					WHERE ov.option_id = $1 AND NOT (ov.id = ANY($2))
+				)
+			`
+			if err := tx.QueryRowContext(ctx, query, optionID, pq.Array(valueIDs)).Scan(&inUse); err != nil {
+				return err
+			}
+			if inUse {
+				return errors.New("option value in use")
+			}
+			query = `DELETE FROM product_option_values WHERE option_id = $1 AND NOT (id = ANY($2))`
+			if _, err := tx.ExecContext(ctx, query, optionID, pq.Array(valueIDs)); err != nil {
+				return err
+			}
+		}
+
+		var inUse bool
+		query = `
+			SELECT EXISTS (
+				SELECT 1 FROM variant_option_values vo JOIN product_options o ON o.id = vo.option_id
+				WHERE o.product_id = $1 AND NOT (o.id = ANY($2))
+			)
+		`
+		if err := tx.QueryRowContext(ctx, query, productID, pq.Array(optionIDs)).Scan(&inUse); err != nil {
+			return err
+		}
+		if inUse {
+			return errors.New("option in use")
+		}
+		query = `DELETE FROM product_options WHERE product_id = $1 AND NOT (id = ANY($2))`
+		_, err = tx.ExecContext(ctx, query, productID, pq.Array(optionIDs))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getOptionsForProduct(ctx, db, productID)
+}
+
+// variantSKUPart turns an option value into SKU characters: upper case
+// letters and digits, with anything else as a dash.
+func variantSKUPart(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToUpper(value) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// GenerateVariantMatrixService creates a variant, without stock, for every
+// combination of a product's option values that no variant has yet. Each is
+// named after its values, such as "Red / XL", and given the SKU prefix
+// followed by them, such as "SHIRT-RED-XL".
+func GenerateVariantMatrixService(ctx context.Context, db *sql.DB, productUUID string, matrixRequest product.VariantMatrixRequest, adminId int) ([]variant.VariantResponse, error) {
+	variants := []variant.VariantResponse{}
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		productID, err := lockProduct(ctx, tx, productUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		options, err := getOptionsForProduct(ctx, tx, productID)
+		if err != nil {
+			return err
+		}
+		if len(options) == 0 {
+			return errors.New("product has no options")
+		}
+		size := 1
+		for _, option := range options {
+			size *= len(option.Values)
+			if size > variantMatrixMaxSize {
+				return errors.New("variant matrix too large")
+			}
+		}
+
+		existing := make(map[string]bool)
+		rows, err := tx.QueryContext(ctx, `SELECT option_key FROM variants WHERE product_id = $1 AND option_key IS NOT NULL`, productID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return err
+			}
+			existing[key] = true
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		warehouseID, err := resolveWarehouseID(ctx, tx, 0)
+		if err != nil {
+			return err
+		}
+
+		// Walk the combinations like an odometer, the last option turning
+		// fastest
+		picks := make([]int, len(options))
+		for n := 0; n < size; n++ {
+			values := make(map[int]int, len(options))
+			names := make([]string, len(options))
+			skuParts := []string{matrixRequest.SKUPrefix}
+			for i, option := range options {
+				value := option.Values[picks[i]]
+				values[option.ID] = value.ID
+				names[i] = value.Value
+				if part := variantSKUPart(value.Value); part != "" {
+					skuParts = append(skuParts, part)
+				}
+			}
+			for i := len(picks) - 1; i >= 0; i-- {
+				picks[i]++
+				if picks[i] < len(options[i].Values) {
+					break
+				}
+				picks[i] = 0
+			}
+
+			if existing[optionKey(values)] {
+				continue
+			}
+			sku := strings.Join(skuParts, "-")
+			if len(sku) > 64 {
+				return errors.New("sku too long")
+			}
+
+			var variantID int
+			query := `INSERT INTO variants (variant_name, sku, quantity, product_id) VALUES ($1, $2, 0, $3) RETURNING id`
+			err := tx.QueryRowContext(ctx, query, strings.Join(names, " / "), sku, productID).Scan(&variantID)
+			if database.IsUniqueViolation(err) {
+				return errors.New("sku already exists")
+			} else if err != nil {
+				return err
+			}
+			if err := setStock(ctx, tx, variantID, warehouseID, 0); err != nil {
+				return err
+			}
+			if err := writeVariantOptions(ctx, tx, variantID, values); err != nil {
+				return err
+			}
+
+			var variantResponse variant.VariantResponse
+			query = `SELECT ` + variantColumns + ` FROM variants WHERE id = $1`
+			if err := scanVariant(tx.QueryRowContext(ctx, query, variantID), &variantResponse); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, webhook.EventVariantCreated, variantResponse.UUID, variantResponse); err != nil {
+				return err
+			}
+			variants = append(variants, variantResponse)
+		}
+
+		if len(variants) == 0 {
+			return nil
+		}
+		return recordProductRevision(ctx, tx, productID, adminId, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
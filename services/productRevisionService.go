@@ -0,0 +1,450 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/product"
+	"basic-trade-api/models/variant"
+	"basic-trade-api/models/webhook"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// productSnapshotQuery selects product $1 in the shape of
+// product.ProductSnapshot. Migration 000019 built the first revisions with
+// the same expression, so snapshots compare equal when nothing changed; a
+// variant only gets options when it has option values, which keeps those
+// revisions comparable.
+const productSnapshotQuery = `
+	SELECT jsonb_build_object(
+		'name', p.name,
+		'imageUrl', p.image_url,
+		'variants', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'uuid', v.uuid,
+				'variantName', v.variant_name,
+				'sku', v.sku,
+				'barcode', v.barcode,
+				'reorderPoint', v.reorder_point
+			) || COALESCE((
+				SELECT jsonb_build_object('options', jsonb_object_agg(o.name, ov.value))
+				FROM variant_option_values vo
+				JOIN product_options o ON o.id = vo.option_id
+				JOIN product_option_values ov ON ov.id = vo.value_id
+				WHERE vo.variant_id = v.id
+				HAVING COUNT(*) > 0
+			), '{}'::JSONB) ORDER BY v.id)
+			FROM variants v WHERE v.product_id = p.id
+		), '[]'::JSONB)
+	) AS snapshot
+	FROM products p WHERE p.id = $1
+`
+
+// recordProductRevision stores the current state of a product as its next
+// revision. Call it in the transaction that made the change. Changes a
+// snapshot does not keep, such as stock, do not make a revision; restores
+// always do.
+func recordProductRevision(ctx context.Context, tx *sql.Tx, productID, adminID int, restoredFrom *int) error {
+	// Lock the product so concurrent changes number their revisions in turn
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, productID); err != nil {
+		return err
+	}
+
+	query := `
+		WITH current AS (` + productSnapshotQuery + `),
+		latest AS (
+			SELECT revision, snapshot FROM product_revisions WHERE product_id = $1 ORDER BY revision DESC LIMIT 1
+		)
+		INSERT INTO product_revisions (product_id, revision, snapshot, admin_id, restored_from)
+		SELECT $1, COALESCE((SELECT revision FROM latest), 0) + 1, current.snapshot, NULLIF($2, 0), $3
+		FROM current
+		WHERE $3::INTEGER IS NOT NULL OR NOT EXISTS (SELECT 1 FROM latest WHERE latest.snapshot = current.snapshot)
+	`
+	_, err := tx.ExecContext(ctx, query, productID, adminID, restoredFrom)
+	return err
+}
+
+const productRevisionColumns = `r.revision, r.admin_id, r.restored_from, r.snapshot, r.created_at`
+
+func scanProductRevision(row rowScanner, revision *product.ProductRevision) error {
+	var snapshot []byte
+	if err := row.Scan(&revision.Revision, &revision.AdminID, &revision.RestoredFrom, &snapshot, &revision.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(snapshot, &revision.Snapshot)
+}
+
+// GetProductRevisionsService lists a product's revisions, newest first.
+func GetProductRevisionsService(ctx context.Context, db *sql.DB, productUUID string, pageSize, offset int) ([]product.ProductRevision, int, error) {
+	var total int
+	query := `SELECT COUNT(*) FROM product_revisions r JOIN products p ON p.id = r.product_id WHERE p.uuid = $1`
+	if err := db.QueryRowContext(ctx, query, productUUID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query = `
+		SELECT ` + productRevisionColumns + `
+		FROM product_revisions r
+		JOIN products p ON p.id = r.product_id
+		WHERE p.uuid = $1
+		ORDER BY r.revision DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.QueryContext(ctx, query, productUUID, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	revisions := []product.ProductRevision{}
+	for rows.Next() {
+		var revision product.ProductRevision
+		if err := scanProductRevision(rows, &revision); err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+func getProductRevision(ctx context.Context, q queryRower, productUUID string, rev int) (*product.ProductRevision, error) {
+	var revision product.ProductRevision
+	query := `
+		SELECT ` + productRevisionColumns + `
+		FROM product_revisions r
+		JOIN products p ON p.id = r.product_id
+		WHERE p.uuid = $1 AND r.revision = $2
+	`
+	err := scanProductRevision(q.QueryRowContext(ctx, query, productUUID, rev), &revision)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("revision not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func GetProductRevisionService(ctx context.Context, db *sql.DB, productUUID string, rev int) (*product.ProductRevision, error) {
+	return getProductRevision(ctx, db, productUUID, rev)
+}
+
+// DiffProductRevisionsService compares revision from with revision to.
+func DiffProductRevisionsService(ctx context.Context, db *sql.DB, productUUID string, from, to int) (*product.RevisionDiff, error) {
+	fromRevision, err := getProductRevision(ctx, db, productUUID, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := getProductRevision(ctx, db, productUUID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := product.RevisionDiff{
+		From:            from,
+		To:              to,
+		Changes:         []product.FieldChange{},
+		AddedVariants:   []product.VariantSnapshot{},
+		RemovedVariants: []product.VariantSnapshot{},
+		ChangedVariants: []product.VariantChange{},
+	}
+	before, after := fromRevision.Snapshot, toRevision.Snapshot
+	diff.Changes = appendChange(diff.Changes, "name", before.Name, after.Name)
+	diff.Changes = appendChange(diff.Changes, "imageUrl", before.ImageURL, after.ImageURL)
+
+	previous := make(map[string]product.VariantSnapshot, len(before.Variants))
+	for _, v := range before.Variants {
+		previous[v.UUID] = v
+	}
+	for _, v := range after.Variants {
+		old, ok := previous[v.UUID]
+		if !ok {
+			diff.AddedVariants = append(diff.AddedVariants, v)
+			continue
+		}
+		delete(previous, v.UUID)
+
+		changes := appendChange(nil, "variantName", old.VariantName, v.VariantName)
+		changes = appendChange(changes, "sku", old.SKU, v.SKU)
+		changes = appendChange(changes, "barcode", derefString(old.Barcode), derefString(v.Barcode))
+		changes = appendChange(changes, "reorderPoint", derefInt(old.ReorderPoint), derefInt(v.ReorderPoint))
+		if !sameOptions(old.Options, v.Options) {
+			changes = append(changes, product.FieldChange{Field: "options", From: old.Options, To: v.Options})
+		}
+		if len(changes) > 0 {
+			diff.ChangedVariants = append(diff.ChangedVariants, product.VariantChange{UUID: v.UUID, Changes: changes})
+		}
+	}
+	// Keep removed variants in the order the older revision listed them
+	for _, v := range before.Variants {
+		if _, ok := previous[v.UUID]; ok {
+			diff.RemovedVariants = append(diff.RemovedVariants, v)
+		}
+	}
+	return &diff, nil
+}
+
+func appendChange(changes []product.FieldChange, field string, from, to interface{}) []product.FieldChange {
+	if from == to {
+		return changes
+	}
+	return append(changes, product.FieldChange{Field: field, From: from, To: to})
+}
+
+// derefString and derefInt turn optional fields into comparable values,
+// nil when unset.
+func derefString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func derefInt(i *int) interface{} {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
+
+func sameOptions(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RestoreProductRevisionService puts a product and its variants back the way
+// revision rev had them, and records that as a new revision so the restore
+// can be undone too. Variants removed since are recreated without stock;
+// variants added since are deleted, which is refused while they hold stock.
+// Stock of the remaining variants is left alone.
+func RestoreProductRevisionService(ctx context.Context, db *sql.DB, productUUID string, rev int, adminId int) (*product.ProductResponse, error) {
+	var productID int
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		query := `SELECT id FROM products WHERE uuid = $1 AND admin_id = $2 FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, productUUID, adminId).Scan(&productID)
+		if err == sql.ErrNoRows {
+			return errors.New("product not found")
+		} else if err != nil {
+			return err
+		}
+
+		revision, err := getProductRevision(ctx, tx, productUUID, rev)
+		if err != nil {
+			return err
+		}
+		snapshot := revision.Snapshot
+
+		query = `UPDATE products SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, snapshot.Name, productID); err != nil {
+			return err
+		}
+		// Make the image primary again if it is still in the gallery; one
+		// since removed may already be deleted from storage, so the current
+		// image is kept instead
+		var imageID int
+		query = `SELECT id FROM product_images WHERE product_id = $1 AND url = $2 ORDER BY position, id LIMIT 1`
+		err = tx.QueryRowContext(ctx, query, productID, snapshot.ImageURL).Scan(&imageID)
+		if err == nil {
+			err = setPrimaryImage(ctx, tx, productID, imageID)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if err := removeVariantsNotIn(ctx, tx, productID, snapshot.Variants); err != nil {
+			return err
+		}
+		events := make([]string, len(snapshot.Variants))
+		variantIDs := make([]int, len(snapshot.Variants))
+		for i, v := range snapshot.Variants {
+			variantIDs[i], events[i], err = restoreVariant(ctx, tx, productID, v)
+			if err != nil {
+				return err
+			}
+		}
+		if err := restoreVariantOptions(ctx, tx, productID, snapshot.Variants, variantIDs, events); err != nil {
+			return err
+		}
+		for i, variantID := range variantIDs {
+			if events[i] == "" {
+				continue
+			}
+			var variantResponse variant.VariantResponse
+			query = `SELECT ` + variantColumns + ` FROM variants WHERE id = $1`
+			if err := scanVariant(tx.QueryRowContext(ctx, query, variantID), &variantResponse); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, events[i], variantResponse.UUID, variantResponse); err != nil {
+				return err
+			}
+		}
+
+		var restored product.ProductResponse
+		query = `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+		err = scanProduct(tx.QueryRowContext(ctx, query, productID), &restored)
+		if err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, tx, webhook.EventProductUpdated, restored.UUID, restored); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, productID, adminId, &rev)
+	})
+	if database.IsUniqueViolation(err) || (err != nil && err.Error() == "option combination already exists") {
+		return nil, errors.New("revision conflicts with current data")
+	} else if err != nil {
+		return nil, err
+	}
+
+	restored, err := GetProductByIDService(ctx, db, productUUID)
+	if err != nil {
+		return nil, err
+	}
+	restored.Variants, err = getVariantsForProduct(ctx, db, productID)
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// removeVariantsNotIn deletes the product's variants that keep is missing,
+// unless one of them still has stock or held reservations.
+func removeVariantsNotIn(ctx context.Context, tx *sql.Tx, productID int, keep []product.VariantSnapshot) error {
+	kept := make(map[string]bool, len(keep))
+	for _, v := range keep {
+		kept[v.UUID] = true
+	}
+
+	query := `SELECT ` + variantColumns + ` FROM variants WHERE product_id = $1 ORDER BY id FOR UPDATE`
+	rows, err := tx.QueryContext(ctx, query, productID)
+	if err != nil {
+		return err
+	}
+	var removed []variant.VariantResponse
+	for rows.Next() {
+		var variantResponse variant.VariantResponse
+		if err := scanVariant(rows, &variantResponse); err != nil {
+			rows.Close()
+			return err
+		}
+		if !kept[variantResponse.UUID] {
+			removed = append(removed, variantResponse)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, v := range removed {
+		if v.Quantity > 0 || v.Reserved > 0 {
+			return errors.New("variant has stock")
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM variants WHERE id = $1`, v.ID); err != nil {
+			return err
+		}
+		if err := recordEvent(ctx, tx, webhook.EventVariantDeleted, v.UUID, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreVariant puts back the fields of one variant of a snapshot,
+// recreating it with its old UUID and no stock if it has been deleted. It
+// returns the variant and the event to record for it, empty if unchanged.
+func restoreVariant(ctx context.Context, tx *sql.Tx, productID int, v product.VariantSnapshot) (int, string, error) {
+	var variantID, currentProductID int
+	err := tx.QueryRowContext(ctx, `SELECT id, product_id FROM variants WHERE uuid = $1 FOR UPDATE`, v.UUID).Scan(&variantID, &currentProductID)
+	switch {
+	case err == sql.ErrNoRows:
+		query := `
+			INSERT INTO variants (uuid, variant_name, sku, barcode, quantity, reorder_point, product_id)
+			VALUES ($1, $2, $3, $4, 0, $5, $6)
+			RETURNING id
+		`
+		err = tx.QueryRowContext(ctx, query, v.UUID, v.VariantName, v.SKU, v.Barcode, v.ReorderPoint, productID).Scan(&variantID)
+		if err != nil {
+			return 0, "", err
+		}
+		warehouseID, err := resolveWarehouseID(ctx, tx, 0)
+		if err != nil {
+			return 0, "", err
+		}
+		if err := setStock(ctx, tx, variantID, warehouseID, 0); err != nil {
+			return 0, "", err
+		}
+		return variantID, webhook.EventVariantCreated, nil
+	case err != nil:
+		return 0, "", err
+	case currentProductID != productID:
+		return 0, "", errors.New("variant moved to another product")
+	}
+
+	query := `
+		UPDATE variants SET variant_name = $1, sku = $2, barcode = $3, reorder_point = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND (variant_name, sku, barcode, reorder_point) IS DISTINCT FROM ($1, $2, $3, $4)
+	`
+	result, err := tx.ExecContext(ctx, query, v.VariantName, v.SKU, v.Barcode, v.ReorderPoint, variantID)
+	if err != nil {
+		return 0, "", err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, "", err
+	}
+	if updated == 0 {
+		// Unchanged since the revision, unless its options were
+		return variantID, "", nil
+	}
+	return variantID, webhook.EventVariantUpdated, nil
+}
+
+// restoreVariantOptions gives the restored variants, variantIDs in the order
+// of snapshot, the option values the snapshot has for them, marking those
+// that change as updated in events. Every value must still exist, and every
+// option of the product be given, or the restore is refused. The changed
+// variants are cleared first so values swapped between them do not collide.
+func restoreVariantOptions(ctx context.Context, tx *sql.Tx, productID int, snapshot []product.VariantSnapshot, variantIDs []int, events []string) error {
+	var changed []int
+	for i, v := range snapshot {
+		var current variant.VariantResponse
+		query := `SELECT ` + variantColumns + ` FROM variants WHERE id = $1`
+		if err := scanVariant(tx.QueryRowContext(ctx, query, variantIDs[i]), &current); err != nil {
+			return err
+		}
+		if sameOptions(current.Options, v.Options) {
+			continue
+		}
+		if err := writeVariantOptions(ctx, tx, variantIDs[i], nil); err != nil {
+			return err
+		}
+		changed = append(changed, i)
+	}
+
+	for _, i := range changed {
+		err := setVariantOptions(ctx, tx, variantIDs[i], productID, snapshot[i].Options)
+		switch {
+		case err == nil:
+		case err.Error() == "option not found", err.Error() == "option value not found", err.Error() == "missing option value":
+			return errors.New("revision option values no longer exist")
+		default:
+			return err
+		}
+		if events[i] == "" {
+			events[i] = webhook.EventVariantUpdated
+		}
+	}
+	return nil
+}
@@ -1,29 +1,90 @@
 package services
 
 import (
+	"basic-trade-api/database"
 	"basic-trade-api/models/product"
 	"basic-trade-api/models/variant"
+	"basic-trade-api/models/webhook"
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
-func CreateProductService(db *sql.DB, productRequest product.ProductRequest, adminId int) (*product.ProductResponse, error) {
+const productColumns = `products.id, products.uuid, products.name, products.image_url, products.admin_id, products.created_at, products.updated_at,
+	products.status, products.publish_at, products.unpublish_at`
+
+func scanProduct(row rowScanner, productResponse *product.ProductResponse) error {
+	return row.Scan(&productResponse.ID, &productResponse.UUID, &productResponse.Name, &productResponse.ImageURL, &productResponse.AdminID, &productResponse.CreatedAt, &productResponse.UpdatedAt,
+		&productResponse.Status, &productResponse.PublishAt, &productResponse.UnpublishAt)
+}
+
+// productSchedule applies the schedule fields of a request to the current
+// ones: nil keeps a time, the zero time clears it. Times are stored in UTC
+// whatever offset they were sent with.
+func productSchedule(current, requested *time.Time) *time.Time {
+	if requested == nil {
+		return current
+	}
+	if requested.IsZero() {
+		return nil
+	}
+	utc := requested.UTC()
+	return &utc
+}
+
+func checkProductSchedule(publishAt, unpublishAt *time.Time) error {
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return errors.New("unpublish must be after publish")
+	}
+	return nil
+}
+
+func CreateProductService(ctx context.Context, db *sql.DB, productRequest product.ProductRequest, adminId int) (*product.ProductResponse, error) {
 	var productResponse product.ProductResponse
 
-	// Insert the data and retrieve the generated ID
-	query := `INSERT INTO products (name, image_url, admin_id) VALUES ($1, $2, $3) RETURNING id`
-	err := db.QueryRow(query, productRequest.Name, productRequest.ImageURL, adminId).Scan(&productResponse.ID)
-	if err != nil {
+	status := productRequest.Status
+	if status == "" {
+		status = product.StatusDraft
+	}
+	publishAt := productSchedule(nil, productRequest.PublishAt)
+	unpublishAt := productSchedule(nil, productRequest.UnpublishAt)
+	if err := checkProductSchedule(publishAt, unpublishAt); err != nil {
 		return nil, err
 	}
 
-	// Fetch the inserted row using the generated ID
-	query = `SELECT id, uuid, name, image_url, admin_id, created_at, updated_at FROM products WHERE id = $1`
-	err = db.QueryRow(query, productResponse.ID).Scan(
-		&productResponse.ID, &productResponse.UUID, &productResponse.Name, &productResponse.ImageURL,
-		&productResponse.AdminID, &productResponse.CreatedAt, &productResponse.UpdatedAt,
-	)
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Insert the data and retrieve the generated ID
+		query := `INSERT INTO products (name, image_url, admin_id, status, publish_at, unpublish_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+		err := tx.QueryRowContext(ctx, query, productRequest.Name, productRequest.ImageURL, adminId, status, publishAt, unpublishAt).Scan(&productResponse.ID)
+		if err != nil {
+			return err
+		}
+
+		// Fetch the inserted row using the generated ID
+		query = `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+		err = scanProduct(tx.QueryRowContext(ctx, query, productResponse.ID), &productResponse)
+		if err != nil {
+			return err
+		}
+
+		productResponse.Images = []product.ProductImage{}
+		productResponse.Options = []product.ProductOption{}
+		if productRequest.ImageURL != "" {
+			image, err := addRequestImage(ctx, tx, productResponse.ID, productRequest)
+			if err != nil {
+				return err
+			}
+			productResponse.Images = append(productResponse.Images, *image)
+		}
+
+		if err := recordEvent(ctx, tx, webhook.EventProductCreated, productResponse.UUID, productResponse); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, productResponse.ID, adminId, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -33,133 +94,131 @@ func CreateProductService(db *sql.DB, productRequest product.ProductRequest, adm
 	return &productResponse, nil
 }
 
-func GetAllProductService(db *sql.DB, pageSize, offset int, name string) ([]product.ProductResponse, int, error) {
-    var products []product.ProductResponse
-    var total int
-
-    // Count total number of products
-    query := `SELECT COUNT(*) FROM products`
-    err := db.QueryRow(query).Scan(&total)
-    if err != nil {
-        return nil, 0, err
-    }
-
-    baseQuery := ` SELECT products.id, products.uuid, products.name, products.image_url, products.admin_id, products.created_at, products.updated_at FROM products `
-
-    // Add WHERE clause if name is provided
-    if name != "" {
-        name = "%" + name + "%"
-        baseQuery += `WHERE name ILIKE $1`
-        baseQuery += `LIMIT $2 OFFSET $3`
-
-        // Execute the query
-        rows, err := db.Query(baseQuery, name, pageSize, offset)
-        if err != nil {
-            return nil, 0, err
-        }
-        defer rows.Close()
-
-        // Process the query results
-        for rows.Next() {
-            var productResponse product.ProductResponse
-            err := rows.Scan(&productResponse.ID, &productResponse.UUID, &productResponse.Name, &productResponse.ImageURL, &productResponse.AdminID, &productResponse.CreatedAt, &productResponse.UpdatedAt)
-            if err != nil {
-                return nil, 0, err
-            }
-
-            // Fetch variants for the product
-            variants, err := getVariantsForProduct(db, productResponse.ID)
-            if err != nil {
-                return nil, 0, err
-            }
-            productResponse.Variants = variants
-
-            products = append(products, productResponse)
-        }
-
-        if err = rows.Err(); err != nil {
-            return nil, 0, err
-        }
-    } else {
-        baseQuery += `LIMIT $1 OFFSET $2`
-
-        // Execute the query
-        rows, err := db.Query(baseQuery, pageSize, offset)
-        if err != nil {
-            return nil, 0, err
-        }
-        defer rows.Close()
-
-        // Process the query results
-        for rows.Next() {
-            var productResponse product.ProductResponse
-            err := rows.Scan(&productResponse.ID, &productResponse.UUID, &productResponse.Name, &productResponse.ImageURL, &productResponse.AdminID, &productResponse.CreatedAt, &productResponse.UpdatedAt)
-            if err != nil {
-                return nil, 0, err
-            }
-
-            // Fetch variants for the product
-            variants, err := getVariantsForProduct(db, productResponse.ID)
-            if err != nil {
-                return nil, 0, err
-            }
-            productResponse.Variants = variants
-
-            products = append(products, productResponse)
-        }
-
-        if err = rows.Err(); err != nil {
-            return nil, 0, err
-        }
-    }
-
-    return products, total, nil
+// addRequestImage adds the image sent with a product create or update to the
+// product's gallery as its primary image.
+func addRequestImage(ctx context.Context, tx *sql.Tx, productID int, productRequest product.ProductRequest) (*product.ProductImage, error) {
+	image := product.ProductImage{URL: productRequest.ImageURL}
+	if productRequest.Image != nil {
+		image = *productRequest.Image
+	}
+	if err := insertProductImage(ctx, tx, productID, &image, true); err != nil {
+		return nil, err
+	}
+	return &image, nil
 }
 
-func getVariantsForProduct(db *sql.DB, productID int) ([]variant.VariantResponse, error) {
-    var variants []variant.VariantResponse
-    query := ` SELECT id, uuid, variant_name, quantity, product_id, created_at, updated_at FROM variants WHERE product_id = $1 `
-    rows, err := db.Query(query, productID)
-    if err != nil {
-        return nil, err
-    }
-    defer rows.Close()
-
-    for rows.Next() {
-        var variantResponse variant.VariantResponse
-        err := rows.Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
-        if err != nil {
-            return nil, err
-        }
-        variants = append(variants, variantResponse)
-    }
-
-    if err = rows.Err(); err != nil {
-        return nil, err
-    }
-
-    return variants, nil
+// productFilter builds the WHERE clause shared by the product listing and
+// export queries. Placeholders are numbered from 1.
+func productFilter(filter product.ProductFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Name != "" {
+		args = append(args, "%"+filter.Name+"%")
+		conditions = append(conditions, fmt.Sprintf(`products.name ILIKE $%d`, len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf(`products.status = $%d`, len(args)))
+	}
+	if filter.ViewerID != 0 {
+		args = append(args, filter.ViewerID)
+		conditions = append(conditions, fmt.Sprintf(`(products.status = 'published' OR products.admin_id = $%d)`, len(args)))
+	} else {
+		conditions = append(conditions, `products.status = 'published'`)
+	}
+
+	return ` WHERE ` + strings.Join(conditions, ` AND `), args
 }
 
-func GetProductByIDService(db *sql.DB, productUUID string) (*product.ProductResponse, error) {
-	var product product.ProductResponse
+func GetAllProductService(ctx context.Context, db *sql.DB, pageSize, offset int, filter product.ProductFilter) ([]product.ProductResponse, int, error) {
+	var products []product.ProductResponse
+	var total int
 
-	query := `SELECT id, uuid, name, image_url, admin_id, created_at, updated_at FROM products WHERE UUID = $1`
-	err := db.QueryRow(query, productUUID).Scan(&product.ID, &product.UUID, &product.Name, &product.ImageURL, &product.AdminID, &product.CreatedAt, &product.UpdatedAt)
-	if err == sql.ErrNoRows {
-		// If no product is found with the given UUID, return a custom error
-		return nil, errors.New("product not found")
-	} else if err != nil {
+	where, args := productFilter(filter)
+
+	// Count total number of products
+	query := `SELECT COUNT(*) FROM products` + where
+	err := db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	baseQuery := `SELECT ` + productColumns + ` FROM products` + where
+	baseQuery += fmt.Sprintf(` ORDER BY products.id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+
+	// Execute the query
+	rows, err := db.QueryContext(ctx, baseQuery, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Process the query results
+	for rows.Next() {
+		var productResponse product.ProductResponse
+		err := scanProduct(rows, &productResponse)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Fetch variants for the product
+		variants, err := getVariantsForProduct(ctx, db, productResponse.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		productResponse.Variants = variants
+
+		productResponse.Images, err = getImagesForProduct(ctx, db, productResponse.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		productResponse.Options, err = getOptionsForProduct(ctx, db, productResponse.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		products = append(products, productResponse)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+func getVariantsForProduct(ctx context.Context, db *sql.DB, productID int) ([]variant.VariantResponse, error) {
+	var variants []variant.VariantResponse
+	query := `SELECT ` + variantColumns + ` FROM variants WHERE product_id = $1`
+	rows, err := db.QueryContext(ctx, query, productID)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return &product, nil
+	for rows.Next() {
+		var variantResponse variant.VariantResponse
+		err := scanVariant(rows, &variantResponse)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variantResponse)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
 }
 
-func UpdateProductService(db *sql.DB, productRequest product.ProductRequest, productUUID string, adminId int) (*product.ProductResponse, error) {
+func GetProductByIDService(ctx context.Context, db *sql.DB, productUUID string) (*product.ProductResponse, error) {
 	var product product.ProductResponse
-	query := `SELECT id, uuid, name, image_url, admin_id, created_at, updated_at FROM products WHERE UUID = $1 AND admin_id = $2`
-	err := db.QueryRow(query, productUUID, adminId).Scan(&product.ID, &product.UUID, &product.Name, &product.ImageURL, &product.AdminID, &product.CreatedAt, &product.UpdatedAt)
+
+	query := `SELECT ` + productColumns + ` FROM products WHERE UUID = $1`
+	err := scanProduct(db.QueryRowContext(ctx, query, productUUID), &product)
 	if err == sql.ErrNoRows {
 		// If no product is found with the given UUID, return a custom error
 		return nil, errors.New("product not found")
@@ -167,17 +226,12 @@ func UpdateProductService(db *sql.DB, productRequest product.ProductRequest, pro
 		return nil, err
 	}
 
-	product.Name = productRequest.Name
-	// Update product.ImageURL with the new uploaded file URL
-	if productRequest.ImageURL != "" {
-		product.ImageURL = productRequest.ImageURL
+	product.Images, err = getImagesForProduct(ctx, db, product.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	product.ImageFileHeader = productRequest.ImageFile
-	product.UpdatedAt = time.Now()
-
-	query = `UPDATE products SET name = $1, image_url = $2, updated_at = $3 WHERE UUID = $4 AND admin_id = $5`
-	_, err = db.Exec(query, product.Name, product.ImageURL, product.UpdatedAt, productUUID, adminId)
+	product.Options, err = getOptionsForProduct(ctx, db, product.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -185,29 +239,177 @@ func UpdateProductService(db *sql.DB, productRequest product.ProductRequest, pro
 	return &product, nil
 }
 
-func DeleteProductService(db *sql.DB, productUUID string, adminId int) (*product.ProductResponse, error) {
+func UpdateProductService(ctx context.Context, db *sql.DB, productRequest product.ProductRequest, productUUID string, adminId int) (*product.ProductResponse, error) {
 	var product product.ProductResponse
-	query := `SELECT id, uuid, name, image_url, admin_id, created_at, updated_at FROM products WHERE uuid = $1 AND admin_id = $2`
-	rows, err := db.Query(query, productUUID, adminId)
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Lock the row so a concurrent update or delete waits for this one
+		query := `SELECT ` + productColumns + ` FROM products WHERE UUID = $1 AND admin_id = $2 FOR UPDATE`
+		err := scanProduct(tx.QueryRowContext(ctx, query, productUUID, adminId), &product)
+		if err == sql.ErrNoRows {
+			// If no product is found with the given UUID, return a custom error
+			return errors.New("product not found")
+		} else if err != nil {
+			return err
+		}
+
+		product.Name = productRequest.Name
+
+		if productRequest.Status != "" {
+			product.Status = productRequest.Status
+		}
+		product.PublishAt = productSchedule(product.PublishAt, productRequest.PublishAt)
+		product.UnpublishAt = productSchedule(product.UnpublishAt, productRequest.UnpublishAt)
+		if err := checkProductSchedule(product.PublishAt, product.UnpublishAt); err != nil {
+			return err
+		}
+
+		product.ImageFileHeader = productRequest.ImageFile
+		product.UpdatedAt = time.Now()
+
+		query = `
+			UPDATE products SET name = $1, updated_at = $2, status = $3, publish_at = $4, unpublish_at = $5
+			WHERE id = $6
+		`
+		_, err = tx.ExecContext(ctx, query, product.Name, product.UpdatedAt, product.Status, product.PublishAt, product.UnpublishAt, product.ID)
+		if err != nil {
+			return err
+		}
+
+		// A new image replaces the primary one in its place in the gallery,
+		// which also makes it the product's image. Deleting the old row
+		// queues its stored image for deletion.
+		if productRequest.ImageURL != "" {
+			var oldID, oldPosition int
+			query = `SELECT id, position FROM product_images WHERE product_id = $1 AND is_primary FOR UPDATE`
+			err := tx.QueryRowContext(ctx, query, product.ID).Scan(&oldID, &oldPosition)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+
+			image, err := addRequestImage(ctx, tx, product.ID, productRequest)
+			if err != nil {
+				return err
+			}
+			if oldID != 0 {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM product_images WHERE id = $1`, oldID); err != nil {
+					return err
+				}
+				query = `UPDATE product_images SET position = $1 WHERE id = $2`
+				if _, err := tx.ExecContext(ctx, query, oldPosition, image.ID); err != nil {
+					return err
+				}
+			}
+			product.ImageURL = productRequest.ImageURL
+		}
+
+		if err := recordEvent(ctx, tx, webhook.EventProductUpdated, product.UUID, product); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, product.ID, adminId, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	if !rows.Next() {
-		// No product found with the given UUID and adminId
-		return nil, errors.New("product not found")
+	product.Images, err = getImagesForProduct(ctx, db, product.ID)
+	if err != nil {
+		return nil, err
 	}
-	err = rows.Scan(&product.ID, &product.UUID, &product.Name, &product.ImageURL, &product.AdminID, &product.CreatedAt, &product.UpdatedAt)
+
+	product.Options, err = getOptionsForProduct(ctx, db, product.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	query = `DELETE FROM products WHERE uuid = $1 AND admin_id = $2`
-	_, err = db.Exec(query, productUUID, adminId)
+	return &product, nil
+}
+
+func DeleteProductService(ctx context.Context, db *sql.DB, productUUID string, adminId int) (*product.ProductResponse, error) {
+	var product product.ProductResponse
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Lock the row so it cannot change between the read and the delete
+		query := `SELECT ` + productColumns + ` FROM products WHERE uuid = $1 AND admin_id = $2 FOR UPDATE`
+		err := scanProduct(tx.QueryRowContext(ctx, query, productUUID, adminId), &product)
+		if err == sql.ErrNoRows {
+			// No product found with the given UUID and adminId
+			return errors.New("product not found")
+		} else if err != nil {
+			return err
+		}
+
+		query = `DELETE FROM products WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, product.ID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, tx, webhook.EventProductDeleted, product.UUID, product)
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	return &product, nil
 }
+
+// ApplyProductSchedulesService applies the schedule times that have come,
+// clearing each once it has been applied. A due publish_at publishes a
+// draft; a due unpublish_at archives a product that is published, or that
+// the same run publishes. Products archived by hand stay archived and drafts
+// are never archived, so those only lose the time. Schedule times are UTC, so
+// they are compared with the database clock in UTC. It returns the products
+// that changed.
+func ApplyProductSchedulesService(ctx context.Context, db *sql.DB) ([]product.ProductResponse, error) {
+	var products []product.ProductResponse
+
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		products = nil
+
+		query := `
+			UPDATE products SET
+				status = CASE
+					WHEN unpublish_at <= clock.now AND (status = 'published' OR (status = 'draft' AND publish_at <= clock.now)) THEN 'archived'
+					WHEN publish_at <= clock.now AND status = 'draft' THEN 'published'
+					ELSE status
+				END,
+				publish_at = CASE WHEN publish_at <= clock.now THEN NULL ELSE publish_at END,
+				unpublish_at = CASE WHEN unpublish_at <= clock.now THEN NULL ELSE unpublish_at END,
+				updated_at = CURRENT_TIMESTAMP
+			FROM (SELECT NOW() AT TIME ZONE 'UTC' AS now) clock
+			WHERE products.id IN (
+				SELECT id FROM products
+				WHERE publish_at <= NOW() AT TIME ZONE 'UTC' OR unpublish_at <= NOW() AT TIME ZONE 'UTC'
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + productColumns
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var productResponse product.ProductResponse
+			if err := scanProduct(rows, &productResponse); err != nil {
+				return err
+			}
+			products = append(products, productResponse)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, productResponse := range products {
+			if err := recordEvent(ctx, tx, webhook.EventProductUpdated, productResponse.UUID, productResponse); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/database"
+	"basic-trade-api/models/reservation"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const reservationColumns = `id, uuid, variant_id, quantity, status, reference, expires_at, created_at, updated_at`
+
+// ownedReservation keeps the reservations of variants of products owned by
+// admin $2, the same owner check ProductAuthorization makes.
+const ownedReservation = `variant_id IN (
+	SELECT variants.id FROM variants JOIN products ON products.id = variants.product_id WHERE products.admin_id = $2
+)`
+
+func scanReservation(row rowScanner, reservationResponse *reservation.ReservationResponse) error {
+	return row.Scan(&reservationResponse.ID, &reservationResponse.UUID, &reservationResponse.VariantID, &reservationResponse.Quantity, &reservationResponse.Status, &reservationResponse.Reference, &reservationResponse.ExpiresAt, &reservationResponse.CreatedAt, &reservationResponse.UpdatedAt)
+}
+
+// ReserveStockService holds stock of a variant of the admin's for a limited
+// time. The variant row is locked while availability is checked so
+// concurrent reservations are serialised and cannot oversell.
+func ReserveStockService(ctx context.Context, db *sql.DB, variantUUID string, reservationReq reservation.ReservationRequest, adminId int) (*reservation.ReservationResponse, error) {
+	ttl := configs.EnvReservationTTL()
+	if reservationReq.TTLSeconds > 0 {
+		ttl = time.Duration(reservationReq.TTLSeconds) * time.Second
+	}
+
+	var reservationResponse reservation.ReservationResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var variantID, onHand int
+		query := `
+			SELECT variants.id, variants.quantity FROM variants JOIN products ON products.id = variants.product_id
+			WHERE variants.uuid = $1 AND products.admin_id = $2
+			FOR UPDATE OF variants
+		`
+		err := tx.QueryRowContext(ctx, query, variantUUID, adminId).Scan(&variantID, &onHand)
+		if err == sql.ErrNoRows {
+			return errors.New("variant not found")
+		} else if err != nil {
+			return err
+		}
+
+		var reserved int
+		query = `SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations WHERE variant_id = $1 AND status = 'held' AND expires_at > NOW()`
+		if err := tx.QueryRowContext(ctx, query, variantID).Scan(&reserved); err != nil {
+			return err
+		}
+		if onHand-reserved < reservationReq.Quantity {
+			return errors.New("insufficient stock")
+		}
+
+		query = `
+			INSERT INTO stock_reservations (variant_id, quantity, reference, expires_at)
+			VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+			RETURNING ` + reservationColumns
+		return scanReservation(tx.QueryRowContext(ctx, query, variantID, reservationReq.Quantity, nullString(reservationReq.Reference), int(ttl.Seconds())), &reservationResponse)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservationResponse, nil
+}
+
+func GetReservationByIDService(ctx context.Context, db *sql.DB, reservationUUID string, adminId int) (*reservation.ReservationResponse, error) {
+	var reservationResponse reservation.ReservationResponse
+
+	query := `SELECT ` + reservationColumns + ` FROM stock_reservations WHERE uuid = $1 AND ` + ownedReservation
+	err := scanReservation(db.QueryRowContext(ctx, query, reservationUUID, adminId), &reservationResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("reservation not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &reservationResponse, nil
+}
+
+// lockHeldReservation locks a reservation of the admin's for update and
+// checks that it can still be confirmed or released.
+func lockHeldReservation(ctx context.Context, tx *sql.Tx, reservationUUID string, adminId int) (*reservation.ReservationResponse, error) {
+	var reservationResponse reservation.ReservationResponse
+
+	// Expiry is checked against the database clock, the one that set expires_at
+	var lapsed bool
+	query := `SELECT ` + reservationColumns + `, expires_at <= NOW() FROM stock_reservations WHERE uuid = $1 AND ` + ownedReservation + ` FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, reservationUUID, adminId).Scan(&reservationResponse.ID, &reservationResponse.UUID, &reservationResponse.VariantID, &reservationResponse.Quantity, &reservationResponse.Status, &reservationResponse.Reference, &reservationResponse.ExpiresAt, &reservationResponse.CreatedAt, &reservationResponse.UpdatedAt, &lapsed)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("reservation not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if reservationResponse.Status == "expired" || (reservationResponse.Status == "held" && lapsed) {
+		return nil, errors.New("reservation expired")
+	}
+	if reservationResponse.Status != "held" {
+		return nil, errors.New("reservation is not held")
+	}
+	return &reservationResponse, nil
+}
+
+// ConfirmReservationService turns a hold into a permanent decrement of
+// on-hand stock, taken from the default warehouse first and then the others.
+func ConfirmReservationService(ctx context.Context, db *sql.DB, reservationUUID string, adminId int) (*reservation.ReservationResponse, error) {
+	var reservationResponse *reservation.ReservationResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		reservationResponse, err = lockHeldReservation(ctx, tx, reservationUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		query := `
+			SELECT s.warehouse_id, s.quantity
+			FROM warehouse_stocks s
+			JOIN warehouses w ON w.id = s.warehouse_id
+			WHERE s.variant_id = $1 AND s.quantity > 0
+			ORDER BY w.is_default DESC, s.warehouse_id
+			FOR UPDATE OF s
+		`
+		rows, err := tx.QueryContext(ctx, query, reservationResponse.VariantID)
+		if err != nil {
+			return err
+		}
+		type stockLevel struct{ warehouseID, quantity int }
+		var levels []stockLevel
+		for rows.Next() {
+			var level stockLevel
+			if err := rows.Scan(&level.warehouseID, &level.quantity); err != nil {
+				rows.Close()
+				return err
+			}
+			levels = append(levels, level)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		previousQuantity, err := variantQuantity(ctx, tx, reservationResponse.VariantID)
+		if err != nil {
+			return err
+		}
+
+		remaining := reservationResponse.Quantity
+		for _, level := range levels {
+			if remaining == 0 {
+				break
+			}
+			take := level.quantity
+			if take > remaining {
+				take = remaining
+			}
+			query = `UPDATE warehouse_stocks SET quantity = quantity - $1, updated_at = CURRENT_TIMESTAMP WHERE variant_id = $2 AND warehouse_id = $3`
+			if _, err := tx.ExecContext(ctx, query, take, reservationResponse.VariantID, level.warehouseID); err != nil {
+				return err
+			}
+			remaining -= take
+		}
+		if remaining > 0 {
+			// Stock was lowered by hand below what was reserved
+			return errors.New("insufficient stock")
+		}
+		if err := raiseStockChanged(ctx, tx, reservationResponse.VariantID, previousQuantity); err != nil {
+			return err
+		}
+
+		reservationResponse.Status = "confirmed"
+		reservationResponse.UpdatedAt = time.Now()
+		query = `UPDATE stock_reservations SET status = $1, updated_at = $2 WHERE id = $3`
+		_, err = tx.ExecContext(ctx, query, reservationResponse.Status, reservationResponse.UpdatedAt, reservationResponse.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservationResponse, nil
+}
+
+func ReleaseReservationService(ctx context.Context, db *sql.DB, reservationUUID string, adminId int) (*reservation.ReservationResponse, error) {
+	var reservationResponse *reservation.ReservationResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		reservationResponse, err = lockHeldReservation(ctx, tx, reservationUUID, adminId)
+		if err != nil {
+			return err
+		}
+
+		reservationResponse.Status = "released"
+		reservationResponse.UpdatedAt = time.Now()
+		query := `UPDATE stock_reservations SET status = $1, updated_at = $2 WHERE id = $3`
+		_, err = tx.ExecContext(ctx, query, reservationResponse.Status, reservationResponse.UpdatedAt, reservationResponse.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reservationResponse, nil
+}
+
+// ExpireReservationsService marks holds past their expiry as expired. Rows
+// locked by an in-flight confirm or release are skipped and picked up on the
+// next sweep.
+func ExpireReservationsService(ctx context.Context, db *sql.DB) (int64, error) {
+	query := `
+		UPDATE stock_reservations SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM stock_reservations
+			WHERE status = 'held' AND expires_at <= NOW()
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+	result, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// signingKeyLock is the advisory lock that keeps replicas from rotating the
+// signing keys at the same time
+const signingKeyLock = 7261543
+
+// LoadSigningKeysService returns every signing key that may still verify
+// tokens.
+func LoadSigningKeysService(ctx context.Context, db *sql.DB) ([]*helpers.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, active_from, expires_at
+		FROM jwt_keys
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY active_from
+	`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*helpers.SigningKey
+	for rows.Next() {
+		var kid, algorithm, privateKey string
+		var activeFrom time.Time
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&kid, &algorithm, &privateKey, &activeFrom, &expiresAt); err != nil {
+			return nil, err
+		}
+
+		var expires *time.Time
+		if expiresAt.Valid {
+			expires = &expiresAt.Time
+		}
+		key, err := helpers.ParseSigningKey(kid, algorithm, privateKey, activeFrom, expires)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKeysService adds a new signing key once the newest one is
+// older than every, or uses another algorithm than the configured one. The
+// new key becomes active after publishAhead, except for the very first key,
+// which is active at once. Older keys keep verifying for overlap after the
+// new key takes over, long enough for the tokens they signed to run out.
+func RotateSigningKeysService(ctx context.Context, db *sql.DB, algorithm string, every, publishAhead, overlap time.Duration) (bool, error) {
+	var rotated bool
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		rotated = false
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, signingKeyLock); err != nil {
+			return err
+		}
+
+		var newestAlgorithm string
+		var stale bool
+		query := `
+			SELECT algorithm, created_at <= NOW() - $1 * INTERVAL '1 second'
+			FROM jwt_keys
+			WHERE expires_at IS NULL
+			ORDER BY active_from DESC
+			LIMIT 1
+		`
+		err := tx.QueryRowContext(ctx, query, int(every.Seconds())).Scan(&newestAlgorithm, &stale)
+		first := err == sql.ErrNoRows
+		if err != nil && !first {
+			return err
+		}
+		if !first && !stale && newestAlgorithm == algorithm {
+			return nil
+		}
+
+		key, privatePEM, err := helpers.GenerateSigningKey(algorithm)
+		if err != nil {
+			return err
+		}
+		ahead := publishAhead
+		if first {
+			ahead = 0
+		}
+
+		var activeFrom time.Time
+		query = `
+			INSERT INTO jwt_keys (kid, algorithm, private_key, active_from)
+			VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+			RETURNING active_from
+		`
+		if err := tx.QueryRowContext(ctx, query, key.ID, key.Algorithm, privatePEM, int(ahead.Seconds())).Scan(&activeFrom); err != nil {
+			return err
+		}
+
+		query = `UPDATE jwt_keys SET expires_at = $1 + $2 * INTERVAL '1 second' WHERE expires_at IS NULL AND kid <> $3`
+		if _, err := tx.ExecContext(ctx, query, activeFrom, int(overlap.Seconds()), key.ID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM jwt_keys WHERE expires_at <= NOW()`); err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
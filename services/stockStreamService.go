@@ -0,0 +1,148 @@
+package services
+
+import (
+	"basic-trade-api/models/product"
+	"basic-trade-api/models/variant"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// stockSubscriberBuffer is how many changes a stream may fall behind
+	// before it is dropped
+	stockSubscriberBuffer = 64
+	// stockBacklogBatchSize is the most changes read per backlog query
+	stockBacklogBatchSize = 500
+)
+
+// StockHub fans stock changes out to the variant streams open on this
+// replica.
+type StockHub struct {
+	mu          sync.RWMutex
+	subscribers map[*StockSubscription]struct{}
+}
+
+// StockSubscription receives the changes of the products it was opened for,
+// or of every product if none were given, as long as its viewer may see the
+// product. C is closed when the subscriber falls too far behind; the client
+// is expected to reconnect and resume.
+type StockSubscription struct {
+	C        chan variant.StockChange
+	products map[string]bool
+	viewerID int
+}
+
+func NewStockHub() *StockHub {
+	return &StockHub{subscribers: make(map[*StockSubscription]struct{})}
+}
+
+// Subscribe opens a subscription for viewerID, the admin asking or zero for
+// the public, who only sees changes to published products and their own.
+func (h *StockHub) Subscribe(productUUIDs []string, viewerID int) *StockSubscription {
+	sub := &StockSubscription{
+		C:        make(chan variant.StockChange, stockSubscriberBuffer),
+		products: make(map[string]bool),
+		viewerID: viewerID,
+	}
+	for _, productUUID := range productUUIDs {
+		sub.products[productUUID] = true
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *StockHub) Unsubscribe(sub *StockSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// Publish hands change to every matching subscriber without blocking.
+func (h *StockHub) Publish(change variant.StockChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if !sub.Matches(change) {
+			continue
+		}
+		select {
+		case sub.C <- change:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
+
+func (s *StockSubscription) Matches(change variant.StockChange) bool {
+	if change.ProductStatus != product.StatusPublished && (s.viewerID == 0 || change.AdminID != s.viewerID) {
+		return false
+	}
+	return len(s.products) == 0 || s.products[change.ProductUUID]
+}
+
+// GetStockChangesSinceService returns up to stockBacklogBatchSize changes
+// after afterID, oldest first, optionally limited to some products. Each
+// carries its product's current status and owner; changes to products since
+// deleted have neither.
+func GetStockChangesSinceService(ctx context.Context, db *sql.DB, afterID int64, productUUIDs []string) ([]variant.StockChange, error) {
+	var changes []variant.StockChange
+
+	conditions := []string{`stock_changes.id > $1`}
+	args := []interface{}{afterID}
+	if len(productUUIDs) > 0 {
+		args = append(args, pq.Array(productUUIDs))
+		conditions = append(conditions, fmt.Sprintf(`stock_changes.product_uuid::text = ANY($%d)`, len(args)))
+	}
+	args = append(args, stockBacklogBatchSize)
+
+	query := `
+		SELECT stock_changes.id, stock_changes.variant_id, stock_changes.variant_uuid, stock_changes.product_uuid,
+			stock_changes.previous_quantity, stock_changes.quantity, stock_changes.created_at,
+			COALESCE(products.status, ''), COALESCE(products.admin_id, 0)
+		FROM stock_changes
+		LEFT JOIN products ON products.uuid = stock_changes.product_uuid
+		WHERE ` + strings.Join(conditions, ` AND `) + fmt.Sprintf(`
+		ORDER BY stock_changes.id
+		LIMIT $%d`, len(args))
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change variant.StockChange
+		if err := rows.Scan(&change.ID, &change.VariantID, &change.VariantUUID, &change.ProductUUID, &change.PreviousQuantity, &change.Quantity, &change.CreatedAt, &change.ProductStatus, &change.AdminID); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// PruneStockChangesService deletes changes older than retention. Streams can
+// only resume from changes that are still kept.
+func PruneStockChangesService(ctx context.Context, db *sql.DB, retention time.Duration) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM stock_changes WHERE created_at < NOW() - $1 * INTERVAL '1 second'`, int(retention.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/database"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// recoveryCodeCount is how many recovery codes an admin gets at a time
+const recoveryCodeCount = 10
+
+// TwoFactorStatus describes an admin's two-factor setup.
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	Pending                bool `json:"pending"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+func GetTwoFactorStatusService(ctx context.Context, db *sql.DB, adminID int) (*TwoFactorStatus, error) {
+	var status TwoFactorStatus
+	query := `
+		SELECT
+			a.totp_enabled_at IS NOT NULL,
+			a.totp_secret IS NOT NULL AND a.totp_enabled_at IS NULL,
+			(SELECT COUNT(*) FROM admin_recovery_codes r WHERE r.admin_id = a.id AND r.used_at IS NULL)
+		FROM admins a
+		WHERE a.id = $1
+	`
+	err := db.QueryRowContext(ctx, query, adminID).Scan(&status.Enabled, &status.Pending, &status.RecoveryCodesRemaining)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("admin not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// EnrollTwoFactorService starts two-factor setup with a fresh secret. It
+// takes effect only once ConfirmTwoFactorService sees a code from it, so a
+// setup abandoned halfway does not lock the admin out.
+func EnrollTwoFactorService(ctx context.Context, db *sql.DB, adminID int) (*helpers.TOTPEnrollment, error) {
+	var enrollment *helpers.TOTPEnrollment
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var email string
+		var enabled bool
+		query := `SELECT email, totp_enabled_at IS NOT NULL FROM admins WHERE id = $1 FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, adminID).Scan(&email, &enabled)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+		if enabled {
+			return errors.New("two-factor already enabled")
+		}
+
+		enrollment, err = helpers.NewTOTPEnrollment(configs.EnvTOTPIssuer(), email)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE admins SET totp_secret = $1, totp_last_step = 0 WHERE id = $2`, enrollment.Secret, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// ConfirmTwoFactorService turns two-factor on once code proves the admin's
+// app holds the enrolled secret, and returns their recovery codes. The codes
+// are only ever shown here and by RegenerateRecoveryCodesService.
+func ConfirmTwoFactorService(ctx context.Context, db *sql.DB, adminID int, code string) ([]string, error) {
+	var codes []string
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var secret sql.NullString
+		var enabled bool
+		var lastStep int64
+		query := `SELECT totp_secret, totp_enabled_at IS NOT NULL, totp_last_step FROM admins WHERE id = $1 FOR UPDATE`
+		err := tx.QueryRowContext(ctx, query, adminID).Scan(&secret, &enabled, &lastStep)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+		if enabled {
+			return errors.New("two-factor already enabled")
+		}
+		if !secret.Valid {
+			return errors.New("two-factor not enrolled")
+		}
+
+		step, ok := helpers.MatchTOTP(secret.String, code, lastStep)
+		if !ok {
+			return errors.New("invalid code")
+		}
+		query = `UPDATE admins SET totp_enabled_at = NOW(), totp_last_step = $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, query, step, adminID); err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(ctx, tx, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodesService replaces an admin's recovery codes, used or
+// not, after checking a code from their authenticator app.
+func RegenerateRecoveryCodesService(ctx context.Context, db *sql.DB, adminID int, code string) ([]string, error) {
+	var codes []string
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		secret, lastStep, err := lockTwoFactor(ctx, tx, adminID)
+		if err != nil {
+			return err
+		}
+
+		step, ok := helpers.MatchTOTP(secret, code, lastStep)
+		if !ok {
+			return errors.New("invalid code")
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE admins SET totp_last_step = $1 WHERE id = $2`, step, adminID); err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(ctx, tx, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactorService turns two-factor off for an admin who confirms
+// both their password and a current code or recovery code.
+func DisableTwoFactorService(ctx context.Context, db *sql.DB, adminID int, password, code string) error {
+	return database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var hashedPassword string
+		err := tx.QueryRowContext(ctx, `SELECT password FROM admins WHERE id = $1`, adminID).Scan(&hashedPassword)
+		if err == sql.ErrNoRows {
+			return errors.New("admin not found")
+		} else if err != nil {
+			return err
+		}
+		secret, lastStep, err := lockTwoFactor(ctx, tx, adminID)
+		if err != nil {
+			return err
+		}
+
+		if !helpers.ComparePassword([]byte(hashedPassword), []byte(password)) {
+			return errors.New("invalid password")
+		}
+		ok, err := checkSecondFactor(ctx, tx, adminID, secret, lastStep, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("invalid code")
+		}
+
+		query := `UPDATE admins SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, adminID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+			return err
+		}
+		// Challenges already handed out would otherwise lead nowhere
+		query = `UPDATE admin_tokens SET used_at = NOW() WHERE admin_id = $1 AND purpose = $2 AND used_at IS NULL`
+		_, err = tx.ExecContext(ctx, query, adminID, tokenLoginChallenge)
+		return err
+	})
+}
+
+// CreateLoginChallengeService issues the token an admin who passed the
+// password check trades, together with a code, for an access token.
+func CreateLoginChallengeService(ctx context.Context, db *sql.DB, adminID int) (string, error) {
+	var token string
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		token, err = issueAdminToken(ctx, tx, adminID, tokenLoginChallenge, configs.EnvLoginChallengeTTL())
+		return err
+	})
+	return token, err
+}
+
+// CompleteLoginChallengeService finishes a two-factor login. A wrong code
+// counts towards the same lockout as a wrong password; the challenge stays
+// usable until it expires, so a mistyped code can be retried.
+func CompleteLoginChallengeService(ctx context.Context, db *sql.DB, challengeToken, code string) (*admin.AdminResponse, error) {
+	var adminResponse admin.AdminResponse
+	var authenticated bool
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		authenticated = false
+
+		var tokenID int
+		var secret string
+		var lastStep int64
+		var locked bool
+		query := `
+			SELECT t.id, a.id, a.name, a.email, a.totp_secret, a.totp_last_step, COALESCE(a.locked_until > NOW(), false)
+			FROM admin_tokens t
+			JOIN admins a ON a.id = t.admin_id
+			WHERE t.token_hash = $1 AND t.purpose = $2 AND t.used_at IS NULL AND t.expires_at > NOW()
+				AND a.totp_enabled_at IS NOT NULL AND a.deactivated_at IS NULL
+			FOR UPDATE OF t, a
+		`
+		err := tx.QueryRowContext(ctx, query, helpers.HashToken(challengeToken), tokenLoginChallenge).Scan(&tokenID, &adminResponse.ID, &adminResponse.Name, &adminResponse.Email, &secret, &lastStep, &locked)
+		if err == sql.ErrNoRows {
+			return errors.New("invalid or expired challenge")
+		} else if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		ok, err := checkSecondFactor(ctx, tx, adminResponse.ID, secret, lastStep, code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return recordLoginFailure(ctx, tx, adminResponse.ID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE admin_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
+			return err
+		}
+		if err := clearLoginFailures(ctx, tx, adminResponse.ID); err != nil {
+			return err
+		}
+		authenticated = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !authenticated {
+		return nil, errors.New("invalid code")
+	}
+
+	adminResponse.TwoFactorEnabled = true
+	return &adminResponse, nil
+}
+
+// lockTwoFactor locks the admin row and returns their TOTP secret and last
+// used step, failing if two-factor is not on.
+func lockTwoFactor(ctx context.Context, tx *sql.Tx, adminID int) (string, int64, error) {
+	var secret sql.NullString
+	var lastStep int64
+	query := `SELECT totp_secret, totp_last_step FROM admins WHERE id = $1 AND totp_enabled_at IS NOT NULL FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, adminID).Scan(&secret, &lastStep)
+	if err == sql.ErrNoRows || (err == nil && !secret.Valid) {
+		return "", 0, errors.New("two-factor not enabled")
+	} else if err != nil {
+		return "", 0, err
+	}
+	return secret.String, lastStep, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code,
+// using either up.
+func checkSecondFactor(ctx context.Context, tx *sql.Tx, adminID int, secret string, lastStep int64, code string) (bool, error) {
+	if step, ok := helpers.MatchTOTP(secret, code, lastStep); ok {
+		_, err := tx.ExecContext(ctx, `UPDATE admins SET totp_last_step = $1 WHERE id = $2`, step, adminID)
+		return err == nil, err
+	}
+
+	query := `UPDATE admin_recovery_codes SET used_at = NOW() WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := tx.ExecContext(ctx, query, adminID, helpers.HashToken(helpers.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	used, err := result.RowsAffected()
+	return used == 1, err
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, adminID int) ([]string, error) {
+	codes, err := helpers.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		query := `INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, adminID, helpers.HashToken(code)); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
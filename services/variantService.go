@@ -1,94 +1,272 @@
 package services
 
 import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/notification"
 	"basic-trade-api/models/variant"
+	"basic-trade-api/models/webhook"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
-func CreateVariantService(db *sql.DB, variantReq variant.VariantRequest, adminId int) (*variant.VariantResponse, error) {
-	var variantResponse variant.VariantResponse
-	query := `INSERT INTO variants (variant_name, quantity, product_id) VALUES ($1, $2, $3) RETURNING id, uuid, variant_name, quantity, product_id, created_at, updated_at`
-	err := db.QueryRow(query, variantReq.VariantName, variantReq.Quantity, variantReq.ProductID).Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
+// variantColumns is the column list scanned by scanVariant. The reserved
+// quantity only counts holds that have not yet expired, so availability is
+// correct even before the sweeper has released them. Option values come as
+// one JSON object of option name to value.
+const variantColumns = `variants.id, variants.uuid, variants.variant_name, variants.sku, variants.barcode, variants.quantity,
+	COALESCE((SELECT SUM(r.quantity) FROM stock_reservations r WHERE r.variant_id = variants.id AND r.status = 'held' AND r.expires_at > NOW()), 0),
+	variants.reorder_point, variants.product_id,
+	COALESCE((SELECT jsonb_object_agg(o.name, ov.value) FROM variant_option_values vo
+		JOIN product_options o ON o.id = vo.option_id JOIN product_option_values ov ON ov.id = vo.value_id
+		WHERE vo.variant_id = variants.id), '{}'),
+	variants.created_at, variants.updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVariant(row rowScanner, variantResponse *variant.VariantResponse) error {
+	var options []byte
+	err := row.Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.SKU, &variantResponse.Barcode, &variantResponse.Quantity, &variantResponse.Reserved, &variantResponse.ReorderPoint, &variantResponse.ProductID, &options, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &variantResponse, nil
+	variantResponse.Available = variantResponse.Quantity - variantResponse.Reserved
+	return json.Unmarshal(options, &variantResponse.Options)
 }
 
-func GetAllVariantService(db *sql.DB, pageSize int, offset int, variantName string) ([]variant.VariantResponse, int, error) {
-	var variants []variant.VariantResponse
-	var total int
+// nullString stores empty optional fields as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
 
-	// Construct the base query
-	baseQuery := `SELECT COUNT(*) FROM variants`
-	err := db.QueryRow(baseQuery).Scan(&total)
+// variantNotice is the dashboard notification payload for a variant change.
+func variantNotice(v variant.VariantResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"variantUuid": v.UUID,
+		"variantName": v.VariantName,
+		"sku":         v.SKU,
+		"productId":   v.ProductID,
+		"quantity":    v.Quantity,
+	}
+}
+
+// checkVariantUnique rejects a SKU or barcode already used by another variant.
+// Barcodes are compared as GTIN-14 so a UPC-A and its EAN-13 form collide.
+// It only answers early: a concurrent request can still take the SKU or
+// barcode before the write, which variantUniqueError then reports the same
+// way.
+func checkVariantUnique(ctx context.Context, db *sql.DB, variantReq variant.VariantRequest, variantUUID string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM variants WHERE sku = $1 AND uuid::text <> $2`
+	err := db.QueryRowContext(ctx, query, variantReq.SKU, variantUUID).Scan(&count)
 	if err != nil {
-		return nil, 0, err
+		return err
+	}
+	if count > 0 {
+		return errors.New("sku already exists")
 	}
 
-	baseQuery = `SELECT id, uuid, variant_name, quantity, product_id, created_at, updated_at FROM variants`
+	if variantReq.Barcode == "" {
+		return nil
+	}
+	query = `SELECT COUNT(*) FROM variants WHERE LPAD(barcode, 14, '0') = LPAD($1, 14, '0') AND uuid::text <> $2`
+	err = db.QueryRowContext(ctx, query, variantReq.Barcode, variantUUID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("barcode already exists")
+	}
+	return nil
+}
 
-	// Add WHERE clause if variantName is provided
-	if variantName != "" {
-		variantName = "%" + variantName + "%"
-		baseQuery += ` WHERE variant_name ILIKE $1`
-		baseQuery += ` LIMIT $2 OFFSET $3`
+// variantUniqueError turns a write refused by the SKU or barcode unique
+// indexes into the error checkVariantUnique gives.
+func variantUniqueError(err error) error {
+	switch database.UniqueViolation(err) {
+	case "uq_variant_sku":
+		return errors.New("sku already exists")
+	case "uq_variant_barcode":
+		return errors.New("barcode already exists")
+	}
+	return err
+}
 
-		// Execute the query
-		rows, err := db.Query(baseQuery, variantName, pageSize, offset)
+func CreateVariantService(ctx context.Context, db *sql.DB, variantReq variant.VariantRequest, adminId int) (*variant.VariantResponse, error) {
+	if err := checkVariantUnique(ctx, db, variantReq, ""); err != nil {
+		return nil, err
+	}
+
+	var variantResponse variant.VariantResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		warehouseID, err := resolveWarehouseID(ctx, tx, variantReq.WarehouseID)
 		if err != nil {
-			return nil, 0, err
+			return err
+		}
+
+		var variantID int
+		query := `INSERT INTO variants (variant_name, sku, barcode, quantity, product_id) VALUES ($1, $2, $3, 0, $4) RETURNING id`
+		err = tx.QueryRowContext(ctx, query, variantReq.VariantName, variantReq.SKU, nullString(variantReq.Barcode), variantReq.ProductID).Scan(&variantID)
+		if err != nil {
+			return err
 		}
-		defer rows.Close()
 
-		// Process the query results
-		for rows.Next() {
-			var variantResponse variant.VariantResponse
-			err := rows.Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
+		// The quantity column is filled in by the warehouse_stocks trigger
+		if err := setStock(ctx, tx, variantID, warehouseID, variantReq.Quantity); err != nil {
+			return err
+		}
+
+		// Set after the initial stock so a variant created below its reorder
+		// point still raises an alert
+		if variantReq.ReorderPoint != nil {
+			_, err = tx.ExecContext(ctx, `UPDATE variants SET reorder_point = $1 WHERE id = $2`, *variantReq.ReorderPoint, variantID)
 			if err != nil {
-				return nil, 0, err
+				return err
 			}
-			variants = append(variants, variantResponse)
 		}
-		if err = rows.Err(); err != nil {
-			return nil, 0, err
+
+		if len(variantReq.Options) > 0 {
+			if err := setVariantOptions(ctx, tx, variantID, variantReq.ProductID, variantReq.Options); err != nil {
+				return err
+			}
 		}
-	} else {
-		baseQuery += ` LIMIT $1 OFFSET $2`
 
-		// Execute the query
-		rows, err := db.Query(baseQuery, pageSize, offset)
-		if err != nil {
-			return nil, 0, err
+		query = `SELECT ` + variantColumns + ` FROM variants WHERE id = $1`
+		if err := scanVariant(tx.QueryRowContext(ctx, query, variantID), &variantResponse); err != nil {
+			return err
 		}
-		defer rows.Close()
 
-		// Process the query results
-		for rows.Next() {
-			var variantResponse variant.VariantResponse
-			err := rows.Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
-			if err != nil {
-				return nil, 0, err
-			}
-			variants = append(variants, variantResponse)
+		if err := recordEvent(ctx, tx, webhook.EventVariantCreated, variantResponse.UUID, variantResponse); err != nil {
+			return err
+		}
+		if err := recordProductRevision(ctx, tx, variantResponse.ProductID, adminId, nil); err != nil {
+			return err
+		}
+		return notifyProductOwner(ctx, tx, variantResponse.ProductID, adminId, notification.TypeVariantCreated, variantNotice(variantResponse))
+	})
+	if err != nil {
+		return nil, variantUniqueError(err)
+	}
+	return &variantResponse, nil
+}
+
+// variantVisibility is the condition, on variants joined with their
+// products, that keeps the variants a viewer may see: those of published
+// products and, for an admin, all of their own. Placeholder $n holds the
+// viewer's admin ID, zero for the public.
+func variantVisibility(n int) string {
+	return fmt.Sprintf(`(products.status = 'published' OR products.admin_id = $%d)`, n)
+}
+
+// variantFilter builds the WHERE clause for listing variants joined with
+// their products. Placeholders are numbered from 1.
+func variantFilter(filter variant.VariantFilter) (string, []interface{}) {
+	args := []interface{}{filter.ViewerID}
+	conditions := []string{variantVisibility(1)}
+
+	if filter.VariantName != "" {
+		args = append(args, "%"+filter.VariantName+"%")
+		conditions = append(conditions, fmt.Sprintf(`variants.variant_name ILIKE $%d`, len(args)))
+	}
+
+	if filter.Warehouse != "" {
+		args = append(args, filter.Warehouse)
+		stocked := fmt.Sprintf(`SELECT 1 FROM warehouse_stocks s JOIN warehouses w ON w.id = s.warehouse_id WHERE s.variant_id = variants.id AND w.code = $%d`, len(args))
+		switch {
+		case filter.InStock == nil:
+			conditions = append(conditions, `EXISTS (`+stocked+`)`)
+		case *filter.InStock:
+			conditions = append(conditions, `EXISTS (`+stocked+` AND s.quantity > 0)`)
+		default:
+			conditions = append(conditions, `NOT EXISTS (`+stocked+` AND s.quantity > 0)`)
+		}
+	} else if filter.InStock != nil {
+		if *filter.InStock {
+			conditions = append(conditions, `variants.quantity > 0`)
+		} else {
+			conditions = append(conditions, `variants.quantity = 0`)
 		}
-		if err = rows.Err(); err != nil {
+	}
+
+	// Sorted so the same filter always builds the same query
+	names := make([]string, 0, len(filter.Options))
+	for name := range filter.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := make([]string, len(filter.Options[name]))
+		for i, value := range filter.Options[name] {
+			values[i] = strings.ToLower(value)
+		}
+		args = append(args, name, pq.Array(values))
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM variant_option_values vo
+			JOIN product_options o ON o.id = vo.option_id JOIN product_option_values ov ON ov.id = vo.value_id
+			WHERE vo.variant_id = variants.id AND LOWER(o.name) = LOWER($%d) AND LOWER(ov.value) = ANY($%d)
+		)`, len(args)-1, len(args)))
+	}
+
+	return ` WHERE ` + strings.Join(conditions, ` AND `), args
+}
+
+func GetAllVariantService(ctx context.Context, db *sql.DB, pageSize int, offset int, filter variant.VariantFilter) ([]variant.VariantResponse, int, error) {
+	var variants []variant.VariantResponse
+	var total int
+
+	where, args := variantFilter(filter)
+
+	// Construct the base query
+	baseQuery := `SELECT COUNT(*) FROM variants JOIN products ON products.id = variants.product_id` + where
+	err := db.QueryRowContext(ctx, baseQuery, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	baseQuery = `SELECT ` + variantColumns + ` FROM variants JOIN products ON products.id = variants.product_id` + where
+	baseQuery += fmt.Sprintf(` ORDER BY variants.id LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+
+	// Execute the query
+	rows, err := db.QueryContext(ctx, baseQuery, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Process the query results
+	for rows.Next() {
+		var variantResponse variant.VariantResponse
+		err := scanVariant(rows, &variantResponse)
+		if err != nil {
 			return nil, 0, err
 		}
+		variants = append(variants, variantResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
 	}
 
 	return variants, total, nil
 }
 
-
-func GetVariantByIDService(db *sql.DB, variantUUID string) (*variant.VariantResponse, error) {
+// GetVariantByIDService finds a variant viewerID may see, as
+// variantVisibility decides.
+func GetVariantByIDService(ctx context.Context, db *sql.DB, variantUUID string, viewerID int) (*variant.VariantResponse, error) {
 	var variantResponse variant.VariantResponse
 
-	query := `SELECT id, uuid, variant_name, quantity, product_id, created_at, updated_at FROM variants WHERE uuid = $1`
-	err := db.QueryRow(query, variantUUID).Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
+	query := `SELECT ` + variantColumns + ` FROM variants JOIN products ON products.id = variants.product_id
+		WHERE variants.uuid = $1 AND ` + variantVisibility(2)
+	err := scanVariant(db.QueryRowContext(ctx, query, variantUUID, viewerID), &variantResponse)
 	if err == sql.ErrNoRows {
 		// If no product is found with the given UUID, return a custom error
 		return nil, errors.New("variant not found")
@@ -98,52 +276,139 @@ func GetVariantByIDService(db *sql.DB, variantUUID string) (*variant.VariantResp
 	return &variantResponse, nil
 }
 
-func UpdateVariantService(db *sql.DB, variantRequest variant.VariantRequest, variantUUID string, adminId int) (*variant.VariantResponse, error) {
-    var variantResponse variant.VariantResponse
-    query := `SELECT id, uuid, variant_name, quantity, product_id, created_at, updated_at FROM variants WHERE uuid = $1`
-    err := db.QueryRow(query, variantUUID).Scan(&variantResponse.ID, &variantResponse.UUID, &variantResponse.VariantName, &variantResponse.Quantity, &variantResponse.ProductID, &variantResponse.CreatedAt, &variantResponse.UpdatedAt)
-    if err == sql.ErrNoRows {
-        // If no product is found with the given UUID, return a custom error
-        return nil, errors.New("variant not found")
-    } else if err != nil {
-        return nil, err
-    }
-
-    // Update variant details with new values
-    variantResponse.VariantName = variantRequest.VariantName
-    variantResponse.Quantity = variantRequest.Quantity
-    variantResponse.ProductID = variantRequest.ProductID
-    variantResponse.UpdatedAt = time.Now()
-
-    query = `UPDATE variants SET variant_name = $1, quantity = $2, product_id = $3, updated_at = $4 WHERE uuid = $5`
-    fmt.Printf("Query: %s\nParams: %v\n", query, []interface{}{variantResponse.VariantName, variantResponse.Quantity, variantResponse.ProductID, variantResponse.UpdatedAt, variantUUID})
-    _, err = db.Exec(query, variantResponse.VariantName, variantResponse.Quantity, variantResponse.ProductID, variantResponse.UpdatedAt, variantUUID)
-    if err != nil {
-        return nil, err
-    }
-
-    return &variantResponse, nil
+// GetVariantByLookupService finds a variant by exact SKU or by barcode, so
+// scanners may send any GTIN length that pads to the stored code. Only
+// variants viewerID may see are found.
+func GetVariantByLookupService(ctx context.Context, db *sql.DB, sku, barcode string, viewerID int) (*variant.VariantResponse, error) {
+	var variantResponse variant.VariantResponse
+
+	query := `SELECT ` + variantColumns + ` FROM variants JOIN products ON products.id = variants.product_id WHERE `
+	var row *sql.Row
+	if sku != "" {
+		query += `variants.sku = $1 AND ` + variantVisibility(2)
+		row = db.QueryRowContext(ctx, query, sku, viewerID)
+	} else {
+		query += `LPAD(variants.barcode, 14, '0') = LPAD($1, 14, '0') AND ` + variantVisibility(2)
+		row = db.QueryRowContext(ctx, query, barcode, viewerID)
+	}
+
+	err := scanVariant(row, &variantResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("variant not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &variantResponse, nil
 }
 
-func DeleteVariantService(db *sql.DB, variantUUID string, adminId int) error {
-    query := `SELECT id FROM variants WHERE uuid = $1`
-    var variantID int
-    err := db.QueryRow(query, variantUUID).Scan(&variantID)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            // Variant not found
-            return errors.New("variant not found")
-        }
-        // Other error occurred
-        return err
-    }
-
-    // Delete the variant
-    deleteQuery := `DELETE FROM variants WHERE uuid = $1`
-    _, err = db.Exec(deleteQuery, variantUUID)
-    if err != nil {
-        return err
-    }
-
-    return nil
-}
\ No newline at end of file
+func UpdateVariantService(ctx context.Context, db *sql.DB, variantRequest variant.VariantRequest, variantUUID string, adminId int) (*variant.VariantResponse, error) {
+	var variantResponse variant.VariantResponse
+	query := `SELECT ` + variantColumns + ` FROM variants WHERE uuid = $1`
+	err := scanVariant(db.QueryRowContext(ctx, query, variantUUID), &variantResponse)
+	if err == sql.ErrNoRows {
+		// If no product is found with the given UUID, return a custom error
+		return nil, errors.New("variant not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := checkVariantUnique(ctx, db, variantRequest, variantUUID); err != nil {
+		return nil, err
+	}
+
+	previousQuantity := variantResponse.Quantity
+	previousProductID := variantResponse.ProductID
+
+	// Update variant details with new values
+	variantResponse.VariantName = variantRequest.VariantName
+	variantResponse.SKU = variantRequest.SKU
+	variantResponse.Barcode = nil
+	if variantRequest.Barcode != "" {
+		variantResponse.Barcode = &variantRequest.Barcode
+	}
+	variantResponse.ReorderPoint = variantRequest.ReorderPoint
+	variantResponse.ProductID = variantRequest.ProductID
+	variantResponse.UpdatedAt = time.Now()
+
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		warehouseID, err := resolveWarehouseID(ctx, tx, variantRequest.WarehouseID)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE variants SET variant_name = $1, sku = $2, barcode = $3, reorder_point = $4, product_id = $5, updated_at = $6 WHERE uuid = $7`
+		_, err = tx.ExecContext(ctx, query, variantResponse.VariantName, variantResponse.SKU, nullString(variantRequest.Barcode), variantRequest.ReorderPoint, variantResponse.ProductID, variantResponse.UpdatedAt, variantUUID)
+		if err != nil {
+			return err
+		}
+
+		// Quantity is the stock in the given warehouse; the variant total is
+		// recomputed by the warehouse_stocks trigger
+		if err := setStock(ctx, tx, variantResponse.ID, warehouseID, variantRequest.Quantity); err != nil {
+			return err
+		}
+
+		// Option values belong to the product's options, so a variant moved
+		// to another product loses them unless new ones are given
+		if variantRequest.Options != nil {
+			err = setVariantOptions(ctx, tx, variantResponse.ID, variantResponse.ProductID, variantRequest.Options)
+		} else if previousProductID != variantResponse.ProductID {
+			err = writeVariantOptions(ctx, tx, variantResponse.ID, nil)
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `SELECT ` + variantColumns + ` FROM variants WHERE id = $1`
+		if err := scanVariant(tx.QueryRowContext(ctx, query, variantResponse.ID), &variantResponse); err != nil {
+			return err
+		}
+
+		if err := recordEvent(ctx, tx, webhook.EventVariantUpdated, variantResponse.UUID, variantResponse); err != nil {
+			return err
+		}
+		if err := raiseStockChanged(ctx, tx, variantResponse.ID, previousQuantity); err != nil {
+			return err
+		}
+		// A variant moved to another product changes both histories
+		if err := recordProductRevision(ctx, tx, variantResponse.ProductID, adminId, nil); err != nil {
+			return err
+		}
+		if previousProductID != variantResponse.ProductID {
+			if err := recordProductRevision(ctx, tx, previousProductID, adminId, nil); err != nil {
+				return err
+			}
+		}
+		// Only the owner may update a variant, so the only change another
+		// admin sees is a variant moved into one of their products
+		if previousProductID == variantResponse.ProductID {
+			return nil
+		}
+		return notifyProductOwner(ctx, tx, variantResponse.ProductID, adminId, notification.TypeVariantUpdated, variantNotice(variantResponse))
+	})
+	if err != nil {
+		return nil, variantUniqueError(err)
+	}
+
+	return &variantResponse, nil
+}
+
+func DeleteVariantService(ctx context.Context, db *sql.DB, variantUUID string, adminId int) error {
+	deletedVariant, err := GetVariantByIDService(ctx, db, variantUUID, adminId)
+	if err != nil {
+		return err
+	}
+
+	return database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Delete the variant
+		deleteQuery := `DELETE FROM variants WHERE uuid = $1`
+		if _, err := tx.ExecContext(ctx, deleteQuery, variantUUID); err != nil {
+			return err
+		}
+
+		if err := recordEvent(ctx, tx, webhook.EventVariantDeleted, deletedVariant.UUID, deletedVariant); err != nil {
+			return err
+		}
+		return recordProductRevision(ctx, tx, deletedVariant.ProductID, adminId, nil)
+	})
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"basic-trade-api/database"
+	"basic-trade-api/models/warehouse"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// queryRower is satisfied by *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func scanWarehouse(row rowScanner, warehouseResponse *warehouse.WarehouseResponse) error {
+	return row.Scan(&warehouseResponse.ID, &warehouseResponse.UUID, &warehouseResponse.Name, &warehouseResponse.Code, &warehouseResponse.IsDefault, &warehouseResponse.CreatedAt, &warehouseResponse.UpdatedAt)
+}
+
+func CreateWarehouseService(ctx context.Context, db *sql.DB, warehouseReq warehouse.WarehouseRequest) (*warehouse.WarehouseResponse, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM warehouses WHERE code = $1`, warehouseReq.Code).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("warehouse code already exists")
+	}
+
+	var warehouseResponse warehouse.WarehouseResponse
+	query := `INSERT INTO warehouses (name, code) VALUES ($1, $2) RETURNING id, uuid, name, code, is_default, created_at, updated_at`
+	err = scanWarehouse(db.QueryRowContext(ctx, query, warehouseReq.Name, warehouseReq.Code), &warehouseResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &warehouseResponse, nil
+}
+
+func GetAllWarehouseService(ctx context.Context, db *sql.DB) ([]warehouse.WarehouseResponse, error) {
+	var warehouses []warehouse.WarehouseResponse
+
+	query := `SELECT id, uuid, name, code, is_default, created_at, updated_at FROM warehouses ORDER BY id`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var warehouseResponse warehouse.WarehouseResponse
+		if err := scanWarehouse(rows, &warehouseResponse); err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouseResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return warehouses, nil
+}
+
+func GetWarehouseByIDService(ctx context.Context, db *sql.DB, warehouseUUID string) (*warehouse.WarehouseResponse, error) {
+	var warehouseResponse warehouse.WarehouseResponse
+
+	query := `SELECT id, uuid, name, code, is_default, created_at, updated_at FROM warehouses WHERE uuid = $1`
+	err := scanWarehouse(db.QueryRowContext(ctx, query, warehouseUUID), &warehouseResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("warehouse not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &warehouseResponse, nil
+}
+
+func UpdateWarehouseService(ctx context.Context, db *sql.DB, warehouseReq warehouse.WarehouseRequest, warehouseUUID string) (*warehouse.WarehouseResponse, error) {
+	warehouseResponse, err := GetWarehouseByIDService(ctx, db, warehouseUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM warehouses WHERE code = $1 AND id <> $2`, warehouseReq.Code, warehouseResponse.ID).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errors.New("warehouse code already exists")
+	}
+
+	warehouseResponse.Name = warehouseReq.Name
+	warehouseResponse.Code = warehouseReq.Code
+	warehouseResponse.UpdatedAt = time.Now()
+
+	query := `UPDATE warehouses SET name = $1, code = $2, updated_at = $3 WHERE id = $4`
+	_, err = db.ExecContext(ctx, query, warehouseResponse.Name, warehouseResponse.Code, warehouseResponse.UpdatedAt, warehouseResponse.ID)
+	if err != nil {
+		return nil, err
+	}
+	return warehouseResponse, nil
+}
+
+// resolveWarehouseID returns warehouseID if it exists, or the default
+// warehouse when warehouseID is zero.
+func resolveWarehouseID(ctx context.Context, q queryRower, warehouseID int) (int, error) {
+	var err error
+	if warehouseID == 0 {
+		err = q.QueryRowContext(ctx, `SELECT id FROM warehouses WHERE is_default`).Scan(&warehouseID)
+	} else {
+		err = q.QueryRowContext(ctx, `SELECT id FROM warehouses WHERE id = $1`, warehouseID).Scan(&warehouseID)
+	}
+	if err == sql.ErrNoRows {
+		return 0, errors.New("warehouse not found")
+	}
+	return warehouseID, err
+}
+
+// setStock sets the on-hand quantity of a variant in one warehouse. The
+// variant total is recomputed by the warehouse_stocks trigger.
+func setStock(ctx context.Context, tx *sql.Tx, variantID, warehouseID, quantity int) error {
+	query := `
+		INSERT INTO warehouse_stocks (warehouse_id, variant_id, quantity)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (warehouse_id, variant_id)
+		DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := tx.ExecContext(ctx, query, warehouseID, variantID, quantity)
+	return err
+}
+
+func variantIDByUUID(ctx context.Context, q queryRower, variantUUID string) (int, error) {
+	var variantID int
+	err := q.QueryRowContext(ctx, `SELECT id FROM variants WHERE uuid = $1`, variantUUID).Scan(&variantID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("variant not found")
+	}
+	return variantID, err
+}
+
+// GetVariantStockService lists the stock of a variant viewerID may see, as
+// variantVisibility decides, in every warehouse.
+func GetVariantStockService(ctx context.Context, db *sql.DB, variantUUID string, viewerID int) ([]warehouse.StockResponse, error) {
+	var variantID int
+	query := `SELECT variants.id FROM variants JOIN products ON products.id = variants.product_id
+		WHERE variants.uuid = $1 AND ` + variantVisibility(2)
+	err := db.QueryRowContext(ctx, query, variantUUID, viewerID).Scan(&variantID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("variant not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return getStocksForVariant(ctx, db, variantID)
+}
+
+func getStocksForVariant(ctx context.Context, db *sql.DB, variantID int) ([]warehouse.StockResponse, error) {
+	var stocks []warehouse.StockResponse
+	query := `
+		SELECT w.id, w.uuid, w.code, w.name, s.quantity, s.updated_at
+		FROM warehouse_stocks s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.variant_id = $1
+		ORDER BY w.id
+	`
+	rows, err := db.QueryContext(ctx, query, variantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stock warehouse.StockResponse
+		err := rows.Scan(&stock.WarehouseID, &stock.WarehouseUUID, &stock.WarehouseCode, &stock.WarehouseName, &stock.Quantity, &stock.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+func SetVariantStockService(ctx context.Context, db *sql.DB, variantUUID string, stockReq warehouse.StockRequest) ([]warehouse.StockResponse, error) {
+	var variantID int
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		variantID, err = variantIDByUUID(ctx, tx, variantUUID)
+		if err != nil {
+			return err
+		}
+		warehouseID, err := resolveWarehouseID(ctx, tx, stockReq.WarehouseID)
+		if err != nil {
+			return err
+		}
+		previousQuantity, err := variantQuantity(ctx, tx, variantID)
+		if err != nil {
+			return err
+		}
+		if err := setStock(ctx, tx, variantID, warehouseID, stockReq.Quantity); err != nil {
+			return err
+		}
+		return raiseStockChanged(ctx, tx, variantID, previousQuantity)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getStocksForVariant(ctx, db, variantID)
+}
+
+// TransferStockService moves stock of a variant between two warehouses. Both
+// stock rows are locked in a fixed order so concurrent transfers cannot
+// deadlock or drive the source below zero.
+func TransferStockService(ctx context.Context, db *sql.DB, variantUUID string, transferReq warehouse.TransferRequest, adminId int) (*warehouse.TransferResponse, error) {
+	var transfer warehouse.TransferResponse
+	err := database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		variantID, err := variantIDByUUID(ctx, tx, variantUUID)
+		if err != nil {
+			return err
+		}
+		if _, err := resolveWarehouseID(ctx, tx, transferReq.FromWarehouseID); err != nil {
+			return err
+		}
+		if _, err := resolveWarehouseID(ctx, tx, transferReq.ToWarehouseID); err != nil {
+			return err
+		}
+
+		query := `
+			SELECT warehouse_id, quantity FROM warehouse_stocks
+			WHERE variant_id = $1 AND warehouse_id IN ($2, $3)
+			ORDER BY warehouse_id
+			FOR UPDATE
+		`
+		rows, err := tx.QueryContext(ctx, query, variantID, transferReq.FromWarehouseID, transferReq.ToWarehouseID)
+		if err != nil {
+			return err
+		}
+		available := 0
+		for rows.Next() {
+			var warehouseID, quantity int
+			if err := rows.Scan(&warehouseID, &quantity); err != nil {
+				rows.Close()
+				return err
+			}
+			if warehouseID == transferReq.FromWarehouseID {
+				available = quantity
+			}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		rows.Close()
+
+		if available < transferReq.Quantity {
+			return errors.New("insufficient stock")
+		}
+
+		query = `UPDATE warehouse_stocks SET quantity = quantity - $1, updated_at = CURRENT_TIMESTAMP WHERE variant_id = $2 AND warehouse_id = $3`
+		if _, err := tx.ExecContext(ctx, query, transferReq.Quantity, variantID, transferReq.FromWarehouseID); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO warehouse_stocks (warehouse_id, variant_id, quantity)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (warehouse_id, variant_id)
+			DO UPDATE SET quantity = warehouse_stocks.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP
+		`
+		if _, err := tx.ExecContext(ctx, query, transferReq.ToWarehouseID, variantID, transferReq.Quantity); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO stock_transfers (variant_id, from_warehouse_id, to_warehouse_id, quantity, admin_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, uuid, variant_id, from_warehouse_id, to_warehouse_id, quantity, admin_id, created_at
+		`
+		return tx.QueryRowContext(ctx, query, variantID, transferReq.FromWarehouseID, transferReq.ToWarehouseID, transferReq.Quantity, adminId).Scan(
+			&transfer.ID, &transfer.UUID, &transfer.VariantID, &transfer.FromWarehouseID, &transfer.ToWarehouseID, &transfer.Quantity, &transfer.AdminID, &transfer.CreatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/admin"
+	"basic-trade-api/models/outbox"
+	"basic-trade-api/models/webhook"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// webhookMaxAttempts is how many times a delivery is tried before it is
+	// marked failed
+	webhookMaxAttempts = 8
+	// webhookBaseBackoff is the wait after the first failure; it doubles on
+	// every further failure
+	webhookBaseBackoff = 30 * time.Second
+	// webhookBatchSize is the most deliveries sent per dispatcher tick
+	webhookBatchSize = 20
+	// webhookLease is how long claimed deliveries are held back from other
+	// dispatchers while they are sent; it outlasts a whole batch timing out
+	webhookLease = 5 * time.Minute
+)
+
+// webhookSubscriber is an active subscription listening for an event, with
+// the role of the admin who owns it.
+type webhookSubscriber struct {
+	ID      int
+	AdminID int
+	Role    string
+}
+
+// webhookRecipients picks the subscribers an event about a product owned by
+// ownerID goes to: the owner's own subscriptions and every superadmin's.
+// Events with no known owner only reach superadmins.
+func webhookRecipients(subscribers []webhookSubscriber, ownerID int) []int {
+	var ids []int
+	for _, sub := range subscribers {
+		if sub.Role == admin.RoleSuperadmin || (ownerID != 0 && sub.AdminID == ownerID) {
+			ids = append(ids, sub.ID)
+		}
+	}
+	return ids
+}
+
+// QueueWebhookDeliveriesService queues a delivery of an outbox event to
+// every active subscription that listens for it and may see it, as
+// webhookRecipients decides. It is safe to call again for the same event:
+// subscriptions that already have a delivery are skipped.
+func QueueWebhookDeliveriesService(ctx context.Context, db *sql.DB, event outbox.Event) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         event.UUID,
+		"event":      event.Type,
+		"occurredAt": event.CreatedAt.UTC(),
+		"data":       json.RawMessage(event.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	query := `
+		SELECT s.id, s.admin_id, a.role FROM webhook_subscriptions s
+		JOIN admins a ON a.id = s.admin_id
+		WHERE s.is_active AND $1 = ANY(s.events)
+	`
+	rows, err := db.QueryContext(ctx, query, event.Type)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var subscribers []webhookSubscriber
+	for rows.Next() {
+		var sub webhookSubscriber
+		if err := rows.Scan(&sub.ID, &sub.AdminID, &sub.Role); err != nil {
+			return err
+		}
+		subscribers = append(subscribers, sub)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	recipients := webhookRecipients(subscribers, event.AdminID)
+	if len(recipients) == 0 {
+		return nil
+	}
+	query = `
+		INSERT INTO webhook_deliveries (subscription_id, outbox_id, event, payload)
+		SELECT id, $1, $2, $3 FROM webhook_subscriptions
+		WHERE id = ANY($4)
+		ON CONFLICT (outbox_id, subscription_id) DO NOTHING
+	`
+	_, err = db.ExecContext(ctx, query, event.ID, event.Type, string(payload), pq.Array(recipients))
+	return err
+}
+
+const webhookColumns = `id, uuid, admin_id, url, events, is_active, created_at, updated_at`
+
+func scanWebhook(row rowScanner, webhookResponse *webhook.WebhookResponse) error {
+	return row.Scan(&webhookResponse.ID, &webhookResponse.UUID, &webhookResponse.AdminID, &webhookResponse.URL, pq.Array(&webhookResponse.Events), &webhookResponse.IsActive, &webhookResponse.CreatedAt, &webhookResponse.UpdatedAt)
+}
+
+func CreateWebhookService(ctx context.Context, db *sql.DB, webhookReq webhook.WebhookRequest, adminId int) (*webhook.WebhookResponse, error) {
+	if err := helpers.CheckWebhookURL(ctx, webhookReq.URL); err != nil {
+		return nil, err
+	}
+
+	token, err := helpers.RandomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	secret := "whsec_" + token
+
+	isActive := true
+	if webhookReq.IsActive != nil {
+		isActive = *webhookReq.IsActive
+	}
+
+	var webhookResponse webhook.WebhookResponse
+	query := `INSERT INTO webhook_subscriptions (admin_id, url, secret, events, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING ` + webhookColumns
+	err = scanWebhook(db.QueryRowContext(ctx, query, adminId, webhookReq.URL, secret, pq.Array(webhookReq.Events), isActive), &webhookResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	// The secret is shown once so the receiver can verify signatures
+	webhookResponse.Secret = secret
+	return &webhookResponse, nil
+}
+
+func GetAllWebhookService(ctx context.Context, db *sql.DB, adminId int) ([]webhook.WebhookResponse, error) {
+	var webhooks []webhook.WebhookResponse
+
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE admin_id = $1 ORDER BY id`
+	rows, err := db.QueryContext(ctx, query, adminId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var webhookResponse webhook.WebhookResponse
+		if err := scanWebhook(rows, &webhookResponse); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhookResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func GetWebhookByIDService(ctx context.Context, db *sql.DB, webhookUUID string, adminId int) (*webhook.WebhookResponse, error) {
+	var webhookResponse webhook.WebhookResponse
+
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE uuid = $1 AND admin_id = $2`
+	err := scanWebhook(db.QueryRowContext(ctx, query, webhookUUID, adminId), &webhookResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("webhook not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &webhookResponse, nil
+}
+
+func UpdateWebhookService(ctx context.Context, db *sql.DB, webhookReq webhook.WebhookRequest, webhookUUID string, adminId int) (*webhook.WebhookResponse, error) {
+	webhookResponse, err := GetWebhookByIDService(ctx, db, webhookUUID, adminId)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.CheckWebhookURL(ctx, webhookReq.URL); err != nil {
+		return nil, err
+	}
+
+	webhookResponse.URL = webhookReq.URL
+	webhookResponse.Events = webhookReq.Events
+	if webhookReq.IsActive != nil {
+		webhookResponse.IsActive = *webhookReq.IsActive
+	}
+	webhookResponse.UpdatedAt = time.Now()
+
+	query := `UPDATE webhook_subscriptions SET url = $1, events = $2, is_active = $3, updated_at = $4 WHERE id = $5`
+	_, err = db.ExecContext(ctx, query, webhookResponse.URL, pq.Array(webhookResponse.Events), webhookResponse.IsActive, webhookResponse.UpdatedAt, webhookResponse.ID)
+	if err != nil {
+		return nil, err
+	}
+	return webhookResponse, nil
+}
+
+func DeleteWebhookService(ctx context.Context, db *sql.DB, webhookUUID string, adminId int) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE uuid = $1 AND admin_id = $2`, webhookUUID, adminId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("webhook not found")
+	}
+	return nil
+}
+
+const deliveryColumns = `id, uuid, subscription_id, event, payload, status, attempts, next_attempt_at, last_attempt_at, last_status_code, last_error, created_at`
+
+func scanDelivery(row rowScanner, deliveryResponse *webhook.DeliveryResponse) error {
+	var payload []byte
+	err := row.Scan(&deliveryResponse.ID, &deliveryResponse.UUID, &deliveryResponse.SubscriptionID, &deliveryResponse.Event, &payload, &deliveryResponse.Status, &deliveryResponse.Attempts, &deliveryResponse.NextAttemptAt, &deliveryResponse.LastAttemptAt, &deliveryResponse.LastStatusCode, &deliveryResponse.LastError, &deliveryResponse.CreatedAt)
+	if err != nil {
+		return err
+	}
+	deliveryResponse.Payload = payload
+	return nil
+}
+
+// GetWebhookDeliveriesService returns the delivery log of a subscription,
+// newest first.
+func GetWebhookDeliveriesService(ctx context.Context, db *sql.DB, webhookUUID string, adminId, pageSize, offset int) ([]webhook.DeliveryResponse, int, error) {
+	webhookResponse, err := GetWebhookByIDService(ctx, db, webhookUUID, adminId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE subscription_id = $1`, webhookResponse.ID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []webhook.DeliveryResponse
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE subscription_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := db.QueryContext(ctx, query, webhookResponse.ID, pageSize, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var deliveryResponse webhook.DeliveryResponse
+		if err := scanDelivery(rows, &deliveryResponse); err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, deliveryResponse)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// RedeliverWebhookService queues a fresh copy of an earlier delivery. The
+// original entry is left as it was so the log stays intact.
+func RedeliverWebhookService(ctx context.Context, db *sql.DB, webhookUUID, deliveryUUID string, adminId int) (*webhook.DeliveryResponse, error) {
+	webhookResponse, err := GetWebhookByIDService(ctx, db, webhookUUID, adminId)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveryResponse webhook.DeliveryResponse
+	query := `
+		INSERT INTO webhook_deliveries (subscription_id, event, payload)
+		SELECT subscription_id, event, payload FROM webhook_deliveries
+		WHERE uuid = $1 AND subscription_id = $2
+		RETURNING ` + deliveryColumns
+	err = scanDelivery(db.QueryRowContext(ctx, query, deliveryUUID, webhookResponse.ID), &deliveryResponse)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("delivery not found")
+	} else if err != nil {
+		return nil, err
+	}
+	return &deliveryResponse, nil
+}
+
+// ProcessWebhookDeliveriesService claims due deliveries, hands each to send
+// and records the outcome. Failed attempts are retried with exponential
+// backoff until webhookMaxAttempts. Deliveries are claimed with SKIP LOCKED and
+// leased for webhookLease by moving their next attempt ahead, so replicas
+// never send the same one concurrently; the claim commits before anything is
+// sent, and a delivery whose outcome was never recorded is sent again once
+// its lease runs out.
+func ProcessWebhookDeliveriesService(ctx context.Context, db *sql.DB, send func(webhook.Delivery) (int, error)) (int, error) {
+	deliveries, err := claimWebhookDeliveries(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		statusCode, sendErr := send(delivery)
+		if err := recordWebhookAttempt(ctx, db, delivery, statusCode, sendErr); err != nil {
+			return 0, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func claimWebhookDeliveries(ctx context.Context, db *sql.DB) ([]webhook.Delivery, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT d.id, d.uuid, d.event, d.payload, d.attempts, s.url, s.secret
+		FROM webhook_deliveries d
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= NOW()
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT $1
+		FOR UPDATE OF d SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, query, webhookBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []webhook.Delivery
+	var ids []int64
+	for rows.Next() {
+		var delivery webhook.Delivery
+		if err := rows.Scan(&delivery.ID, &delivery.UUID, &delivery.Event, &delivery.Payload, &delivery.Attempts, &delivery.URL, &delivery.Secret); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+		ids = append(ids, int64(delivery.ID))
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
+	query = `UPDATE webhook_deliveries SET next_attempt_at = NOW() + $1 * INTERVAL '1 second' WHERE id = ANY($2)`
+	if _, err := tx.ExecContext(ctx, query, int(webhookLease.Seconds()), pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// recordWebhookAttempt stores the outcome of sending a claimed delivery,
+// scheduling the next attempt if it failed and may be retried.
+func recordWebhookAttempt(ctx context.Context, db *sql.DB, delivery webhook.Delivery, statusCode int, sendErr error) error {
+	attempts := delivery.Attempts + 1
+
+	var code sql.NullInt64
+	if statusCode > 0 {
+		code = sql.NullInt64{Int64: int64(statusCode), Valid: true}
+	}
+
+	var err error
+	if sendErr == nil {
+		query := `UPDATE webhook_deliveries SET status = 'succeeded', attempts = $1, last_attempt_at = NOW(), last_status_code = $2, last_error = NULL, next_attempt_at = NULL WHERE id = $3`
+		_, err = db.ExecContext(ctx, query, attempts, code, delivery.ID)
+	} else if attempts >= webhookMaxAttempts {
+		query := `UPDATE webhook_deliveries SET status = 'failed', attempts = $1, last_attempt_at = NOW(), last_status_code = $2, last_error = $3, next_attempt_at = NULL WHERE id = $4`
+		_, err = db.ExecContext(ctx, query, attempts, code, sendErr.Error(), delivery.ID)
+	} else {
+		backoff := webhookBaseBackoff << (attempts - 1)
+		query := `UPDATE webhook_deliveries SET attempts = $1, last_attempt_at = NOW(), last_status_code = $2, last_error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 second' WHERE id = $5`
+		_, err = db.ExecContext(ctx, query, attempts, code, sendErr.Error(), int(backoff.Seconds()), delivery.ID)
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"basic-trade-api/models/admin"
+	"reflect"
+	"testing"
+)
+
+func TestWebhookRecipients(t *testing.T) {
+	// Admins 1 and 2 each subscribe; admin 3 is a superadmin
+	subscribers := []webhookSubscriber{
+		{ID: 10, AdminID: 1, Role: admin.RoleAdmin},
+		{ID: 11, AdminID: 1, Role: admin.RoleAdmin},
+		{ID: 20, AdminID: 2, Role: admin.RoleAdmin},
+		{ID: 30, AdminID: 3, Role: admin.RoleSuperadmin},
+	}
+
+	tests := []struct {
+		name    string
+		ownerID int
+		want    []int
+	}{
+		{"first admin's product", 1, []int{10, 11, 30}},
+		{"second admin's product", 2, []int{20, 30}},
+		{"superadmin's product", 3, []int{30}},
+		{"unknown owner", 0, []int{30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := webhookRecipients(subscribers, tt.ownerID)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("webhookRecipients(%d) = %v, want %v", tt.ownerID, got, tt.want)
+			}
+		})
+	}
+}
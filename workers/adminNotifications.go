@@ -0,0 +1,46 @@
+package workers
+
+import (
+	"basic-trade-api/models/notification"
+	"basic-trade-api/services"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// StartAdminNotifications publishes the admin notifications sent by any
+// replica to hub until ctx is cancelled. Notifications are live only: those
+// sent while the listener is reconnecting are lost.
+func StartAdminNotifications(ctx context.Context, hub *services.NotificationHub) {
+	listener, err := newListener("admin notifications", services.AdminNotificationsChannel)
+	if err != nil {
+		log.Println("admin notifications:", err)
+		return
+	}
+	defer listener.Close()
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				continue
+			}
+			var message notification.Notification
+			if err := json.Unmarshal([]byte(n.Extra), &message); err != nil {
+				log.Println("admin notifications:", err)
+				continue
+			}
+			hub.Publish(message)
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				log.Println("admin notifications:", err)
+			}
+		}
+	}
+}
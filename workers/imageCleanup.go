@@ -0,0 +1,31 @@
+package workers
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// StartImageCleanup expires unconfirmed direct uploads and deletes stored
+// images queued for deletion every interval until ctx is cancelled.
+func StartImageCleanup(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := services.ExpireImageUploadsService(ctx, db); err != nil {
+				log.Println("image cleanup:", err)
+			}
+			if _, err := services.ProcessImageDeletionsService(ctx, db, helpers.DeleteFile); err != nil {
+				log.Println("image cleanup:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package workers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// keyReloadInterval is how often each replica rereads the signing keys. It
+// must be well below JWT_KEY_PUBLISH_AHEAD so every replica knows a new key
+// before it is used.
+const keyReloadInterval = time.Minute
+
+// RefreshSigningKeys rotates the signing keys if they are due and loads them
+// into helpers.JWTKeys. It is run once at startup, before any token is
+// issued, and then by StartKeyRotation.
+func RefreshSigningKeys(ctx context.Context, db *sql.DB) error {
+	// Tokens from a retired key stay valid for their whole lifetime
+	overlap := configs.EnvJWTTTL() + configs.EnvJWTLeeway()
+	rotated, err := services.RotateSigningKeysService(ctx, db, configs.EnvJWTAlgorithm(), configs.EnvJWTKeyRotationInterval(), configs.EnvJWTKeyPublishAhead(), overlap)
+	if err != nil {
+		return err
+	}
+	if rotated {
+		log.Println("key rotation: new signing key created")
+	}
+
+	keys, err := services.LoadSigningKeysService(ctx, db)
+	if err != nil {
+		return err
+	}
+	helpers.JWTKeys.Replace(keys)
+	return nil
+}
+
+// StartKeyRotation keeps the signing keys current until ctx is cancelled.
+func StartKeyRotation(ctx context.Context, db *sql.DB) {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := RefreshSigningKeys(ctx, db); err != nil {
+				log.Println("key rotation:", err)
+			}
+		}
+	}
+}
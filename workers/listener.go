@@ -0,0 +1,24 @@
+package workers
+
+import (
+	"basic-trade-api/database"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// newListener opens a dedicated connection listening on channel. It
+// reconnects on its own and sends nil on Notify after each reconnection.
+func newListener(name, channel string) (*pq.Listener, error) {
+	listener := pq.NewListener(database.ConnString(), 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,138 @@
+package workers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/notification"
+	"basic-trade-api/models/variant"
+	"basic-trade-api/services"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// LowStockSink receives low-stock events from the checker.
+type LowStockSink interface {
+	Name() string
+	Send(event variant.LowStockEvent) error
+}
+
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Send(event variant.LowStockEvent) error {
+	log.Printf("low stock: variant %s (%s) of product %q has %d left, reorder point %d",
+		event.VariantUUID, event.SKU, event.ProductName, event.Quantity, event.ReorderPoint)
+	return nil
+}
+
+// WebhookSink posts each event as JSON to URL.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s WebhookSink) Name() string { return "webhook" }
+
+func (s WebhookSink) Send(event variant.LowStockEvent) error {
+	body, err := json.Marshal(map[string]interface{}{"event": "stock.low", "data": event})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// EmailSink mails each event to To through Mailer.
+type EmailSink struct {
+	Mailer helpers.Mailer
+	To     []string
+}
+
+func (s EmailSink) Name() string { return "email" }
+
+func (s EmailSink) Send(event variant.LowStockEvent) error {
+	subject := fmt.Sprintf("Low stock: %s %s", event.ProductName, event.VariantName)
+	body := fmt.Sprintf("%s %s (SKU %s) is down to %d, below its reorder point of %d.\n",
+		event.ProductName, event.VariantName, event.SKU, event.Quantity, event.ReorderPoint)
+	return s.Mailer.Send(s.To, subject, body)
+}
+
+// DashboardSink notifies the product owner's open admin dashboards.
+type DashboardSink struct {
+	DB *sql.DB
+}
+
+func (DashboardSink) Name() string { return "dashboard" }
+
+func (s DashboardSink) Send(event variant.LowStockEvent) error {
+	return services.NotifyAdminService(context.Background(), s.DB, event.AdminID, notification.TypeStockLow, event)
+}
+
+// NewLowStockSinks builds the sinks named in LOW_STOCK_SINKS.
+func NewLowStockSinks(db *sql.DB) []LowStockSink {
+	var sinks []LowStockSink
+	for _, name := range configs.EnvLowStockSinks() {
+		switch name {
+		case "log":
+			sinks = append(sinks, LogSink{})
+		case "dashboard":
+			sinks = append(sinks, DashboardSink{DB: db})
+		case "webhook":
+			sinks = append(sinks, WebhookSink{
+				URL:    configs.EnvLowStockWebhookURL(),
+				Client: &http.Client{Timeout: 10 * time.Second},
+			})
+		case "email":
+			sinks = append(sinks, EmailSink{
+				Mailer: helpers.NewMailer(),
+				To:     configs.EnvLowStockEmailTo(),
+			})
+		default:
+			log.Printf("low stock checker: unknown sink %q ignored", name)
+		}
+	}
+	return sinks
+}
+
+// StartLowStockChecker sends pending low-stock events to every sink each
+// interval until ctx is cancelled. Alerts are best effort: they are sent
+// after the events are marked processed, and a failing sink is logged and
+// does not hold the event back from the others.
+func StartLowStockChecker(ctx context.Context, db *sql.DB, interval time.Duration, sinks []LowStockSink) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := services.ProcessLowStockEventsService(ctx, db, func(event variant.LowStockEvent) {
+				for _, sink := range sinks {
+					if err := sink.Send(event); err != nil {
+						log.Printf("low stock checker: %s sink: %v", sink.Name(), err)
+					}
+				}
+			})
+			if err != nil {
+				log.Println("low stock checker:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package workers
+
+import (
+	"basic-trade-api/configs"
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/outbox"
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// OutboxPublisher receives events from the outbox relay. Events can arrive
+// more than once, so publishers and their consumers must be idempotent; the
+// event UUID is the deduplication key.
+type OutboxPublisher interface {
+	Name() string
+	Publish(ctx context.Context, event outbox.Event) error
+}
+
+type LogPublisher struct{}
+
+func (LogPublisher) Name() string { return "log" }
+
+func (LogPublisher) Publish(ctx context.Context, event outbox.Event) error {
+	log.Printf("outbox: %s %s/%s (%s)", event.Type, event.AggregateType, event.AggregateID, event.UUID)
+	return nil
+}
+
+// WebhookPublisher fans events out to the matching webhook subscriptions.
+// The webhook dispatcher then sends them.
+type WebhookPublisher struct {
+	DB *sql.DB
+}
+
+func (WebhookPublisher) Name() string { return "webhook" }
+
+func (p WebhookPublisher) Publish(ctx context.Context, event outbox.Event) error {
+	return services.QueueWebhookDeliveriesService(ctx, p.DB, event)
+}
+
+// BrokerPublisher publishes events to a message broker, one topic per
+// aggregate type and keyed by aggregate ID so events for the same record stay
+// in order.
+type BrokerPublisher struct {
+	Broker      helpers.Broker
+	TopicPrefix string
+}
+
+func (BrokerPublisher) Name() string { return "broker" }
+
+func (p BrokerPublisher) Publish(ctx context.Context, event outbox.Event) error {
+	return p.Broker.Publish(ctx, helpers.BrokerMessage{
+		Topic: p.TopicPrefix + event.AggregateType,
+		Key:   event.AggregateID,
+		Value: event.Payload,
+		Headers: map[string]string{
+			"event-id":    event.UUID,
+			"event-type":  event.Type,
+			"occurred-at": event.CreatedAt.UTC().Format(time.RFC3339Nano),
+		},
+	})
+}
+
+// NewOutboxPublishers builds the publishers named in OUTBOX_PUBLISHERS.
+func NewOutboxPublishers(db *sql.DB) []OutboxPublisher {
+	var publishers []OutboxPublisher
+	for _, name := range configs.EnvOutboxPublishers() {
+		switch name {
+		case "log":
+			publishers = append(publishers, LogPublisher{})
+		case "webhook":
+			publishers = append(publishers, WebhookPublisher{DB: db})
+		case "broker":
+			broker, err := newBroker(configs.EnvOutboxBroker())
+			if err != nil {
+				log.Printf("outbox relay: %v; broker publisher ignored", err)
+				continue
+			}
+			publishers = append(publishers, BrokerPublisher{
+				Broker:      broker,
+				TopicPrefix: configs.EnvOutboxTopicPrefix(),
+			})
+		default:
+			log.Printf("outbox relay: unknown publisher %q ignored", name)
+		}
+	}
+	return publishers
+}
+
+func newBroker(name string) (helpers.Broker, error) {
+	switch name {
+	case "memory":
+		return helpers.NewMemoryBroker(), nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", name)
+	}
+}
+
+// StartOutboxRelay publishes pending outbox events to every publisher each
+// interval until ctx is cancelled. An event is retried, for all publishers,
+// until every one of them accepts it.
+func StartOutboxRelay(ctx context.Context, db *sql.DB, interval time.Duration, publishers []OutboxPublisher) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := services.ProcessOutboxService(ctx, db, func(event outbox.Event) error {
+				for _, publisher := range publishers {
+					if err := publisher.Publish(ctx, event); err != nil {
+						return fmt.Errorf("%s publisher: %w", publisher.Name(), err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				log.Println("outbox relay:", err)
+			}
+		}
+	}
+}
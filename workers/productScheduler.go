@@ -0,0 +1,32 @@
+package workers
+
+import (
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// StartProductScheduler publishes and archives products as their schedules
+// fall due, checking every interval until ctx is cancelled.
+func StartProductScheduler(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			products, err := services.ApplyProductSchedulesService(ctx, db)
+			if err != nil {
+				log.Println("product scheduler:", err)
+				continue
+			}
+			if len(products) > 0 {
+				log.Printf("product scheduler: applied the schedule of %d products", len(products))
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package workers
+
+import (
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// StartReservationSweeper releases expired stock reservations every interval
+// until ctx is cancelled.
+func StartReservationSweeper(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := services.ExpireReservationsService(ctx, db)
+			if err != nil {
+				log.Println("reservation sweeper:", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("reservation sweeper: released %d expired reservations", expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package workers
+
+import (
+	"basic-trade-api/models/variant"
+	"basic-trade-api/services"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	// stockChangesChannel is the NOTIFY channel of the stock_changes trigger
+	stockChangesChannel = "stock_changes"
+	// stockStreamPruneInterval is how often expired stock changes are deleted
+	stockStreamPruneInterval = 10 * time.Minute
+)
+
+// StartStockStream publishes the stock changes notified by Postgres to hub
+// until ctx is cancelled. Every replica runs its own listener, so a change
+// made through any of them reaches all open streams. Changes committed while
+// the listener was reconnecting are read back from the stock_changes table.
+func StartStockStream(ctx context.Context, db *sql.DB, hub *services.StockHub, retention time.Duration) {
+	listener, err := newListener("stock stream", stockChangesChannel)
+	if err != nil {
+		log.Println("stock stream:", err)
+		return
+	}
+	defer listener.Close()
+
+	// Only changes from now on are live; older ones are served as backlog
+	var lastID int64
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM stock_changes`).Scan(&lastID); err != nil {
+		log.Println("stock stream:", err)
+	}
+
+	prune := time.NewTicker(stockStreamPruneInterval)
+	defer prune.Stop()
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// The connection was re-established and notifications may
+				// have been lost in between
+				lastID = catchUpStockChanges(ctx, db, hub, lastID)
+				continue
+			}
+			var notice struct {
+				variant.StockChange
+				ProductStatus string `json:"productStatus"`
+				AdminID       int    `json:"adminId"`
+			}
+			if err := json.Unmarshal([]byte(notification.Extra), &notice); err != nil {
+				log.Println("stock stream:", err)
+				continue
+			}
+			change := notice.StockChange
+			change.ProductStatus, change.AdminID = notice.ProductStatus, notice.AdminID
+			hub.Publish(change)
+			if change.ID > lastID {
+				lastID = change.ID
+			}
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				log.Println("stock stream:", err)
+			}
+		case <-prune.C:
+			if _, err := services.PruneStockChangesService(ctx, db, retention); err != nil {
+				log.Println("stock stream:", err)
+			}
+		}
+	}
+}
+
+// catchUpStockChanges publishes every change after lastID and returns the
+// last one published.
+func catchUpStockChanges(ctx context.Context, db *sql.DB, hub *services.StockHub, lastID int64) int64 {
+	for {
+		changes, err := services.GetStockChangesSinceService(ctx, db, lastID, nil)
+		if err != nil {
+			log.Println("stock stream:", err)
+			return lastID
+		}
+		for _, change := range changes {
+			hub.Publish(change)
+			lastID = change.ID
+		}
+		if len(changes) == 0 {
+			return lastID
+		}
+	}
+}
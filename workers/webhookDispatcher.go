@@ -0,0 +1,71 @@
+package workers
+
+import (
+	"basic-trade-api/helpers"
+	"basic-trade-api/models/webhook"
+	"basic-trade-api/services"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// StartWebhookDispatcher sends due webhook deliveries every interval until
+// ctx is cancelled.
+func StartWebhookDispatcher(ctx context.Context, db *sql.DB, interval time.Duration) {
+	client := helpers.NewWebhookClient(10 * time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := services.ProcessWebhookDeliveriesService(ctx, db, func(delivery webhook.Delivery) (int, error) {
+				return sendWebhook(ctx, client, delivery)
+			})
+			if err != nil {
+				log.Println("webhook dispatcher:", err)
+			}
+		}
+	}
+}
+
+// sendWebhook posts a signed delivery. Any 2xx response counts as delivered;
+// redirects are not followed. Transport errors are only logged here: the
+// delivery log, which the subscriber reads, gets a generic error so it does
+// not tell what answers inside the network.
+func sendWebhook(ctx context.Context, client *http.Client, delivery webhook.Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "basic-trade-api-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.UUID)
+	req.Header.Set("X-Webhook-Timestamp", fmt.Sprint(timestamp))
+	req.Header.Set("X-Webhook-Signature", helpers.SignWebhookPayload(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("webhook dispatcher: delivery %s: %v", delivery.UUID, err)
+		return 0, errors.New("could not reach receiver")
+	}
+	defer resp.Body.Close()
+	// Drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}